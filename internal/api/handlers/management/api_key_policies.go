@@ -0,0 +1,196 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/quota"
+	log "github.com/sirupsen/logrus"
+)
+
+// api-key-policies: map[string]APIKeyPolicy
+func (h *Handler) GetAPIKeyPolicies(c *gin.Context) {
+	policies := h.cfg.APIKeyPolicies
+	if policies == nil {
+		policies = map[string]config.APIKeyPolicy{}
+	}
+	c.JSON(http.StatusOK, gin.H{"api-key-policies": policies})
+}
+
+// PutAPIKeyPolicies replaces all API key policies.
+func (h *Handler) PutAPIKeyPolicies(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var entries map[string]config.APIKeyPolicy
+	if err = json.Unmarshal(data, &entries); err != nil {
+		var wrapper struct {
+			Items map[string]config.APIKeyPolicy `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &wrapper); err2 != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		entries = wrapper.Items
+	}
+	normalized := make(map[string]config.APIKeyPolicy, len(entries))
+	for key, policy := range entries {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "empty api key"})
+			return
+		}
+		if errValidate := validateAPIKeyPolicy(&policy); errValidate != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid policy for %s: %v", key, errValidate)})
+			return
+		}
+		normalized[key] = policy
+	}
+	if len(normalized) == 0 {
+		normalized = nil
+	}
+	h.cfg.APIKeyPolicies = normalized
+	h.persistAPIKeyPolicies(c)
+}
+
+// PatchAPIKeyPolicy creates or replaces the policy of a single API key.
+func (h *Handler) PatchAPIKeyPolicy(c *gin.Context) {
+	var body struct {
+		APIKey *string              `json:"api-key"`
+		Value  *config.APIKeyPolicy `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.APIKey == nil || body.Value == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	key := strings.TrimSpace(*body.APIKey)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing api-key"})
+		return
+	}
+	if err := validateAPIKeyPolicy(body.Value); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.cfg.APIKeyPolicies == nil {
+		h.cfg.APIKeyPolicies = make(map[string]config.APIKeyPolicy)
+	}
+	h.cfg.APIKeyPolicies[key] = *body.Value
+	h.persistAPIKeyPolicies(c)
+}
+
+// DeleteAPIKeyPolicy removes the policy of a single API key.
+func (h *Handler) DeleteAPIKeyPolicy(c *gin.Context) {
+	key := strings.TrimSpace(c.Query("api-key"))
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing api-key"})
+		return
+	}
+	if _, ok := h.cfg.APIKeyPolicies[key]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "policy not found"})
+		return
+	}
+	delete(h.cfg.APIKeyPolicies, key)
+	if len(h.cfg.APIKeyPolicies) == 0 {
+		h.cfg.APIKeyPolicies = nil
+	}
+	h.persistAPIKeyPolicies(c)
+}
+
+// GetQuotaUsage returns per-key quota consumption together with the applicable policy.
+// An optional api-key query parameter restricts the result to a single key.
+func (h *Handler) GetQuotaUsage(c *gin.Context) {
+	manager := quota.GetManager()
+	keys := make(map[string]struct{})
+	if filter := strings.TrimSpace(c.Query("api-key")); filter != "" {
+		keys[filter] = struct{}{}
+	} else {
+		for key := range manager.AllPolicies() {
+			keys[key] = struct{}{}
+		}
+		for key := range manager.AllUsage() {
+			keys[key] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	items := make([]gin.H, 0, len(sorted))
+	for _, key := range sorted {
+		items = append(items, gin.H{
+			"api-key": key,
+			"status":  manager.GetQuotaStatus(key),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"quota-usage": items})
+}
+
+// ResetQuotaUsage clears the recorded usage of one API key, or of every key when all is true.
+func (h *Handler) ResetQuotaUsage(c *gin.Context) {
+	var body struct {
+		APIKey *string `json:"api-key"`
+		All    bool    `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	manager := quota.GetManager()
+	switch {
+	case body.All:
+		manager.ResetAllUsage()
+	case body.APIKey != nil && strings.TrimSpace(*body.APIKey) != "":
+		manager.ResetUsage(strings.TrimSpace(*body.APIKey))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing api-key or all"})
+		return
+	}
+	if quota.GetPersistencePath() != "" {
+		if err := quota.SaveUsageToFile(); err != nil {
+			log.Warnf("failed to persist quota usage after reset: %v", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// persistAPIKeyPolicies saves the config and applies the new policies immediately.
+func (h *Handler) persistAPIKeyPolicies(c *gin.Context) {
+	if h.persist(c) {
+		quota.GetManager().LoadPolicies(&h.cfg.SDKConfig)
+	}
+}
+
+func validateAPIKeyPolicy(policy *config.APIKeyPolicy) error {
+	if policy == nil {
+		return fmt.Errorf("missing policy")
+	}
+	if policy.MaxTokens < 0 || policy.MaxCostUSD < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	policy.ExpiresAt = strings.TrimSpace(policy.ExpiresAt)
+	if policy.ExpiresAt != "" && policy.ParsedExpiresAt().IsZero() {
+		return fmt.Errorf("invalid expires_at %q", policy.ExpiresAt)
+	}
+	if policy.Limits != nil {
+		for _, limit := range []*config.APIKeyWindowLimit{policy.Limits.Minute, policy.Limits.Hour, policy.Limits.Day, policy.Limits.Month} {
+			if limit != nil && (limit.Requests < 0 || limit.Tokens < 0 || limit.CostUSD < 0) {
+				return fmt.Errorf("limits must not be negative")
+			}
+		}
+		if !policy.HasWindowLimits() {
+			policy.Limits = nil
+		}
+	}
+	return nil
+}
@@ -434,6 +434,18 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if claims := extractCodexIDTokenClaims(auth); claims != nil {
 		entry["id_token"] = claims
 	}
+	if h.authManager != nil {
+		if scores := h.authManager.SelectorScores(auth.ID); len(scores) > 0 {
+			entry["adaptive_scores"] = scores
+		}
+		if breaker, ok := h.authManager.CircuitBreakerFor(auth); ok {
+			entry["circuit_breaker"] = breaker
+		}
+		entry["in_flight"] = h.authManager.InFlight(auth.ID)
+		if limit := h.authManager.ConcurrencyLimit(auth); limit > 0 {
+			entry["max_concurrency"] = limit
+		}
+	}
 	return entry
 }
 
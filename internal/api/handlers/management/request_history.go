@@ -0,0 +1,212 @@
+package management
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/requesthistory"
+)
+
+// GetRequestHistory searches the request history.
+//
+// Query parameters (all optional):
+//   - from, to: RFC3339 timestamps or Unix seconds bounding the request start time
+//   - api_key: the client API key, or its masked form as shown in results
+//   - model, provider, auth_index: exact matches (model and provider ignore case)
+//   - status: an exact code such as 429, or a class such as 5xx
+//   - min_latency_ms, max_latency_ms: latency bounds in milliseconds
+//   - limit (default 50, max 500), offset: pagination, newest requests first
+func (h *Handler) GetRequestHistory(c *gin.Context) {
+	query, err := parseRequestHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, requesthistory.Default().Query(query))
+}
+
+// GetRequestHistoryEntry returns one indexed request together with its request log. JSONL
+// request logs are embedded under "log"; text logs are linked through "log_url".
+func (h *Handler) GetRequestHistoryEntry(c *gin.Context) {
+	requestID := strings.TrimSpace(c.Param("id"))
+	if requestID == "" || strings.ContainsAny(requestID, "/\\") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request ID"})
+		return
+	}
+	entry, ok := requesthistory.Default().Get(requestID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+		return
+	}
+
+	response := gin.H{"request": entry}
+	dir := h.logDirectory()
+	if logEntry, errFind := findJSONLRequestLog(dir, requestID); errFind != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read request logs: %v", errFind)})
+		return
+	} else if logEntry != nil {
+		response["log"] = logEntry
+	} else if hasTextRequestLog(dir, requestID) {
+		response["log_url"] = "/v0/management/request-log-by-id/" + requestID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func parseRequestHistoryQuery(c *gin.Context) (requesthistory.Query, error) {
+	query := requesthistory.Query{
+		APIKey:    strings.TrimSpace(c.Query("api_key")),
+		Model:     strings.TrimSpace(c.Query("model")),
+		Provider:  strings.TrimSpace(c.Query("provider")),
+		AuthIndex: strings.TrimSpace(c.Query("auth_index")),
+	}
+	var err error
+	if query.From, err = parseHistoryTime(c.Query("from")); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if query.To, err = parseHistoryTime(c.Query("to")); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+
+	if status := strings.ToLower(strings.TrimSpace(c.Query("status"))); status != "" {
+		if len(status) == 3 && strings.HasSuffix(status, "xx") && status[0] >= '1' && status[0] <= '5' {
+			query.StatusClass = int(status[0] - '0')
+		} else if query.Status, err = strconv.Atoi(status); err != nil || query.Status < 100 || query.Status > 599 {
+			return query, fmt.Errorf("invalid status: must be a status code or a class such as 5xx")
+		}
+	}
+
+	minLatency, err := parseNonNegative(c.Query("min_latency_ms"))
+	if err != nil {
+		return query, fmt.Errorf("invalid min_latency_ms: %w", err)
+	}
+	maxLatency, err := parseNonNegative(c.Query("max_latency_ms"))
+	if err != nil {
+		return query, fmt.Errorf("invalid max_latency_ms: %w", err)
+	}
+	query.MinLatency = time.Duration(minLatency) * time.Millisecond
+	query.MaxLatency = time.Duration(maxLatency) * time.Millisecond
+
+	if query.Limit, err = parseLimit(c.Query("limit")); err != nil {
+		return query, fmt.Errorf("invalid limit: %w", err)
+	}
+	if query.Offset, err = parseNonNegative(c.Query("offset")); err != nil {
+		return query, fmt.Errorf("invalid offset: %w", err)
+	}
+	return query, nil
+}
+
+func parseHistoryTime(raw string) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be RFC3339 or Unix seconds")
+	}
+	return ts, nil
+}
+
+func parseNonNegative(raw string) (int, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a non-negative integer")
+	}
+	return n, nil
+}
+
+// findJSONLRequestLog scans the JSONL request log and its uncompressed rotations, newest
+// first, for the entry of requestID.
+func findJSONLRequestLog(dir, requestID string) (json.RawMessage, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, nil
+	}
+	base := strings.TrimSuffix(logging.JSONLRequestLogFileName, ".jsonl")
+	rotated, err := filepath.Glob(filepath.Join(dir, base+"-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	// Rotated names embed their timestamp, so reverse lexical order is newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+	candidates := append([]string{filepath.Join(dir, logging.JSONLRequestLogFileName)}, rotated...)
+
+	marker := []byte(`"request_id":` + strconv.Quote(requestID))
+	for _, path := range candidates {
+		found, errScan := scanJSONLRequestLog(path, requestID, marker)
+		if errScan != nil {
+			return nil, errScan
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+func scanJSONLRequestLog(path, requestID string, marker []byte) (json.RawMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if bytes.Contains(line, marker) {
+			var probe struct {
+				RequestID string `json:"request_id"`
+			}
+			line = bytes.TrimSpace(line)
+			if json.Unmarshal(line, &probe) == nil && probe.RequestID == requestID {
+				return json.RawMessage(line), nil
+			}
+		}
+		if errors.Is(errRead, io.EOF) {
+			return nil, nil
+		}
+		if errRead != nil {
+			return nil, errRead
+		}
+	}
+}
+
+func hasTextRequestLog(dir, requestID string) bool {
+	if strings.TrimSpace(dir) == "" {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	suffix := "-" + requestID + ".log"
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/requesthistory"
+)
+
+func historyContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return c, rec
+}
+
+func TestParseRequestHistoryQuery(t *testing.T) {
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		raw     string
+		want    requesthistory.Query
+		wantErr bool
+	}{
+		{name: "empty", raw: ""},
+		{name: "exact status", raw: "status=429", want: requesthistory.Query{Status: 429}},
+		{name: "status class", raw: "status=5xx", want: requesthistory.Query{StatusClass: 5}},
+		{name: "status class upper case", raw: "status=4XX", want: requesthistory.Query{StatusClass: 4}},
+		{name: "status out of range", raw: "status=600", wantErr: true},
+		{name: "status class out of range", raw: "status=6xx", wantErr: true},
+		{name: "status not a number", raw: "status=error", wantErr: true},
+		{name: "rfc3339 range", raw: "from=2026-03-01T12:00:00Z&to=2026-03-01T13:00:00Z", want: requesthistory.Query{From: from, To: from.Add(time.Hour)}},
+		{name: "unix seconds", raw: "from=1772366400", want: requesthistory.Query{From: time.Unix(1772366400, 0)}},
+		{name: "invalid from", raw: "from=yesterday", wantErr: true},
+		{name: "invalid to", raw: "to=2026-03-01", wantErr: true},
+		{name: "latency and paging", raw: "min_latency_ms=100&max_latency_ms=2000&limit=10&offset=20", want: requesthistory.Query{MinLatency: 100 * time.Millisecond, MaxLatency: 2 * time.Second, Limit: 10, Offset: 20}},
+		{name: "negative latency", raw: "min_latency_ms=-1", wantErr: true},
+		{name: "zero limit", raw: "limit=0", wantErr: true},
+		{name: "filters", raw: "api_key=sk-a&model=gpt-5&provider=codex&auth_index=2", want: requesthistory.Query{APIKey: "sk-a", Model: "gpt-5", Provider: "codex", AuthIndex: "2"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := historyContext("/v0/management/request-history?" + tc.raw)
+			got, err := parseRequestHistoryQuery(c)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseRequestHistoryQuery(%q) = %+v, want error", tc.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRequestHistoryQuery(%q): %v", tc.raw, err)
+			}
+			if !got.From.Equal(tc.want.From) || !got.To.Equal(tc.want.To) {
+				t.Fatalf("range = %v..%v, want %v..%v", got.From, got.To, tc.want.From, tc.want.To)
+			}
+			got.From, got.To = tc.want.From, tc.want.To
+			if got != tc.want {
+				t.Fatalf("query = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetRequestHistory(t *testing.T) {
+	const model = "history-handler-test"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, status := range []int{200, 502, 503} {
+		entry := requesthistory.NewEntry(fmt.Sprintf("history-handler-%d", i), base.Add(time.Duration(i)*time.Minute), http.MethodPost, "/v1/chat/completions", status, "")
+		entry.Model = model
+		requesthistory.Default().Record(entry)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+
+	c, rec := historyContext("/v0/management/request-history?model=" + model + "&status=5xx&from=2026-03-01T12:01:30Z")
+	h.GetRequestHistory(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var page requesthistory.Page
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if page.Total != 1 || len(page.Requests) != 1 || page.Requests[0].ID != "history-handler-2" {
+		t.Fatalf("page = %+v, want only history-handler-2", page)
+	}
+
+	c, rec = historyContext("/v0/management/request-history?status=abc")
+	h.GetRequestHistory(c)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid status: code = %d, want 400", rec.Code)
+	}
+}
+
+func TestGetRequestHistoryEntry(t *testing.T) {
+	dir := t.TempDir()
+	requesthistory.Default().Record(requesthistory.NewEntry("history-entry-jsonl", time.Now(), http.MethodPost, "/v1/messages", 200, ""))
+	requesthistory.Default().Record(requesthistory.NewEntry("history-entry-text", time.Now(), http.MethodPost, "/v1/messages", 200, ""))
+	logLine := `{"request_id":"history-entry-jsonl","status":200}`
+	if err := os.WriteFile(filepath.Join(dir, logging.JSONLRequestLogFileName), []byte(`{"request_id":"other"}`+"\n"+logLine+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "v1-messages-history-entry-text.log"), []byte("log"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	h.SetLogDirectory(dir)
+
+	get := func(id string) (int, map[string]json.RawMessage) {
+		c, rec := historyContext("/v0/management/request-history/" + id)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		h.GetRequestHistoryEntry(c)
+		var body map[string]json.RawMessage
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+	if code, body := get("history-entry-jsonl"); code != http.StatusOK || string(body["log"]) != logLine {
+		t.Fatalf("jsonl entry: code %d, log %s", code, body["log"])
+	}
+	if code, body := get("history-entry-text"); code != http.StatusOK || string(body["log_url"]) != `"/v0/management/request-log-by-id/history-entry-text"` {
+		t.Fatalf("text entry: code %d, body %v", code, body)
+	}
+	if code, _ := get("history-entry-missing"); code != http.StatusNotFound {
+		t.Fatalf("missing entry: code %d, want 404", code)
+	}
+}
+
+func TestScanJSONLRequestLogReportsReadErrors(t *testing.T) {
+	// Reading a directory fails after the open succeeds.
+	if _, err := scanJSONLRequestLog(t.TempDir(), "id", []byte(`"request_id":"id"`)); err == nil {
+		t.Fatal("scanJSONLRequestLog on an unreadable file returned no error")
+	}
+}
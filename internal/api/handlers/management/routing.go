@@ -0,0 +1,22 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// GetCircuitBreakers lists the circuit breaker state of every upstream endpoint with recorded failures.
+func (h *Handler) GetCircuitBreakers(c *gin.Context) {
+	breakers := []coreauth.CircuitBreakerStatus{}
+	if h.authManager != nil {
+		if list := h.authManager.CircuitBreakers(); list != nil {
+			breakers = list
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":          h.cfg.Routing.CircuitBreaker.Enabled,
+		"circuit-breakers": breakers,
+	})
+}
@@ -8,10 +8,13 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/requesthistory"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
 )
 
 // RequestLoggingMiddleware creates a Gin middleware that logs HTTP requests and responses.
@@ -60,7 +63,33 @@ func RequestLoggingMiddleware(logger logging.RequestLogger) gin.HandlerFunc {
 			// Log error but don't interrupt the response
 			// In a real implementation, you might want to use a proper logger here
 		}
+
+		if logger.IsEnabled() {
+			recordRequestHistory(c, requestInfo, wrapper.isStreaming)
+		}
+	}
+}
+
+// recordRequestHistory indexes the logged request so it can be searched through the
+// management API. The model falls back to the one named in the request body when no
+// upstream was reached.
+func recordRequestHistory(c *gin.Context, info *RequestInfo, streaming bool) {
+	if info == nil || info.RequestID == "" {
+		return
+	}
+	apiKey := ""
+	if value, ok := c.Get("apiKey"); ok {
+		apiKey, _ = value.(string)
+	}
+	entry := requesthistory.NewEntry(info.RequestID, info.StartedAt, info.Method, c.Request.URL.Path, c.Writer.Status(), apiKey)
+	entry.Streaming = streaming
+	entry.Provider = c.GetString("API_PROVIDER")
+	entry.Model = c.GetString("API_MODEL")
+	entry.AuthIndex = c.GetString("API_AUTH_INDEX")
+	if entry.Model == "" && len(info.Body) > 0 {
+		entry.Model = gjson.GetBytes(info.Body, "model").String()
 	}
+	requesthistory.Default().Record(entry)
 }
 
 // captureRequestInfo extracts relevant information from the incoming HTTP request.
@@ -103,6 +132,7 @@ func captureRequestInfo(c *gin.Context) (*RequestInfo, error) {
 		Headers:   headers,
 		Body:      body,
 		RequestID: logging.GetGinRequestID(c),
+		StartedAt: time.Now(),
 	}, nil
 }
 
@@ -9,9 +9,11 @@ import (
 	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -23,10 +25,16 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/quota"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/requesthistory"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsecache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -58,10 +66,14 @@ type ServerOption func(*serverOptionConfig)
 
 func defaultRequestLoggerFactory(cfg *config.Config, configPath string) logging.RequestLogger {
 	configDir := filepath.Dir(configPath)
+	logsDir := "logs"
 	if base := util.WritablePath(); base != "" {
-		return logging.NewFileRequestLogger(cfg.RequestLog, filepath.Join(base, "logs"), configDir)
+		logsDir = filepath.Join(base, "logs")
 	}
-	return logging.NewFileRequestLogger(cfg.RequestLog, "logs", configDir)
+	if strings.EqualFold(strings.TrimSpace(cfg.RequestLogSink.Format), "jsonl") {
+		return logging.NewJSONLRequestLogger(cfg.RequestLog, logsDir, configDir, cfg.RequestLogSink)
+	}
+	return logging.NewFileRequestLogger(cfg.RequestLog, logsDir, configDir)
 }
 
 // WithMiddleware appends additional Gin middleware during server construction.
@@ -203,6 +215,8 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	// Add middleware
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
+	engine.Use(metrics.Default().Middleware())
+	engine.Use(middleware.TracingMiddleware())
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
@@ -252,9 +266,14 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	s.applyAccessConfig(nil, cfg)
 	if authManager != nil {
 		authManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
+		authManager.AddHook(metrics.Default())
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	s.applyResponseStore(nil, cfg)
+	s.applyResponseCache(nil, cfg)
+	s.applyTracing(nil, cfg)
+	s.applyBatch(nil, cfg)
 
 	// Initialize quota system
 	if cfg.AuthDir != "" {
@@ -275,6 +294,9 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		logDir = filepath.Join(base, "logs")
 	}
 	s.mgmt.SetLogDirectory(logDir)
+	if errHistory := requesthistory.Default().Open(logDir); errHistory != nil {
+		log.Warnf("failed to load request history: %v", errHistory)
+	}
 	s.localPassword = optionState.localPassword
 
 	// Setup routes
@@ -321,12 +343,16 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 // It defines the endpoints and associates them with their respective handlers.
 func (s *Server) setupRoutes() {
 	s.engine.GET("/management.html", s.serveManagementControlPanel)
+	s.engine.GET("/metrics", s.serveMetrics)
 	openaiHandlers := openai.NewOpenAIAPIHandler(s.handlers)
 	geminiHandlers := gemini.NewGeminiAPIHandler(s.handlers)
 	geminiCLIHandlers := gemini.NewGeminiCLIAPIHandler(s.handlers)
 	claudeCodeHandlers := claude.NewClaudeCodeAPIHandler(s.handlers)
 	openaiResponsesHandlers := openai.NewOpenAIResponsesAPIHandler(s.handlers)
 
+	// Images produced for response_format "url" are fetched without API keys.
+	s.engine.GET("/generated-images/:name", openaiHandlers.GeneratedImage)
+
 	// OpenAI compatible API routes
 	v1 := s.engine.Group("/v1")
 	v1.Use(AuthMiddleware(s.accessManager))
@@ -335,9 +361,38 @@ func (s *Server) setupRoutes() {
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
+		v1.POST("/images/generations", openaiHandlers.ImageGenerations)
+		v1.POST("/images/edits", openaiHandlers.ImageEdits)
+		v1.POST("/audio/transcriptions", openaiHandlers.AudioTranscriptions)
+		v1.POST("/audio/speech", openaiHandlers.AudioSpeech)
+		v1.POST("/conversations", openaiHandlers.CreateConversation)
+		v1.GET("/conversations/:id", openaiHandlers.GetConversation)
+		v1.DELETE("/conversations/:id", openaiHandlers.DeleteConversation)
+		v1.GET("/conversations/:id/messages", openaiHandlers.ListConversationMessages)
+		v1.POST("/conversations/:id/messages", openaiHandlers.AppendConversationMessages)
+		v1.POST("/conversations/:id/completions", openaiHandlers.ConversationCompletions)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
+		v1.POST("/messages/batches", claudeCodeHandlers.CreateMessageBatch)
+		v1.GET("/messages/batches", claudeCodeHandlers.ListMessageBatches)
+		v1.GET("/messages/batches/:id", claudeCodeHandlers.GetMessageBatch)
+		v1.DELETE("/messages/batches/:id", claudeCodeHandlers.DeleteMessageBatch)
+		v1.POST("/messages/batches/:id/cancel", claudeCodeHandlers.CancelMessageBatch)
+		v1.GET("/messages/batches/:id/results", claudeCodeHandlers.MessageBatchResults)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
+		v1.GET("/responses/:id", openaiResponsesHandlers.GetResponse)
+		v1.DELETE("/responses/:id", openaiResponsesHandlers.DeleteResponse)
+		v1.GET("/responses/:id/input_items", openaiResponsesHandlers.ListResponseInputItems)
+		v1.POST("/files", openaiHandlers.UploadFile)
+		v1.GET("/files", openaiHandlers.ListFiles)
+		v1.GET("/files/:id", openaiHandlers.GetFile)
+		v1.DELETE("/files/:id", openaiHandlers.DeleteFile)
+		v1.GET("/files/:id/content", openaiHandlers.GetFileContent)
+		v1.POST("/batches", openaiHandlers.CreateBatch)
+		v1.GET("/batches", openaiHandlers.ListBatches)
+		v1.GET("/batches/:id", openaiHandlers.GetBatch)
+		v1.POST("/batches/:id/cancel", openaiHandlers.CancelBatch)
 	}
 
 	// Gemini compatible API routes
@@ -549,6 +604,13 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PATCH("/api-keys", s.mgmt.PatchAPIKeys)
 		mgmt.DELETE("/api-keys", s.mgmt.DeleteAPIKeys)
 
+		mgmt.GET("/api-key-policies", s.mgmt.GetAPIKeyPolicies)
+		mgmt.PUT("/api-key-policies", s.mgmt.PutAPIKeyPolicies)
+		mgmt.PATCH("/api-key-policies", s.mgmt.PatchAPIKeyPolicy)
+		mgmt.DELETE("/api-key-policies", s.mgmt.DeleteAPIKeyPolicy)
+		mgmt.GET("/quota/usage", s.mgmt.GetQuotaUsage)
+		mgmt.POST("/quota/reset", s.mgmt.ResetQuotaUsage)
+
 		mgmt.GET("/gemini-api-key", s.mgmt.GetGeminiKeys)
 		mgmt.PUT("/gemini-api-key", s.mgmt.PutGeminiKeys)
 		mgmt.PATCH("/gemini-api-key", s.mgmt.PatchGeminiKey)
@@ -559,6 +621,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/request-error-logs", s.mgmt.GetRequestErrorLogs)
 		mgmt.GET("/request-error-logs/:name", s.mgmt.DownloadRequestErrorLog)
 		mgmt.GET("/request-log-by-id/:id", s.mgmt.GetRequestLogByID)
+		mgmt.GET("/requests", s.mgmt.GetRequestHistory)
+		mgmt.GET("/requests/:id", s.mgmt.GetRequestHistoryEntry)
 		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
 		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
 		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
@@ -623,6 +687,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
 		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
+		mgmt.GET("/circuit-breakers", s.mgmt.GetCircuitBreakers)
 
 		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
 		mgmt.GET("/codex-auth-url", s.mgmt.RequestCodexToken)
@@ -674,6 +739,33 @@ func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	c.File(filePath)
 }
 
+// serveMetrics renders Prometheus metrics when metrics.enabled is set, requiring the
+// configured bearer token when one exists.
+func (s *Server) serveMetrics(c *gin.Context) {
+	cfg := s.cfg
+	if cfg == nil || !cfg.Metrics.Enabled {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if token := cfg.Metrics.Token; token != "" {
+		provided := strings.TrimSpace(c.GetHeader("Authorization"))
+		parts := strings.SplitN(provided, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(parts[1])), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	var authManager *auth.Manager
+	if s.handlers != nil {
+		authManager = s.handlers.AuthManager
+	}
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	metrics.Default().Write(c.Writer, authManager)
+}
+
 func (s *Server) enableKeepAlive(timeout time.Duration, onTimeout func()) {
 	if timeout <= 0 || onTimeout == nil {
 		return
@@ -825,6 +917,19 @@ func (s *Server) Stop(ctx context.Context) error {
 	if err := s.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
 	}
+	// Stop batch jobs; interrupted requests resume on the next start.
+	batch.SetDefault(nil)
+	// Flush spans that are still buffered for export.
+	tracing.Shutdown()
+	// Close rotating request log files.
+	if closer, ok := s.requestLogger.(io.Closer); ok {
+		if errClose := closer.Close(); errClose != nil {
+			log.WithError(errClose).Warn("failed to close request logger")
+		}
+	}
+	if errClose := requesthistory.Default().Close(); errClose != nil {
+		log.WithError(errClose).Warn("failed to close request history")
+	}
 
 	log.Debug("API server stopped")
 	return nil
@@ -859,6 +964,88 @@ func (s *Server) applyAccessConfig(oldCfg, newCfg *config.Config) {
 	}
 }
 
+// applyResponseStore (re)creates the Responses API store when its configuration changes.
+func (s *Server) applyResponseStore(oldCfg, newCfg *config.Config) {
+	if newCfg == nil {
+		return
+	}
+	if oldCfg != nil && oldCfg.ResponseStore == newCfg.ResponseStore {
+		return
+	}
+	store, err := responsestore.New(context.Background(), newCfg.ResponseStore)
+	if err != nil {
+		log.Errorf("failed to initialize response store: %v", err)
+		return
+	}
+	responsestore.SetDefault(store)
+	if oldCfg != nil {
+		log.Debugf("response store updated to backend %q", newCfg.ResponseStore.Backend)
+	}
+}
+
+// applyResponseCache (re)creates the response cache when its configuration changes.
+func (s *Server) applyResponseCache(oldCfg, newCfg *config.Config) {
+	if newCfg == nil {
+		return
+	}
+	if oldCfg != nil && oldCfg.ResponseCache == newCfg.ResponseCache {
+		return
+	}
+	cache, err := responsecache.New(newCfg.ResponseCache)
+	if err != nil {
+		log.Errorf("failed to initialize response cache: %v", err)
+		return
+	}
+	responsecache.SetDefault(cache)
+	if oldCfg != nil {
+		log.Debugf("response cache updated (enabled=%t, backend %q)", newCfg.ResponseCache.Enabled, newCfg.ResponseCache.Backend)
+	}
+}
+
+// applyTracing installs the OpenTelemetry exporter when the tracing configuration changes.
+func (s *Server) applyTracing(oldCfg, newCfg *config.Config) {
+	if newCfg == nil {
+		return
+	}
+	if oldCfg != nil && reflect.DeepEqual(oldCfg.Tracing, newCfg.Tracing) {
+		return
+	}
+	if err := tracing.Configure(newCfg.Tracing); err != nil {
+		log.Errorf("failed to initialize tracing: %v", err)
+		return
+	}
+	if oldCfg != nil || newCfg.Tracing.Enabled {
+		log.Debugf("tracing updated (enabled=%t, exporter %q)", newCfg.Tracing.Enabled, newCfg.Tracing.Exporter)
+	}
+}
+
+// applyBatch (re)opens the batch runner when its configuration changes. The previous runner
+// is closed first so that jobs it was executing are resumed exactly once by the new one.
+func (s *Server) applyBatch(oldCfg, newCfg *config.Config) {
+	if newCfg == nil || s.handlers == nil {
+		return
+	}
+	if oldCfg != nil && oldCfg.Batch == newCfg.Batch {
+		return
+	}
+	batch.SetDefault(nil)
+	apiKeys := append([]string(nil), newCfg.APIKeys...)
+	resolveKey := func(owner string) string {
+		for _, key := range apiKeys {
+			if handlers.APIKeyOwner(key) == owner {
+				return key
+			}
+		}
+		return ""
+	}
+	manager, err := batch.NewManager(newCfg.Batch.Dir, newCfg.Batch.Concurrency, s.handlers, resolveKey)
+	if err != nil {
+		log.Errorf("failed to initialize batch runner: %v", err)
+		return
+	}
+	batch.SetDefault(manager)
+}
+
 // UpdateClients updates the server's client list and configuration.
 // This method is called when the configuration or authentication tokens change.
 //
@@ -927,6 +1114,10 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	if s.handlers != nil && s.handlers.AuthManager != nil {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
+	s.applyResponseStore(oldCfg, cfg)
+	s.applyResponseCache(oldCfg, cfg)
+	s.applyTracing(oldCfg, cfg)
+	s.applyBatch(oldCfg, cfg)
 
 	// Update log level dynamically when debug flag changes
 	if oldCfg == nil || oldCfg.Debug != cfg.Debug {
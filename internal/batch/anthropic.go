@@ -0,0 +1,141 @@
+package batch
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxAnthropicRequests mirrors the Message Batches API limit per batch.
+const maxAnthropicRequests = 100000
+
+// ParseAnthropicRequests parses the body of POST /v1/messages/batches. Every request needs
+// a unique custom_id and Messages API params naming the model; streaming is disabled.
+func ParseAnthropicRequests(rawJSON []byte) ([]Request, error) {
+	items := gjson.GetBytes(rawJSON, "requests")
+	if !items.IsArray() || len(items.Array()) == 0 {
+		return nil, fmt.Errorf("requests: must contain at least one request")
+	}
+	array := items.Array()
+	if len(array) > maxAnthropicRequests {
+		return nil, fmt.Errorf("requests: at most %d requests are allowed", maxAnthropicRequests)
+	}
+	requests := make([]Request, 0, len(array))
+	seen := make(map[string]struct{}, len(array))
+	for i, item := range array {
+		customID := item.Get("custom_id").String()
+		if customID == "" || len(customID) > 64 {
+			return nil, fmt.Errorf("requests.%d.custom_id: must be 1 to 64 characters", i)
+		}
+		if _, dup := seen[customID]; dup {
+			return nil, fmt.Errorf("requests.%d.custom_id: duplicate custom_id %q", i, customID)
+		}
+		seen[customID] = struct{}{}
+		params := item.Get("params")
+		if !params.IsObject() {
+			return nil, fmt.Errorf("requests.%d.params: must be an object", i)
+		}
+		model := params.Get("model").String()
+		if model == "" {
+			return nil, fmt.Errorf("requests.%d.params.model: field required", i)
+		}
+		body := []byte(params.Raw)
+		if params.Get("stream").Exists() {
+			body, _ = sjson.DeleteBytes(body, "stream")
+		}
+		requests = append(requests, Request{CustomID: customID, Model: model, Body: body})
+	}
+	return requests, nil
+}
+
+// AnthropicBatch renders a job as a Message Batch object. resultsURL is reported once the
+// batch has ended.
+func AnthropicBatch(job *Job, resultsURL string) []byte {
+	out := `{"type":"message_batch","archived_at":null,"cancel_initiated_at":null,"ended_at":null,"results_url":null}`
+	out, _ = sjson.Set(out, "id", job.ID)
+	out, _ = sjson.Set(out, "processing_status", anthropicStatus(job.Status))
+	out, _ = sjson.Set(out, "request_counts.processing", job.Processing())
+	out, _ = sjson.Set(out, "request_counts.succeeded", job.Counts.Succeeded)
+	out, _ = sjson.Set(out, "request_counts.errored", job.Counts.Errored)
+	out, _ = sjson.Set(out, "request_counts.canceled", job.Counts.Canceled)
+	out, _ = sjson.Set(out, "request_counts.expired", job.Counts.Expired)
+	out, _ = sjson.Set(out, "created_at", job.CreatedAt.Format(time.RFC3339))
+	out, _ = sjson.Set(out, "expires_at", job.ExpiresAt.Format(time.RFC3339))
+	if job.CancelRequestedAt != nil {
+		out, _ = sjson.Set(out, "cancel_initiated_at", job.CancelRequestedAt.Format(time.RFC3339))
+	}
+	if job.EndedAt != nil {
+		out, _ = sjson.Set(out, "ended_at", job.EndedAt.Format(time.RFC3339))
+		out, _ = sjson.Set(out, "results_url", resultsURL)
+	}
+	return []byte(out)
+}
+
+func anthropicStatus(status string) string {
+	switch status {
+	case StatusInProgress:
+		return "in_progress"
+	case StatusCanceling:
+		return "canceling"
+	default:
+		return "ended"
+	}
+}
+
+// AnthropicResults renders results as Message Batches results JSONL.
+func AnthropicResults(results []Result) []byte {
+	var buf bytes.Buffer
+	for i := range results {
+		result := &results[i]
+		line := `{"custom_id":"","result":{"type":""}}`
+		line, _ = sjson.Set(line, "custom_id", result.CustomID)
+		line, _ = sjson.Set(line, "result.type", result.Outcome)
+		switch result.Outcome {
+		case OutcomeSucceeded:
+			line, _ = sjson.SetRaw(line, "result.message", string(result.Body))
+		case OutcomeErrored:
+			line, _ = sjson.SetRaw(line, "result.error", anthropicErrorBody(result.StatusCode, result.Error))
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// anthropicErrorBody returns the upstream error when it already is an Anthropic error object
+// and wraps the message otherwise.
+func anthropicErrorBody(status int, msg string) string {
+	if gjson.Valid(msg) && gjson.Get(msg, "type").String() == "error" && gjson.Get(msg, "error").IsObject() {
+		return msg
+	}
+	out := `{"type":"error","error":{"type":"","message":""}}`
+	out, _ = sjson.Set(out, "error.type", AnthropicErrorType(status))
+	out, _ = sjson.Set(out, "error.message", msg)
+	return out
+}
+
+// AnthropicErrorType maps an HTTP status to the Anthropic error type.
+func AnthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
@@ -0,0 +1,201 @@
+// Package batch emulates the Anthropic Message Batches API and the OpenAI Batch API.
+// Submitted requests run in the background through the regular auth manager with bounded
+// concurrency, and jobs, inputs and results are persisted to disk so that unfinished
+// batches resume after a restart.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+)
+
+var (
+	// ErrNotFound is returned when a batch or file does not exist.
+	ErrNotFound = errors.New("batch: not found")
+	// ErrInProgress is returned when an operation requires a batch that has ended.
+	ErrInProgress = errors.New("batch: still in progress")
+)
+
+const (
+	// FormatAnthropic marks jobs created through /v1/messages/batches.
+	FormatAnthropic = "anthropic"
+	// FormatOpenAI marks jobs created through /v1/batches.
+	FormatOpenAI = "openai"
+)
+
+const (
+	// StatusInProgress means requests are still being executed.
+	StatusInProgress = "in_progress"
+	// StatusCanceling means cancellation was requested and in-flight requests are finishing.
+	StatusCanceling = "canceling"
+	// StatusCompleted means every request produced a result.
+	StatusCompleted = "completed"
+	// StatusCanceled means the job was canceled before every request ran.
+	StatusCanceled = "canceled"
+	// StatusExpired means the job outlived its completion window.
+	StatusExpired = "expired"
+)
+
+const (
+	// OutcomeSucceeded marks a request that returned a successful response.
+	OutcomeSucceeded = "succeeded"
+	// OutcomeErrored marks a request that failed upstream or in routing.
+	OutcomeErrored = "errored"
+	// OutcomeCanceled marks a request that never ran because the job was canceled.
+	OutcomeCanceled = "canceled"
+	// OutcomeExpired marks a request that never ran because the job expired.
+	OutcomeExpired = "expired"
+)
+
+// completionWindow is how long a job may run before pending requests expire.
+const completionWindow = 24 * time.Hour
+
+// Counts tallies request outcomes of a job.
+type Counts struct {
+	Succeeded int `json:"succeeded"`
+	Errored   int `json:"errored"`
+	Canceled  int `json:"canceled"`
+	Expired   int `json:"expired"`
+}
+
+// Job describes a batch and its progress.
+type Job struct {
+	// ID is the client-visible batch identifier.
+	ID string `json:"id"`
+	// Format is FormatAnthropic or FormatOpenAI.
+	Format string `json:"format"`
+	// HandlerType is the source format passed to the auth manager for every request.
+	HandlerType string `json:"handler_type"`
+	// Endpoint is the OpenAI endpoint the batch targets, e.g. "/v1/chat/completions".
+	Endpoint string `json:"endpoint,omitempty"`
+	// Status is one of the Status constants.
+	Status string `json:"status"`
+	// Total is the number of requests in the batch.
+	Total int `json:"total"`
+	// Counts tallies the requests that already have a result.
+	Counts Counts `json:"counts"`
+	// Metadata is client-supplied metadata echoed back on the batch object.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// InputFileID is the uploaded OpenAI input file.
+	InputFileID string `json:"input_file_id,omitempty"`
+	// OutputFileID holds successful OpenAI results once the job has ended.
+	OutputFileID string `json:"output_file_id,omitempty"`
+	// ErrorFileID holds failed OpenAI results once the job has ended.
+	ErrorFileID string `json:"error_file_id,omitempty"`
+	// CreatedAt is when the job was submitted.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when pending requests stop being executed.
+	ExpiresAt time.Time `json:"expires_at"`
+	// CancelRequestedAt is set when the client canceled the job.
+	CancelRequestedAt *time.Time `json:"cancel_requested_at,omitempty"`
+	// EndedAt is set once every request has an outcome.
+	EndedAt *time.Time `json:"ended_at,omitempty"`
+	// Owner identifies the client that submitted the job, as a digest of its API key. Only
+	// that client can see or change the job; the key itself is never stored.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Processing returns the number of requests without a result yet.
+func (j *Job) Processing() int {
+	done := j.Counts.Succeeded + j.Counts.Errored + j.Counts.Canceled + j.Counts.Expired
+	if done >= j.Total {
+		return 0
+	}
+	return j.Total - done
+}
+
+// Ended reports whether the job has finished, successfully or not.
+func (j *Job) Ended() bool {
+	return j.EndedAt != nil
+}
+
+func (j *Job) clone() *Job {
+	if j == nil {
+		return nil
+	}
+	out := *j
+	if j.Metadata != nil {
+		out.Metadata = make(map[string]string, len(j.Metadata))
+		for k, v := range j.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	return &out
+}
+
+// Request is a single request of a batch.
+type Request struct {
+	// CustomID is the client-supplied identifier used to match results.
+	CustomID string `json:"custom_id"`
+	// Model is the model named in the request body.
+	Model string `json:"model"`
+	// Body is the non-streaming request payload in the job's source format.
+	Body json.RawMessage `json:"body"`
+}
+
+// Result is the outcome of one request.
+type Result struct {
+	// ID uniquely identifies the result line.
+	ID string `json:"id"`
+	// CustomID matches the request.
+	CustomID string `json:"custom_id"`
+	// Outcome is one of the Outcome constants.
+	Outcome string `json:"outcome"`
+	// StatusCode is the HTTP status of the executed request.
+	StatusCode int `json:"status_code,omitempty"`
+	// Body is the response payload of a successful request.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Error is the error message of a failed request.
+	Error string `json:"error,omitempty"`
+}
+
+// File is an uploaded or generated OpenAI file.
+type File struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Purpose   string    `json:"purpose"`
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	// Owner identifies the client that owns the file; only that client can see it.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Executor runs one non-streaming request. *handlers.BaseAPIHandler satisfies it.
+type Executor interface {
+	ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage)
+}
+
+func newID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultManager *Manager
+)
+
+// Default returns the manager used by the batch handlers, or nil when batches are unavailable.
+func Default() *Manager {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultManager
+}
+
+// SetDefault replaces the manager used by the batch handlers and closes the previous one.
+// Requests interrupted by the close are not recorded and resume in the new manager.
+func SetDefault(manager *Manager) {
+	defaultMu.Lock()
+	previous := defaultManager
+	defaultManager = manager
+	defaultMu.Unlock()
+	if previous != nil && previous != manager {
+		previous.Close()
+	}
+}
@@ -0,0 +1,534 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/quota"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultConcurrency bounds in-flight batch requests when no limit is configured.
+const defaultConcurrency = 8
+
+// Manager runs batch jobs in the background and serves their state. All jobs share one
+// concurrency limit so a large batch cannot starve interactive traffic of credentials.
+type Manager struct {
+	store      *store
+	exec       Executor
+	resolveKey KeyResolver
+	sem        chan struct{}
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+// run tracks a job that is currently executing.
+type run struct {
+	mu       sync.Mutex
+	job      *Job
+	cancel   context.CancelFunc
+	canceled bool
+	// apiKey is the submitting client's key, used for quota admission and charging. It is
+	// kept in memory only and resolved from the job owner when the job resumes.
+	apiKey string
+}
+
+// KeyResolver returns the configured client API key whose owner digest is owner, or ""
+// when no configured key matches.
+type KeyResolver func(owner string) string
+
+// NewManager opens the batch directory and resumes every job that had not ended, finding
+// the API keys of resumed jobs through resolveKey. An empty dir uses "batches" under the
+// writable base path or the working directory.
+func NewManager(dir string, concurrency int, exec Executor, resolveKey KeyResolver) (*Manager, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		base := util.WritablePath()
+		if base == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("batch: resolve working directory: %w", err)
+			}
+			base = wd
+		}
+		dir = filepath.Join(base, "batches")
+	}
+	resolved, err := util.ResolveAuthDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("batch: resolve directory: %w", err)
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		store:      newStore(resolved),
+		exec:       exec,
+		resolveKey: resolveKey,
+		sem:        make(chan struct{}, concurrency),
+		ctx:        ctx,
+		stop:       stop,
+		runs:       make(map[string]*run),
+	}
+	m.resume()
+	return m, nil
+}
+
+func (m *Manager) resume() {
+	jobs, err := m.store.listJobs()
+	if err != nil {
+		log.Warnf("batch: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Ended() {
+			continue
+		}
+		log.Infof("batch: resuming %s (%d of %d requests pending)", job.ID, job.Processing(), job.Total)
+		apiKey := ""
+		if job.Owner != "" && m.resolveKey != nil {
+			apiKey = m.resolveKey(job.Owner)
+		}
+		m.start(job, apiKey, job.Status == StatusCanceling)
+	}
+}
+
+// Close stops all running jobs without recording the interrupted requests, so they run
+// again when the batch directory is reopened.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.stop()
+	m.wg.Wait()
+}
+
+// Submit persists a new job with its requests and starts executing it on behalf of apiKey,
+// whose digest the caller sets as the job owner. ID, status, counts and timestamps of the
+// job are assigned here.
+func (m *Manager) Submit(job *Job, apiKey string, requests []Request) (*Job, error) {
+	prefix := "batch_"
+	if job.Format == FormatAnthropic {
+		prefix = "msgbatch_"
+	}
+	now := time.Now().UTC()
+	job.ID = newID(prefix)
+	job.Status = StatusInProgress
+	job.Total = len(requests)
+	job.Counts = Counts{}
+	job.CreatedAt = now
+	job.ExpiresAt = now.Add(completionWindow)
+	job.CancelRequestedAt = nil
+	job.EndedAt = nil
+	if err := m.store.writeRequests(job.ID, requests); err != nil {
+		return nil, err
+	}
+	if err := m.store.saveJob(job); err != nil {
+		return nil, err
+	}
+	snapshot := job.clone()
+	m.start(job, apiKey, false)
+	return snapshot, nil
+}
+
+func (m *Manager) start(job *Job, apiKey string, canceled bool) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	r := &run{job: job, cancel: cancel, canceled: canceled, apiKey: apiKey}
+	if canceled {
+		cancel()
+	}
+	m.mu.Lock()
+	m.runs[job.ID] = r
+	m.mu.Unlock()
+	m.wg.Add(1)
+	go m.process(ctx, r)
+}
+
+func (m *Manager) process(ctx context.Context, r *run) {
+	defer m.wg.Done()
+	defer r.cancel()
+	id := r.job.ID
+
+	requests, err := m.store.readRequests(id)
+	if err != nil {
+		log.Errorf("batch: %s: %v", id, err)
+		m.detach(id)
+		return
+	}
+	results, err := m.store.readResults(id)
+	if err != nil {
+		log.Errorf("batch: %s: %v", id, err)
+		m.detach(id)
+		return
+	}
+	done := make(map[string]struct{}, len(results))
+	r.mu.Lock()
+	r.job.Counts = Counts{}
+	for i := range results {
+		done[results[i].CustomID] = struct{}{}
+		r.job.Counts.add(results[i].Outcome)
+	}
+	expiresAt := r.job.ExpiresAt
+	r.mu.Unlock()
+
+	var inflight sync.WaitGroup
+dispatch:
+	for i := range requests {
+		req := requests[i]
+		if _, ok := done[req.CustomID]; ok {
+			continue
+		}
+		if !expiresAt.IsZero() && time.Now().After(expiresAt) {
+			m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeExpired})
+			continue
+		}
+		select {
+		case m.sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		if ctx.Err() != nil {
+			<-m.sem
+			break
+		}
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			defer func() { <-m.sem }()
+			m.execute(ctx, r, req)
+		}()
+	}
+	inflight.Wait()
+
+	if m.ctx.Err() != nil {
+		// Shutting down: leave the job in progress so it resumes on the next start.
+		m.detach(id)
+		return
+	}
+	m.finish(r, requests)
+}
+
+func (m *Manager) execute(ctx context.Context, r *run, req Request) {
+	if r.job.Owner != "" && r.apiKey == "" {
+		m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeErrored, StatusCode: http.StatusUnauthorized, Error: "the API key that submitted the batch is no longer configured"})
+		return
+	}
+	if status, msg, ok := admit(r.apiKey, req.Model); !ok {
+		m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeErrored, StatusCode: status, Error: msg})
+		return
+	}
+	ctx = cliproxyexecutor.WithClientAPIKey(ctx, r.apiKey)
+	payload, errMsg := m.exec.ExecuteWithAuthManager(ctx, r.job.HandlerType, req.Model, req.Body, "")
+	if ctx.Err() != nil {
+		if m.ctx.Err() != nil {
+			return
+		}
+		m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeCanceled})
+		return
+	}
+	if errMsg != nil {
+		status := errMsg.StatusCode
+		if status <= 0 {
+			status = http.StatusInternalServerError
+		}
+		msg := http.StatusText(status)
+		if errMsg.Error != nil {
+			msg = errMsg.Error.Error()
+		}
+		m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeErrored, StatusCode: status, Error: msg})
+		return
+	}
+	body := json.RawMessage(payload)
+	if !json.Valid(body) {
+		encoded, _ := json.Marshal(string(payload))
+		body = encoded
+	}
+	m.record(r, &Result{CustomID: req.CustomID, Outcome: OutcomeSucceeded, StatusCode: http.StatusOK, Body: body})
+}
+
+func (m *Manager) record(r *run, result *Result) {
+	result.ID = newID("batch_req_")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := m.store.appendResult(r.job.ID, result); err != nil {
+		log.Errorf("batch: %s: %v", r.job.ID, err)
+		return
+	}
+	r.job.Counts.add(result.Outcome)
+	if err := m.store.saveJob(r.job); err != nil {
+		log.Errorf("batch: %s: %v", r.job.ID, err)
+	}
+}
+
+// finish records requests that never ran as canceled, settles the final status and, for
+// OpenAI jobs, writes the output and error files.
+func (m *Manager) finish(r *run, requests []Request) {
+	results, err := m.store.readResults(r.job.ID)
+	if err != nil {
+		log.Errorf("batch: %s: %v", r.job.ID, err)
+		m.detach(r.job.ID)
+		return
+	}
+	done := make(map[string]struct{}, len(results))
+	for i := range results {
+		done[results[i].CustomID] = struct{}{}
+	}
+	for i := range requests {
+		if _, ok := done[requests[i].CustomID]; ok {
+			continue
+		}
+		result := &Result{CustomID: requests[i].CustomID, Outcome: OutcomeCanceled}
+		m.record(r, result)
+		results = append(results, *result)
+	}
+
+	r.mu.Lock()
+	job := r.job
+	switch {
+	case r.canceled:
+		job.Status = StatusCanceled
+	case job.Counts.Expired > 0:
+		job.Status = StatusExpired
+	default:
+		job.Status = StatusCompleted
+	}
+	if job.Format == FormatOpenAI {
+		if errFiles := m.writeOpenAIFiles(job, results); errFiles != nil {
+			log.Errorf("batch: %s: %v", job.ID, errFiles)
+		}
+	}
+	now := time.Now().UTC()
+	job.EndedAt = &now
+	if errSave := m.store.saveJob(job); errSave != nil {
+		log.Errorf("batch: %s: %v", job.ID, errSave)
+	}
+	r.mu.Unlock()
+	m.detach(job.ID)
+}
+
+func (m *Manager) detach(id string) {
+	m.mu.Lock()
+	delete(m.runs, id)
+	m.mu.Unlock()
+}
+
+func (m *Manager) running(id string) *run {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.runs[id]
+}
+
+// Get returns a snapshot of the job. Jobs of another owner are reported as ErrNotFound.
+func (m *Manager) Get(owner, id string) (*Job, error) {
+	var job *Job
+	if r := m.running(id); r != nil {
+		r.mu.Lock()
+		job = r.job.clone()
+		r.mu.Unlock()
+	} else {
+		var err error
+		if job, err = m.store.loadJob(id); err != nil {
+			return nil, err
+		}
+	}
+	if job.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// List returns the jobs of the given format submitted by owner, newest first.
+func (m *Manager) List(owner, format string) ([]*Job, error) {
+	jobs, err := m.store.listJobs()
+	if err != nil {
+		return nil, err
+	}
+	out := jobs[:0]
+	for _, job := range jobs {
+		if job.Format != format || job.Owner != owner {
+			continue
+		}
+		if r := m.running(job.ID); r != nil {
+			r.mu.Lock()
+			job = r.job.clone()
+			r.mu.Unlock()
+		}
+		out = append(out, job)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID > out[j].ID
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// Cancel stops dispatching new requests for the job. In-flight requests are interrupted and
+// recorded as canceled. Canceling an ended job returns it unchanged.
+func (m *Manager) Cancel(owner, id string) (*Job, error) {
+	r := m.running(id)
+	if r == nil {
+		return m.Get(owner, id)
+	}
+	r.mu.Lock()
+	if r.job.Owner != owner {
+		r.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if !r.canceled {
+		now := time.Now().UTC()
+		r.canceled = true
+		r.job.Status = StatusCanceling
+		r.job.CancelRequestedAt = &now
+		if err := m.store.saveJob(r.job); err != nil {
+			log.Errorf("batch: %s: %v", id, err)
+		}
+	}
+	snapshot := r.job.clone()
+	r.mu.Unlock()
+	r.cancel()
+	return snapshot, nil
+}
+
+// Delete removes an ended job and its results.
+func (m *Manager) Delete(owner, id string) error {
+	if _, err := m.Get(owner, id); err != nil {
+		return err
+	}
+	if m.running(id) != nil {
+		return ErrInProgress
+	}
+	return m.store.deleteJob(id)
+}
+
+// Results returns the results of an ended job in completion order.
+func (m *Manager) Results(owner, id string) (*Job, []Result, error) {
+	job, err := m.Get(owner, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.running(id) != nil || !job.Ended() {
+		return nil, nil, ErrInProgress
+	}
+	results, err := m.store.readResults(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, results, nil
+}
+
+// CreateFile stores a file of owner.
+func (m *Manager) CreateFile(owner, filename, purpose string, data []byte) (*File, error) {
+	file := &File{
+		ID:        newID("file-"),
+		Filename:  filename,
+		Purpose:   purpose,
+		Bytes:     int64(len(data)),
+		CreatedAt: time.Now().UTC(),
+		Owner:     owner,
+	}
+	if err := m.store.saveFile(file, data); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// File returns the metadata of a stored file. Files of another owner are reported as
+// ErrNotFound.
+func (m *Manager) File(owner, id string) (*File, error) {
+	file, err := m.store.loadFile(id)
+	if err != nil {
+		return nil, err
+	}
+	if file.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return file, nil
+}
+
+// FileContent returns the content of a stored file.
+func (m *Manager) FileContent(owner, id string) (*File, []byte, error) {
+	file, err := m.File(owner, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := m.store.fileContent(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, data, nil
+}
+
+// Files returns the files of owner, newest first, optionally filtered by purpose.
+func (m *Manager) Files(owner, purpose string) ([]*File, error) {
+	files, err := m.store.listFiles()
+	if err != nil {
+		return nil, err
+	}
+	out := files[:0]
+	for _, file := range files {
+		if file.Owner == owner && (purpose == "" || file.Purpose == purpose) {
+			out = append(out, file)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// DeleteFile removes a stored file.
+func (m *Manager) DeleteFile(owner, id string) error {
+	if _, err := m.File(owner, id); err != nil {
+		return err
+	}
+	return m.store.deleteFile(id)
+}
+
+// admit counts a batch request against the quota of the submitting API key, like the
+// quota middleware does for interactive requests. It returns the status and message to
+// record when the request is refused.
+func admit(apiKey, model string) (int, string, bool) {
+	if apiKey == "" {
+		return 0, "", true
+	}
+	result := quota.GetManager().AdmitRequest(apiKey, model)
+	if result == nil || result.Allowed {
+		return 0, "", true
+	}
+	status, msg := http.StatusForbidden, "Quota exceeded"
+	if result.Error != nil {
+		msg = result.Error.Message
+		if result.Error.Type == quota.QuotaErrorTypeWindowLimitExceeded {
+			status = http.StatusTooManyRequests
+		}
+	}
+	return status, msg, false
+}
+
+func (c *Counts) add(outcome string) {
+	switch outcome {
+	case OutcomeSucceeded:
+		c.Succeeded++
+	case OutcomeErrored:
+		c.Errored++
+	case OutcomeCanceled:
+		c.Canceled++
+	case OutcomeExpired:
+		c.Expired++
+	}
+}
@@ -0,0 +1,228 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+type executorFunc func(ctx context.Context, handlerType, model string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage)
+
+func (f executorFunc) ExecuteWithAuthManager(ctx context.Context, handlerType, model string, rawJSON []byte, _ string) ([]byte, *interfaces.ErrorMessage) {
+	return f(ctx, handlerType, model, rawJSON)
+}
+
+func waitEnded(t *testing.T, m *Manager, owner, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(owner, id)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if job.Ended() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batch %s did not end", id)
+	return nil
+}
+
+func TestManager_AnthropicBatchResults(t *testing.T) {
+	exec := executorFunc(func(_ context.Context, _, _ string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+		if strings.Contains(string(rawJSON), "fail") {
+			return nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New("bad prompt")}
+		}
+		return []byte(`{"type":"message","content":[{"type":"text","text":"ok"}]}`), nil
+	})
+	m, err := NewManager(t.TempDir(), 2, exec, nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	defer m.Close()
+
+	requests, err := ParseAnthropicRequests([]byte(`{"requests":[
+		{"custom_id":"a","params":{"model":"claude","stream":true,"messages":[{"role":"user","content":"hi"}]}},
+		{"custom_id":"b","params":{"model":"claude","messages":[{"role":"user","content":"fail"}]}},
+		{"custom_id":"c","params":{"model":"claude","messages":[{"role":"user","content":"hi"}]}}]}`))
+	if err != nil {
+		t.Fatalf("ParseAnthropicRequests() error = %v", err)
+	}
+	if gjson.GetBytes(requests[0].Body, "stream").Exists() {
+		t.Fatal("stream was not removed from batch params")
+	}
+	job, err := m.Submit(&Job{Format: FormatAnthropic, HandlerType: "claude"}, "", requests)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	job = waitEnded(t, m, "", job.ID)
+	if job.Status != StatusCompleted || job.Counts.Succeeded != 2 || job.Counts.Errored != 1 {
+		t.Fatalf("job = %+v, want completed with 2 succeeded and 1 errored", job)
+	}
+	batchObj := AnthropicBatch(job, "http://host/results")
+	if gjson.GetBytes(batchObj, "processing_status").String() != "ended" || gjson.GetBytes(batchObj, "results_url").String() == "" {
+		t.Fatalf("AnthropicBatch() = %s", batchObj)
+	}
+
+	_, results, err := m.Results("", job.ID)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(AnthropicResults(results))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("results lines = %d, want 3", len(lines))
+	}
+	for _, line := range lines {
+		switch gjson.Get(line, "custom_id").String() {
+		case "b":
+			if gjson.Get(line, "result.type").String() != "errored" || gjson.Get(line, "result.error.error.type").String() != "invalid_request_error" {
+				t.Fatalf("errored line = %s", line)
+			}
+		default:
+			if gjson.Get(line, "result.message.content.0.text").String() != "ok" {
+				t.Fatalf("succeeded line = %s", line)
+			}
+		}
+	}
+}
+
+func TestManager_ResumesOpenAIBatchAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{}, 4)
+	blocking := executorFunc(func(ctx context.Context, _, _ string, _ []byte) ([]byte, *interfaces.ErrorMessage) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: ctx.Err()}
+	})
+	first, err := NewManager(dir, 1, blocking, nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	input := []byte(`{"custom_id":"1","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt","messages":[]}}
+{"custom_id":"2","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt","messages":[]}}`)
+	requests, err := ParseOpenAIInput(input, "/v1/chat/completions")
+	if err != nil {
+		t.Fatalf("ParseOpenAIInput() error = %v", err)
+	}
+	job, err := first.Submit(&Job{Format: FormatOpenAI, HandlerType: "openai", Endpoint: "/v1/chat/completions", Owner: "owner-a"}, "sk-owner", requests)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+	first.Close()
+
+	second, err := NewManager(dir, 2, executorFunc(func(ctx context.Context, _, _ string, _ []byte) ([]byte, *interfaces.ErrorMessage) {
+		if key := cliproxyexecutor.ClientAPIKey(ctx); key != "sk-owner" {
+			return nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: fmt.Errorf("executed as %q, want the submitting key", key)}
+		}
+		return []byte(`{"id":"chatcmpl-1","object":"chat.completion"}`), nil
+	}), func(owner string) string {
+		if owner == "owner-a" {
+			return "sk-owner"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	defer second.Close()
+
+	job = waitEnded(t, second, "owner-a", job.ID)
+	if job.Counts.Succeeded != 2 || job.OutputFileID == "" || job.ErrorFileID != "" {
+		t.Fatalf("job = %+v, want 2 succeeded requests in an output file", job)
+	}
+	_, data, err := second.FileContent("owner-a", job.OutputFileID)
+	if err != nil {
+		t.Fatalf("FileContent() error = %v", err)
+	}
+	if _, err = second.Get("owner-b", job.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() by another key error = %v, want ErrNotFound", err)
+	}
+	if _, _, err = second.FileContent("owner-b", job.OutputFileID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FileContent() by another key error = %v, want ErrNotFound", err)
+	}
+	if jobs, _ := second.List("owner-b", FormatOpenAI); len(jobs) != 0 {
+		t.Fatalf("List() by another key = %d jobs, want none", len(jobs))
+	}
+	if _, err = second.Cancel("owner-b", job.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Cancel() by another key error = %v, want ErrNotFound", err)
+	}
+	if err = second.DeleteFile("owner-b", job.OutputFileID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteFile() by another key error = %v, want ErrNotFound", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || gjson.Get(lines[0], "response.status_code").Int() != http.StatusOK || gjson.Get(lines[0], "response.body.object").String() != "chat.completion" {
+		t.Fatalf("output file = %s", data)
+	}
+	if status := gjson.GetBytes(OpenAIBatch(job), "status").String(); status != "completed" {
+		t.Fatalf("OpenAIBatch() status = %q, want completed", status)
+	}
+	errWalk := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if content, errRead := os.ReadFile(path); errRead == nil && bytes.Contains(content, []byte("sk-owner")) {
+			t.Errorf("%s stores the client API key", path)
+		}
+		return nil
+	})
+	if errWalk != nil {
+		t.Fatalf("walk batch directory: %v", errWalk)
+	}
+}
+
+func TestManager_ResumeWithoutConfiguredKey(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan struct{}, 1)
+	blocking := executorFunc(func(ctx context.Context, _, _ string, _ []byte) ([]byte, *interfaces.ErrorMessage) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: ctx.Err()}
+	})
+	first, err := NewManager(dir, 1, blocking, nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	requests, err := ParseOpenAIInput([]byte(`{"custom_id":"1","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt","messages":[]}}`), "/v1/chat/completions")
+	if err != nil {
+		t.Fatalf("ParseOpenAIInput() error = %v", err)
+	}
+	job, err := first.Submit(&Job{Format: FormatOpenAI, HandlerType: "openai", Endpoint: "/v1/chat/completions", Owner: "owner-removed"}, "sk-removed", requests)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+	first.Close()
+
+	// The submitting key was removed from the configuration, so nothing can be charged to it.
+	second, err := NewManager(dir, 1, executorFunc(func(context.Context, string, string, []byte) ([]byte, *interfaces.ErrorMessage) {
+		t.Error("request executed without the submitting API key")
+		return nil, nil
+	}), func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	defer second.Close()
+	job = waitEnded(t, second, "owner-removed", job.ID)
+	_, results, err := second.Results("owner-removed", job.ID)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if len(results) != 1 || results[0].StatusCode != http.StatusUnauthorized {
+		t.Fatalf("results = %+v, want one 401 error", results)
+	}
+}
@@ -0,0 +1,225 @@
+package batch
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// openAIEndpoints maps the endpoints an OpenAI batch may target to the handler type used
+// to execute their requests.
+var openAIEndpoints = map[string]string{
+	"/v1/chat/completions": constant.OpenAI,
+	"/v1/responses":        constant.OpenaiResponse,
+	"/v1/embeddings":       constant.OpenAIEmbedding,
+}
+
+// OpenAIHandlerType returns the handler type for a batch endpoint, or false when the
+// endpoint is not supported.
+func OpenAIHandlerType(endpoint string) (string, bool) {
+	handlerType, ok := openAIEndpoints[endpoint]
+	return handlerType, ok
+}
+
+// ParseOpenAIInput parses an OpenAI batch input file. Every line must be a POST to the
+// batch endpoint with a unique custom_id and a body naming the model.
+func ParseOpenAIInput(data []byte, endpoint string) ([]Request, error) {
+	var requests []Request
+	seen := make(map[string]struct{})
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		lineNo := n + 1
+		if !gjson.ValidBytes(line) {
+			return nil, fmt.Errorf("line %d: invalid JSON", lineNo)
+		}
+		root := gjson.ParseBytes(line)
+		customID := root.Get("custom_id").String()
+		if customID == "" {
+			return nil, fmt.Errorf("line %d: custom_id is required", lineNo)
+		}
+		if _, dup := seen[customID]; dup {
+			return nil, fmt.Errorf("line %d: duplicate custom_id %q", lineNo, customID)
+		}
+		seen[customID] = struct{}{}
+		if method := root.Get("method").String(); method != "" && !strings.EqualFold(method, http.MethodPost) {
+			return nil, fmt.Errorf("line %d: method must be POST", lineNo)
+		}
+		if url := root.Get("url").String(); url != endpoint {
+			return nil, fmt.Errorf("line %d: url %q does not match batch endpoint %q", lineNo, url, endpoint)
+		}
+		body := root.Get("body")
+		if !body.IsObject() {
+			return nil, fmt.Errorf("line %d: body must be an object", lineNo)
+		}
+		model := body.Get("model").String()
+		if model == "" {
+			return nil, fmt.Errorf("line %d: body.model is required", lineNo)
+		}
+		raw := []byte(body.Raw)
+		if body.Get("stream").Exists() {
+			raw, _ = sjson.DeleteBytes(raw, "stream")
+		}
+		requests = append(requests, Request{CustomID: customID, Model: model, Body: raw})
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("input file contains no requests")
+	}
+	return requests, nil
+}
+
+// OpenAIBatch renders a job as an OpenAI batch object.
+func OpenAIBatch(job *Job) []byte {
+	out := `{"object":"batch","errors":null,"completion_window":"24h","output_file_id":null,"error_file_id":null,"in_progress_at":null,"finalizing_at":null,"completed_at":null,"failed_at":null,"expired_at":null,"cancelling_at":null,"cancelled_at":null,"metadata":null}`
+	out, _ = sjson.Set(out, "id", job.ID)
+	out, _ = sjson.Set(out, "endpoint", job.Endpoint)
+	out, _ = sjson.Set(out, "input_file_id", job.InputFileID)
+	out, _ = sjson.Set(out, "status", openAIStatus(job.Status))
+	out, _ = sjson.Set(out, "created_at", job.CreatedAt.Unix())
+	out, _ = sjson.Set(out, "in_progress_at", job.CreatedAt.Unix())
+	out, _ = sjson.Set(out, "expires_at", job.ExpiresAt.Unix())
+	if job.OutputFileID != "" {
+		out, _ = sjson.Set(out, "output_file_id", job.OutputFileID)
+	}
+	if job.ErrorFileID != "" {
+		out, _ = sjson.Set(out, "error_file_id", job.ErrorFileID)
+	}
+	if job.CancelRequestedAt != nil {
+		out, _ = sjson.Set(out, "cancelling_at", job.CancelRequestedAt.Unix())
+	}
+	if job.EndedAt != nil {
+		field := "completed_at"
+		switch job.Status {
+		case StatusCanceled:
+			field = "cancelled_at"
+		case StatusExpired:
+			field = "expired_at"
+		}
+		out, _ = sjson.Set(out, field, job.EndedAt.Unix())
+	}
+	out, _ = sjson.Set(out, "request_counts.total", job.Total)
+	out, _ = sjson.Set(out, "request_counts.completed", job.Counts.Succeeded)
+	out, _ = sjson.Set(out, "request_counts.failed", job.Counts.Errored+job.Counts.Expired)
+	if len(job.Metadata) > 0 {
+		out, _ = sjson.Set(out, "metadata", job.Metadata)
+	}
+	return []byte(out)
+}
+
+func openAIStatus(status string) string {
+	switch status {
+	case StatusCanceling:
+		return "cancelling"
+	case StatusCanceled:
+		return "cancelled"
+	default:
+		return status
+	}
+}
+
+// OpenAIFile renders a stored file as an OpenAI file object.
+func OpenAIFile(file *File) []byte {
+	out := `{"object":"file","status":"processed"}`
+	out, _ = sjson.Set(out, "id", file.ID)
+	out, _ = sjson.Set(out, "bytes", file.Bytes)
+	out, _ = sjson.Set(out, "created_at", file.CreatedAt.Unix())
+	out, _ = sjson.Set(out, "filename", file.Filename)
+	out, _ = sjson.Set(out, "purpose", file.Purpose)
+	return []byte(out)
+}
+
+// writeOpenAIFiles stores successful results in the output file and failed or expired
+// results in the error file, using the OpenAI batch output line format.
+func (m *Manager) writeOpenAIFiles(job *Job, results []Result) error {
+	var output, errorsOut bytes.Buffer
+	for i := range results {
+		result := &results[i]
+		line := `{"response":null,"error":null}`
+		line, _ = sjson.Set(line, "id", result.ID)
+		line, _ = sjson.Set(line, "custom_id", result.CustomID)
+		switch result.Outcome {
+		case OutcomeSucceeded:
+			line, _ = sjson.Set(line, "response.status_code", result.StatusCode)
+			line, _ = sjson.Set(line, "response.request_id", result.ID)
+			line, _ = sjson.SetRaw(line, "response.body", string(result.Body))
+			output.WriteString(line)
+			output.WriteByte('\n')
+		case OutcomeErrored:
+			line, _ = sjson.Set(line, "response.status_code", result.StatusCode)
+			line, _ = sjson.Set(line, "response.request_id", result.ID)
+			line, _ = sjson.SetRaw(line, "response.body", openAIErrorBody(result.StatusCode, result.Error))
+			errorsOut.WriteString(line)
+			errorsOut.WriteByte('\n')
+		case OutcomeExpired:
+			line, _ = sjson.Set(line, "error.code", "batch_expired")
+			line, _ = sjson.Set(line, "error.message", "This request could not be executed before the completion window expired.")
+			errorsOut.WriteString(line)
+			errorsOut.WriteByte('\n')
+		}
+	}
+	if output.Len() > 0 {
+		file, err := m.CreateFile(job.Owner, job.ID+"_output.jsonl", "batch_output", output.Bytes())
+		if err != nil {
+			return err
+		}
+		job.OutputFileID = file.ID
+	}
+	if errorsOut.Len() > 0 {
+		file, err := m.CreateFile(job.Owner, job.ID+"_error.jsonl", "batch_output", errorsOut.Bytes())
+		if err != nil {
+			return err
+		}
+		job.ErrorFileID = file.ID
+	}
+	return nil
+}
+
+// openAIErrorBody returns the upstream error when it already is an OpenAI error object and
+// wraps the message otherwise.
+func openAIErrorBody(status int, msg string) string {
+	if gjson.Valid(msg) && gjson.Get(msg, "error").IsObject() {
+		return msg
+	}
+	out := `{"error":{"message":"","type":""}}`
+	out, _ = sjson.Set(out, "error.message", msg)
+	out, _ = sjson.Set(out, "error.type", openAIErrorType(status))
+	return out
+}
+
+func openAIErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	default:
+		return "server_error"
+	}
+}
+
+// OpenAIList wraps rendered OpenAI objects in a list object.
+func OpenAIList(items [][]byte, hasMore bool) []byte {
+	out := `{"object":"list","data":[],"has_more":false}`
+	for _, item := range items {
+		out, _ = sjson.SetRaw(out, "data.-1", string(item))
+	}
+	if len(items) > 0 {
+		out, _ = sjson.Set(out, "first_id", gjson.GetBytes(items[0], "id").String())
+		out, _ = sjson.Set(out, "last_id", gjson.GetBytes(items[len(items)-1], "id").String())
+	}
+	out, _ = sjson.Set(out, "has_more", hasMore)
+	return []byte(out)
+}
@@ -0,0 +1,272 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// store persists jobs under <dir>/jobs and files under <dir>/files. Every job keeps its
+// metadata in <id>.json, its requests in <id>.input.jsonl and its results, appended as
+// they complete, in <id>.results.jsonl.
+type store struct {
+	jobsDir  string
+	filesDir string
+}
+
+// newStore does not touch the disk; directories are created on the first write.
+func newStore(dir string) *store {
+	return &store{jobsDir: filepath.Join(dir, "jobs"), filesDir: filepath.Join(dir, "files")}
+}
+
+func validID(id string) bool {
+	id = strings.TrimSpace(id)
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *store) saveJob(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("batch: marshal job: %w", err)
+	}
+	if err = writeAtomic(filepath.Join(s.jobsDir, job.ID+".json"), data); err != nil {
+		return fmt.Errorf("batch: write job: %w", err)
+	}
+	return nil
+}
+
+func (s *store) loadJob(id string) (*Job, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.jobsDir, id+".json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("batch: read job: %w", err)
+	}
+	var job Job
+	if err = json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("batch: decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (s *store) listJobs() ([]*Job, error) {
+	matches, err := filepath.Glob(filepath.Join(s.jobsDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("batch: list jobs: %w", err)
+	}
+	jobs := make([]*Job, 0, len(matches))
+	for _, path := range matches {
+		job, errLoad := s.loadJob(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if errLoad != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *store) deleteJob(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(filepath.Join(s.jobsDir, id+".json")); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("batch: delete job: %w", err)
+	}
+	_ = os.Remove(filepath.Join(s.jobsDir, id+".input.jsonl"))
+	_ = os.Remove(filepath.Join(s.jobsDir, id+".results.jsonl"))
+	return nil
+}
+
+func (s *store) writeRequests(id string, requests []Request) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range requests {
+		if err := enc.Encode(&requests[i]); err != nil {
+			return fmt.Errorf("batch: encode request: %w", err)
+		}
+	}
+	if err := writeAtomic(filepath.Join(s.jobsDir, id+".input.jsonl"), buf.Bytes()); err != nil {
+		return fmt.Errorf("batch: write requests: %w", err)
+	}
+	return nil
+}
+
+func (s *store) readRequests(id string) ([]Request, error) {
+	var requests []Request
+	err := readJSONLines(filepath.Join(s.jobsDir, id+".input.jsonl"), func(line []byte) error {
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return err
+		}
+		requests = append(requests, req)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch: read requests: %w", err)
+	}
+	return requests, nil
+}
+
+func (s *store) appendResult(id string, result *Result) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("batch: marshal result: %w", err)
+	}
+	if err = os.MkdirAll(s.jobsDir, 0o700); err != nil {
+		return fmt.Errorf("batch: create directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.jobsDir, id+".results.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("batch: open results: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("batch: write result: %w", err)
+	}
+	return nil
+}
+
+// readResults returns the recorded results. A line truncated by a crash is ignored so the
+// request runs again.
+func (s *store) readResults(id string) ([]Result, error) {
+	var results []Result
+	err := readJSONLines(filepath.Join(s.jobsDir, id+".results.jsonl"), func(line []byte) error {
+		var result Result
+		if json.Unmarshal(line, &result) == nil && result.CustomID != "" {
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("batch: read results: %w", err)
+	}
+	return results, nil
+}
+
+func readJSONLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	reader := bufio.NewReader(f)
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if err = fn(trimmed); err != nil {
+				return err
+			}
+		}
+		if errRead != nil {
+			if errors.Is(errRead, io.EOF) {
+				return nil
+			}
+			return errRead
+		}
+	}
+}
+
+func (s *store) saveFile(file *File, data []byte) error {
+	if err := writeAtomic(filepath.Join(s.filesDir, file.ID+".data"), data); err != nil {
+		return fmt.Errorf("batch: write file content: %w", err)
+	}
+	meta, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("batch: marshal file: %w", err)
+	}
+	if err = writeAtomic(filepath.Join(s.filesDir, file.ID+".json"), meta); err != nil {
+		return fmt.Errorf("batch: write file: %w", err)
+	}
+	return nil
+}
+
+func (s *store) loadFile(id string) (*File, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.filesDir, id+".json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("batch: read file: %w", err)
+	}
+	var file File
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("batch: decode file %s: %w", id, err)
+	}
+	return &file, nil
+}
+
+func (s *store) fileContent(id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.filesDir, id+".data"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("batch: read file content: %w", err)
+	}
+	return data, nil
+}
+
+func (s *store) listFiles() ([]*File, error) {
+	matches, err := filepath.Glob(filepath.Join(s.filesDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("batch: list files: %w", err)
+	}
+	files := make([]*File, 0, len(matches))
+	for _, path := range matches {
+		file, errLoad := s.loadFile(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if errLoad != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (s *store) deleteFile(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(filepath.Join(s.filesDir, id+".json")); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("batch: delete file: %w", err)
+	}
+	_ = os.Remove(filepath.Join(s.filesDir, id+".data"))
+	return nil
+}
@@ -25,6 +25,44 @@ type APIKeyPolicy struct {
 	// ExpiresAt is the expiration date for this key (format: "2006-01-02" or RFC3339).
 	// Empty means no expiration.
 	ExpiresAt string `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+
+	// Limits defines windowed limits that reset automatically at the end of each window.
+	// Nil means no windowed limits.
+	Limits *APIKeyWindowLimits `yaml:"limits,omitempty" json:"limits,omitempty"`
+}
+
+// APIKeyWindowLimits groups the windowed limits of an API key policy.
+// Windows are aligned to UTC calendar boundaries (minute, hour, day, month).
+type APIKeyWindowLimits struct {
+	// Minute limits usage within the current UTC minute.
+	Minute *APIKeyWindowLimit `yaml:"minute,omitempty" json:"minute,omitempty"`
+
+	// Hour limits usage within the current UTC hour.
+	Hour *APIKeyWindowLimit `yaml:"hour,omitempty" json:"hour,omitempty"`
+
+	// Day limits usage within the current UTC day.
+	Day *APIKeyWindowLimit `yaml:"day,omitempty" json:"day,omitempty"`
+
+	// Month limits usage within the current UTC calendar month.
+	Month *APIKeyWindowLimit `yaml:"month,omitempty" json:"month,omitempty"`
+}
+
+// APIKeyWindowLimit defines the limits applied within a single window.
+// Zero values mean unlimited.
+type APIKeyWindowLimit struct {
+	// Requests is the maximum number of requests within the window.
+	Requests int64 `yaml:"requests,omitempty" json:"requests,omitempty"`
+
+	// Tokens is the maximum total tokens within the window.
+	Tokens int64 `yaml:"tokens,omitempty" json:"tokens,omitempty"`
+
+	// CostUSD is the maximum cost in USD within the window.
+	CostUSD float64 `yaml:"cost_usd,omitempty" json:"cost_usd,omitempty"`
+}
+
+// IsZero reports whether the limit imposes no restriction.
+func (l *APIKeyWindowLimit) IsZero() bool {
+	return l == nil || (l.Requests <= 0 && l.Tokens <= 0 && l.CostUSD <= 0)
 }
 
 // ParsedExpiresAt returns the parsed expiration time.
@@ -60,6 +98,15 @@ func (p *APIKeyPolicy) HasCostLimit() bool {
 	return p != nil && p.MaxCostUSD > 0
 }
 
+// HasWindowLimits returns true if this policy has at least one windowed limit.
+func (p *APIKeyPolicy) HasWindowLimits() bool {
+	if p == nil || p.Limits == nil {
+		return false
+	}
+	l := p.Limits
+	return !l.Minute.IsZero() || !l.Hour.IsZero() || !l.Day.IsZero() || !l.Month.IsZero()
+}
+
 // HasExpiration returns true if this policy has an expiration date.
 func (p *APIKeyPolicy) HasExpiration() bool {
 	return p != nil && p.ExpiresAt != ""
@@ -95,5 +142,5 @@ func (p *APIKeyPolicy) HasAnyRestriction() bool {
 	if p == nil {
 		return false
 	}
-	return p.HasModelRestriction() || p.HasTokenLimit() || p.HasCostLimit() || p.HasExpiration()
+	return p.HasModelRestriction() || p.HasTokenLimit() || p.HasCostLimit() || p.HasExpiration() || p.HasWindowLimits()
 }
@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
@@ -49,6 +50,9 @@ type Config struct {
 	// When exceeded, the oldest log files are deleted until within the limit. Set to 0 to disable.
 	LogsMaxTotalSizeMB int `yaml:"logs-max-total-size-mb" json:"logs-max-total-size-mb"`
 
+	// RequestLogSink selects how request logs are written when request-log is enabled.
+	RequestLogSink RequestLogSinkConfig `yaml:"request-log-sink,omitempty" json:"request-log-sink,omitempty"`
+
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
@@ -66,6 +70,23 @@ type Config struct {
 	// Routing controls credential selection behavior.
 	Routing RoutingConfig `yaml:"routing" json:"routing"`
 
+	// ResponseStore configures persistence of OpenAI Responses API objects used to resolve
+	// previous_response_id and to serve /v1/responses/{id}.
+	ResponseStore ResponseStoreConfig `yaml:"response-store,omitempty" json:"response-store,omitempty"`
+
+	// ResponseCache configures the opt-in exact-match cache for deterministic requests.
+	ResponseCache ResponseCacheConfig `yaml:"response-cache,omitempty" json:"response-cache,omitempty"`
+
+	// Batch configures the background runner behind the Anthropic Message Batches and
+	// OpenAI Batch API endpoints.
+	Batch BatchConfig `yaml:"batch,omitempty" json:"batch,omitempty"`
+
+	// Metrics exposes Prometheus metrics at /metrics.
+	Metrics MetricsConfig `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+
+	// Tracing exports OpenTelemetry spans for proxied requests. Disabled by default.
+	Tracing TracingConfig `yaml:"tracing,omitempty" json:"tracing,omitempty"`
+
 	// WebsocketAuth enables or disables authentication for the WebSocket API.
 	WebsocketAuth bool `yaml:"ws-auth" json:"ws-auth"`
 
@@ -106,6 +127,11 @@ type Config struct {
 	// gemini-api-key, codex-api-key, claude-api-key, openai-compatibility, vertex-api-key, and ampcode.
 	OAuthModelMappings map[string][]ModelNameMapping `yaml:"oauth-model-mappings,omitempty" json:"oauth-model-mappings,omitempty"`
 
+	// ModelFallbacks maps a requested model to the models tried, in order, when every
+	// credential of the requested model is exhausted or fails with 429/5xx before
+	// the first byte. Fallback models may be served by different providers.
+	ModelFallbacks map[string][]string `yaml:"model-fallbacks,omitempty" json:"model-fallbacks,omitempty"`
+
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
@@ -153,8 +179,241 @@ type QuotaExceeded struct {
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first".
+	// Supported values: "round-robin" (default), "fill-first", "priority", "weighted", "adaptive".
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// Concurrency bounds in-flight requests per credential and queues requests when all are busy.
+	Concurrency ConcurrencyConfig `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// CircuitBreaker stops routing to upstream endpoints that keep failing.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker,omitempty" json:"circuit-breaker,omitempty"`
+
+	// Hedging fires a second attempt for slow non-streaming requests.
+	Hedging HedgingConfig `yaml:"hedging,omitempty" json:"hedging,omitempty"`
+
+	// SessionAffinity pins client sessions to a single credential.
+	SessionAffinity SessionAffinityConfig `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
+}
+
+// ConcurrencyConfig limits how many requests may be in flight on a single credential.
+// A credential's own max-concurrency (config key field or auth file "max_concurrency")
+// takes precedence over the provider default. When every eligible credential is
+// saturated, requests wait in a bounded queue until a slot frees up or the queue
+// timeout elapses.
+type ConcurrencyConfig struct {
+	// ProviderLimits maps a provider key (e.g. "claude", "github-copilot") to its default per-credential limit.
+	ProviderLimits map[string]int `yaml:"provider-limits,omitempty" json:"provider-limits,omitempty"`
+
+	// QueueSize is the maximum number of requests waiting for a free credential. <= 0 uses 64.
+	QueueSize int `yaml:"queue-size,omitempty" json:"queue-size,omitempty"`
+
+	// QueueTimeoutSeconds is how long a queued request waits before failing with 429. <= 0 uses 30.
+	QueueTimeoutSeconds int `yaml:"queue-timeout-seconds,omitempty" json:"queue-timeout-seconds,omitempty"`
+}
+
+// CircuitBreakerConfig configures the circuit breaker kept per upstream endpoint
+// (provider, base-url and proxy). After FailureThreshold consecutive network errors
+// or 5xx responses within WindowSeconds the breaker opens and every credential
+// behind that endpoint is skipped for OpenSeconds, after which a single probe
+// request decides whether it closes again.
+type CircuitBreakerConfig struct {
+	// Enabled toggles the circuit breaker.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// FailureThreshold is the number of consecutive failures that opens the breaker. <= 0 uses 5.
+	FailureThreshold int `yaml:"failure-threshold,omitempty" json:"failure-threshold,omitempty"`
+
+	// WindowSeconds bounds the time span of the consecutive failures. <= 0 uses 60.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// OpenSeconds is how long the breaker stays open before probing. <= 0 uses 30.
+	OpenSeconds int `yaml:"open-seconds,omitempty" json:"open-seconds,omitempty"`
+}
+
+// HedgingConfig configures hedged non-streaming requests. When the first credential
+// has not answered within the configured latency percentile, the same request is sent
+// to a second credential and the first successful response wins; the slower attempt
+// is cancelled and not recorded.
+type HedgingConfig struct {
+	// Enabled toggles hedging. Streaming requests are never hedged.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Percentile of recent per-model latencies after which the hedge fires. <= 0 uses 95.
+	Percentile float64 `yaml:"percentile,omitempty" json:"percentile,omitempty"`
+
+	// InitialDelayMs is the hedge delay used until enough latencies are observed. <= 0 uses 2000.
+	InitialDelayMs int `yaml:"initial-delay-ms,omitempty" json:"initial-delay-ms,omitempty"`
+
+	// MinDelayMs is the lower bound of the hedge delay.
+	MinDelayMs int `yaml:"min-delay-ms,omitempty" json:"min-delay-ms,omitempty"`
+
+	// Models restricts hedging to the listed models. Empty hedges all non-streaming requests.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// SessionAffinityConfig configures session-sticky credential routing.
+// Requests are grouped by the X-Session-Id header, the Claude metadata.user_id
+// or the OpenAI/Codex prompt_cache_key, and each group keeps using the same
+// credential until it idles out or becomes unavailable.
+type SessionAffinityConfig struct {
+	// Enabled toggles session-sticky routing.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// TTLSeconds is how long an idle session stays pinned. <= 0 uses the default of 3600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+}
+
+// DefaultSessionAffinityTTLSeconds is the idle TTL applied when session affinity is enabled without an explicit TTL.
+const DefaultSessionAffinityTTLSeconds = 3600
+
+// TTL returns the effective session pin TTL, or zero when session affinity is disabled.
+func (c SessionAffinityConfig) TTL() time.Duration {
+	if !c.Enabled {
+		return 0
+	}
+	if c.TTLSeconds <= 0 {
+		return DefaultSessionAffinityTTLSeconds * time.Second
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// ResponseStoreConfig selects the backend that stores OpenAI Responses API objects.
+// Responses created with "store": false are never persisted.
+type ResponseStoreConfig struct {
+	// Backend is one of "memory", "file", "postgres" or "none". Empty disables storage.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+
+	// Dir is the directory used by the file backend. Empty uses "responses" under the writable path.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// DSN is the PostgreSQL connection string used by the postgres backend.
+	DSN string `yaml:"dsn,omitempty" json:"dsn,omitempty"`
+
+	// Schema optionally places the postgres table in a dedicated schema.
+	Schema string `yaml:"schema,omitempty" json:"schema,omitempty"`
+
+	// Table overrides the postgres table name (default "response_store").
+	Table string `yaml:"table,omitempty" json:"table,omitempty"`
+
+	// TTLHours expires stored responses after the given number of hours. <= 0 keeps them indefinitely.
+	TTLHours int `yaml:"ttl-hours,omitempty" json:"ttl-hours,omitempty"`
+
+	// MaxEntries bounds the memory backend. <= 0 uses 10000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+}
+
+// ResponseCacheConfig controls the exact-match response cache. Only requests with a
+// temperature of 0 are cached unless CacheNondeterministic is set.
+type ResponseCacheConfig struct {
+	// Enabled turns the cache on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Backend is one of "memory" (default), "file" or "redis".
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+
+	// TTLSeconds expires cached responses. <= 0 uses 3600.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// MaxEntries bounds the memory backend. <= 0 uses 1000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+
+	// MaxEntryBytes skips caching responses larger than this size. <= 0 uses 1 MiB.
+	MaxEntryBytes int `yaml:"max-entry-bytes,omitempty" json:"max-entry-bytes,omitempty"`
+
+	// Dir is the directory used by the file backend. Empty uses "response-cache" under the writable path.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// RedisURL addresses a Redis-compatible server, e.g. "redis://:password@localhost:6379/0".
+	RedisURL string `yaml:"redis-url,omitempty" json:"redis-url,omitempty"`
+
+	// KeyPrefix namespaces keys in the redis backend (default "cliproxy:response-cache:").
+	KeyPrefix string `yaml:"key-prefix,omitempty" json:"key-prefix,omitempty"`
+
+	// CacheNondeterministic also caches requests whose temperature is unset or non-zero.
+	CacheNondeterministic bool `yaml:"cache-nondeterministic,omitempty" json:"cache-nondeterministic,omitempty"`
+
+	// Scope is "key" (default) to reuse responses only for the client API key that produced
+	// them, or "global" to share them between all clients.
+	Scope string `yaml:"scope,omitempty" json:"scope,omitempty"`
+}
+
+// MetricsConfig controls the Prometheus /metrics endpoint.
+type MetricsConfig struct {
+	// Enabled serves /metrics. Metrics are collected either way.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Token, when set, must be sent as "Authorization: Bearer <token>" to scrape /metrics.
+	// The endpoint does not accept client API keys.
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+// RequestLogSinkConfig selects the request log format. "text" (default) writes one
+// human-readable file per request; "jsonl" appends one redacted JSON object per request to
+// a rotating requests.jsonl under the logs directory. Changing the format requires a restart.
+type RequestLogSinkConfig struct {
+	// Format is "text" or "jsonl".
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// MaxSizeMB rotates requests.jsonl once it reaches this size (default 100).
+	MaxSizeMB int `yaml:"max-size-mb,omitempty" json:"max-size-mb,omitempty"`
+
+	// MaxBackups is the number of rotated files kept (default 10, 0 uses the default).
+	MaxBackups int `yaml:"max-backups,omitempty" json:"max-backups,omitempty"`
+
+	// MaxAgeDays removes rotated files older than this many days. 0 keeps them.
+	MaxAgeDays int `yaml:"max-age-days,omitempty" json:"max-age-days,omitempty"`
+
+	// Compress gzips rotated files.
+	Compress bool `yaml:"compress,omitempty" json:"compress,omitempty"`
+
+	// MaxBodyBytes caps every logged body (default 262144). Negative disables the cap.
+	MaxBodyBytes int `yaml:"max-body-bytes,omitempty" json:"max-body-bytes,omitempty"`
+
+	// RedactHeaders lists extra header names whose values are replaced. Authorization,
+	// API key and cookie headers are always redacted.
+	RedactHeaders []string `yaml:"redact-headers,omitempty" json:"redact-headers,omitempty"`
+
+	// RedactFields lists extra JSON body keys whose string values are replaced. Keys such
+	// as api_key, access_token, refresh_token and client_secret are always redacted.
+	RedactFields []string `yaml:"redact-fields,omitempty" json:"redact-fields,omitempty"`
+}
+
+// TracingConfig controls OpenTelemetry tracing. Incoming W3C traceparent headers are
+// honoured when tracing is enabled.
+type TracingConfig struct {
+	// Enabled turns span export on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Exporter is one of "otlp-http" (default), "otlp-grpc" or "stdout".
+	Exporter string `yaml:"exporter,omitempty" json:"exporter,omitempty"`
+
+	// Endpoint is the collector address, e.g. "localhost:4318" for otlp-http or
+	// "localhost:4317" for otlp-grpc. Empty uses the OTEL_EXPORTER_OTLP_* environment or
+	// the exporter default.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Insecure disables TLS towards the collector.
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+
+	// Headers are sent with every export request, e.g. collector API keys.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// SampleRatio is the fraction of new traces recorded. <= 0 or >= 1 records all traces.
+	// Traces started by a sampled client traceparent are always recorded.
+	SampleRatio float64 `yaml:"sample-ratio,omitempty" json:"sample-ratio,omitempty"`
+
+	// ServiceName is reported as service.name (default "cli-proxy-api").
+	ServiceName string `yaml:"service-name,omitempty" json:"service-name,omitempty"`
+}
+
+// BatchConfig controls where batch jobs and uploaded files are persisted and how many
+// batch requests run at the same time across all jobs.
+type BatchConfig struct {
+	// Dir stores batch jobs and files. Empty uses "batches" under the writable path.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Concurrency bounds the number of batch requests executing at once. <= 0 uses 8.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
 }
 
 // ModelNameMapping defines a model ID mapping for a specific channel.
@@ -257,6 +516,15 @@ type ClaudeKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/claude-sonnet-4").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Priority orders credentials for the "priority" routing strategy; lower values are used first.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets the relative share of requests for the "weighted" routing strategy (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of in-flight requests on this credential. 0 uses the provider default.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// BaseURL is the base URL for the Claude API endpoint.
 	// If empty, the default Claude API URL will be used.
 	BaseURL string `yaml:"base-url" json:"base-url"`
@@ -295,6 +563,15 @@ type CodexKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gpt-5-codex").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Priority orders credentials for the "priority" routing strategy; lower values are used first.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets the relative share of requests for the "weighted" routing strategy (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of in-flight requests on this credential. 0 uses the provider default.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// BaseURL is the base URL for the Codex API endpoint.
 	// If empty, the default Codex API URL will be used.
 	BaseURL string `yaml:"base-url" json:"base-url"`
@@ -333,6 +610,15 @@ type GeminiKey struct {
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gemini-3-pro-preview").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
+	// Priority orders credentials for the "priority" routing strategy; lower values are used first.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets the relative share of requests for the "weighted" routing strategy (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps the number of in-flight requests on this credential. 0 uses the provider default.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// BaseURL optionally overrides the Gemini API endpoint.
 	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
 
@@ -402,6 +688,11 @@ type OpenAICompatibility struct {
 	// BaseURL is the base URL for the external OpenAI-compatible API endpoint.
 	BaseURL string `yaml:"base-url" json:"base-url"`
 
+	// Protocol selects the upstream wire format: "openai" (default) for /chat/completions,
+	// "claude" for Anthropic-compatible /v1/messages, or "gemini" for generateContent.
+	// Requests already in that format are forwarded without translation.
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+
 	// APIKeyEntries defines API keys with optional per-key proxy configuration.
 	APIKeyEntries []OpenAICompatibilityAPIKey `yaml:"api-key-entries,omitempty" json:"api-key-entries,omitempty"`
 
@@ -429,6 +720,10 @@ type OpenAICompatibilityModel struct {
 
 	// Alias is the model name alias that clients will use to reference this model.
 	Alias string `yaml:"alias" json:"alias"`
+
+	// Embedding marks the model as an embeddings model served by the provider's /embeddings
+	// endpoint. Only marked models are accepted by the embeddings endpoints.
+	Embedding bool `yaml:"embedding,omitempty" json:"embedding,omitempty"`
 }
 
 // LoadConfig reads a YAML configuration file from the given path,
@@ -548,6 +843,9 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Normalize global OAuth model name mappings.
 	cfg.SanitizeOAuthModelMappings()
 
+	// Normalize model fallback chains.
+	cfg.SanitizeModelFallbacks()
+
 	if cfg.legacyMigrationPending {
 		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
 		if !optional && configFile != "" {
@@ -608,6 +906,49 @@ func (cfg *Config) SanitizeOAuthModelMappings() {
 	cfg.OAuthModelMappings = out
 }
 
+// SanitizeModelFallbacks trims model names, drops empty chains and removes
+// self-references and duplicates from each fallback chain.
+func (cfg *Config) SanitizeModelFallbacks() {
+	if cfg == nil || len(cfg.ModelFallbacks) == 0 {
+		return
+	}
+	cfg.ModelFallbacks = NormalizeModelFallbacks(cfg.ModelFallbacks)
+}
+
+// NormalizeModelFallbacks returns a copy of fallbacks with trimmed model names, without
+// empty chains and without self-references or case-insensitive duplicates in a chain.
+// It returns nil when no chain remains.
+func NormalizeModelFallbacks(fallbacks map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(fallbacks))
+	for rawModel, chain := range fallbacks {
+		model := strings.TrimSpace(rawModel)
+		if model == "" {
+			continue
+		}
+		seen := map[string]struct{}{strings.ToLower(model): {}}
+		clean := make([]string, 0, len(chain))
+		for _, next := range chain {
+			next = strings.TrimSpace(next)
+			key := strings.ToLower(next)
+			if next == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			clean = append(clean, next)
+		}
+		if len(clean) > 0 {
+			out[model] = clean
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
 // SanitizeOpenAICompatibility removes OpenAI-compatibility provider entries that are
 // not actionable, specifically those missing a BaseURL. It trims whitespace before
 // evaluation and preserves the relative order of remaining entries.
@@ -621,6 +962,7 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 		e.Name = strings.TrimSpace(e.Name)
 		e.Prefix = normalizeModelPrefix(e.Prefix)
 		e.BaseURL = strings.TrimSpace(e.BaseURL)
+		e.Protocol = normalizeCompatProtocol(e.Protocol)
 		e.Headers = NormalizeHeaders(e.Headers)
 		if e.BaseURL == "" {
 			// Skip providers with no base-url; treated as removed
@@ -631,6 +973,17 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 	cfg.OpenAICompatibility = out
 }
 
+// normalizeCompatProtocol lower-cases the protocol of a compatibility provider. The default
+// "openai" protocol and unknown values are stored as empty.
+func normalizeCompatProtocol(protocol string) string {
+	switch p := strings.ToLower(strings.TrimSpace(protocol)); p {
+	case "claude", "gemini":
+		return p
+	default:
+		return ""
+	}
+}
+
 // SanitizeCodexKeys removes Codex API key entries missing a BaseURL.
 // It trims whitespace and preserves order for remaining entries.
 func (cfg *Config) SanitizeCodexKeys() {
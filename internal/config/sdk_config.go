@@ -29,6 +29,41 @@ type SDKConfig struct {
 
 	// Streaming configures server-side streaming behavior (keep-alives and safe bootstrap retries).
 	Streaming StreamingConfig `yaml:"streaming" json:"streaming"`
+
+	// ContextOverflow defines per-model policies applied when a request exceeds the model's
+	// context window. Models without a matching rule are forwarded unchanged.
+	ContextOverflow []ContextOverflowRule `yaml:"context-overflow,omitempty" json:"context-overflow,omitempty"`
+}
+
+const (
+	// ContextOverflowReject fails oversized requests before they reach the upstream.
+	ContextOverflowReject = "reject"
+
+	// ContextOverflowTruncate drops the oldest non-system turns until the request fits.
+	ContextOverflowTruncate = "truncate"
+
+	// ContextOverflowSummarize replaces the middle turns with a summary written by SummaryModel.
+	ContextOverflowSummarize = "summarize"
+)
+
+// ContextOverflowRule configures how oversized requests for a set of models are handled.
+type ContextOverflowRule struct {
+	// Models lists model names the rule applies to; "*" wildcards are supported.
+	Models []string `yaml:"models" json:"models"`
+
+	// Strategy is one of "reject", "truncate" or "summarize".
+	Strategy string `yaml:"strategy" json:"strategy"`
+
+	// ReserveTokens keeps additional room below the limit, e.g. for tool output. Default 0.
+	ReserveTokens int `yaml:"reserve-tokens,omitempty" json:"reserve-tokens,omitempty"`
+
+	// SummaryModel is the model used by the "summarize" strategy. It is called with an
+	// OpenAI chat completions request.
+	SummaryModel string `yaml:"summary-model,omitempty" json:"summary-model,omitempty"`
+
+	// KeepRecentTurns is the number of latest turns the "summarize" strategy never compresses.
+	// Default 4.
+	KeepRecentTurns int `yaml:"keep-recent-turns,omitempty" json:"keep-recent-turns,omitempty"`
 }
 
 // StreamingConfig holds server streaming behavior configuration.
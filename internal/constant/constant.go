@@ -22,6 +22,21 @@ const (
 	// OpenaiResponse represents the OpenAI response format identifier.
 	OpenaiResponse = "openai-response"
 
+	// OpenAIEmbedding represents the OpenAI embeddings request format identifier.
+	OpenAIEmbedding = "openai-embedding"
+
+	// GeminiEmbedding represents the Gemini batchEmbedContents request format identifier.
+	GeminiEmbedding = "gemini-embedding"
+
+	// OpenAIImage represents the OpenAI Images API request format identifier.
+	OpenAIImage = "openai-image"
+
+	// OpenAITranscription represents the OpenAI audio transcriptions request format identifier.
+	OpenAITranscription = "openai-transcription"
+
+	// OpenAISpeech represents the OpenAI audio speech request format identifier.
+	OpenAISpeech = "openai-speech"
+
 	// Antigravity represents the Antigravity response format identifier.
 	Antigravity = "antigravity"
 
@@ -0,0 +1,122 @@
+// Package imagestore keeps generated images on disk for a limited time so the Images API
+// can answer response_format "url" requests with links served by the proxy itself.
+package imagestore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// TTL is how long a stored image stays available.
+const TTL = time.Hour
+
+// ErrNotFound is returned when an image does not exist or has expired.
+var ErrNotFound = errors.New("imagestore: image not found")
+
+// validName matches the names handed out by Save, so lookups cannot escape the directory.
+var validName = regexp.MustCompile(`^img_[0-9a-f]{32}\.(png|jpg|webp|gif)$`)
+
+var (
+	dirOnce sync.Once
+	dir     string
+	dirErr  error
+	pruneMu sync.Mutex
+)
+
+func resolveDir() (string, error) {
+	dirOnce.Do(func() {
+		base := util.WritablePath()
+		if base == "" {
+			base, dirErr = os.Getwd()
+			if dirErr != nil {
+				return
+			}
+		}
+		dir = filepath.Join(base, "images")
+	})
+	return dir, dirErr
+}
+
+// Save writes an image and returns its unguessable file name. Expired images are removed
+// on every save.
+func Save(data []byte, mimeType string) (string, error) {
+	root, err := resolveDir()
+	if err != nil {
+		return "", fmt.Errorf("imagestore: resolve directory: %w", err)
+	}
+	if err = os.MkdirAll(root, 0o700); err != nil {
+		return "", fmt.Errorf("imagestore: create directory: %w", err)
+	}
+	prune(root)
+
+	var id [16]byte
+	if _, err = rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("imagestore: generate name: %w", err)
+	}
+	name := "img_" + hex.EncodeToString(id[:]) + "." + extension(mimeType)
+	if err = os.WriteFile(filepath.Join(root, name), data, 0o600); err != nil {
+		return "", fmt.Errorf("imagestore: write image: %w", err)
+	}
+	return name, nil
+}
+
+// Path returns the location of a stored image that has not expired yet.
+func Path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", ErrNotFound
+	}
+	root, err := resolveDir()
+	if err != nil {
+		return "", ErrNotFound
+	}
+	path := filepath.Join(root, name)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > TTL {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
+
+func prune(root string) {
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !validName.MatchString(entry.Name()) {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil || time.Since(info.ModTime()) <= TTL {
+			continue
+		}
+		if errRemove := os.Remove(filepath.Join(root, entry.Name())); errRemove != nil && !os.IsNotExist(errRemove) {
+			log.Debugf("imagestore: remove expired image %s: %v", entry.Name(), errRemove)
+		}
+	}
+}
+
+func extension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return "jpg"
+	case "image/webp":
+		return "webp"
+	case "image/gif":
+		return "gif"
+	default:
+		return "png"
+	}
+}
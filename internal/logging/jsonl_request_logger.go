@@ -0,0 +1,504 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// JSONLRequestLogFileName is the active file written by JSONLRequestLogger.
+	JSONLRequestLogFileName = "requests.jsonl"
+
+	defaultJSONLMaxSizeMB    = 100
+	defaultJSONLMaxBackups   = 10
+	defaultJSONLMaxBodyBytes = 256 << 10
+
+	redactedValue = "[REDACTED]"
+)
+
+// defaultRedactedHeaders are always redacted, in addition to configured headers.
+var defaultRedactedHeaders = []string{
+	"authorization",
+	"proxy-authorization",
+	"x-api-key",
+	"api-key",
+	"x-goog-api-key",
+	"x-management-key",
+	"cookie",
+	"set-cookie",
+}
+
+// defaultRedactedFields are JSON keys always redacted, in addition to configured fields.
+var defaultRedactedFields = []string{
+	"api_key",
+	"apiKey",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"client_secret",
+	"password",
+}
+
+var bearerTokenPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`)
+
+// RequestLogDetails carries request facts that are not part of the RequestLogger method
+// arguments. Loggers that record them implement DetailedRequestLogger.
+type RequestLogDetails struct {
+	// RequestID is the identifier used to correlate the request.
+	RequestID string
+	// StartedAt is when the proxy received the request.
+	StartedAt time.Time
+	// AuthIndex identifies the credential that served the request, when known.
+	AuthIndex string
+}
+
+// DetailedRequestLogger is implemented by request loggers that record RequestLogDetails
+// for non-streaming requests.
+type DetailedRequestLogger interface {
+	LogRequestWithDetails(url, method string, requestHeaders map[string][]string, body []byte, statusCode int, responseHeaders map[string][]string, response, apiRequest, apiResponse []byte, apiResponseErrors []*interfaces.ErrorMessage, force bool, details RequestLogDetails) error
+}
+
+// DetailedStreamingLogWriter is implemented by streaming writers that record
+// RequestLogDetails. WriteDetails is called before Close.
+type DetailedStreamingLogWriter interface {
+	WriteDetails(details RequestLogDetails)
+}
+
+// JSONLRequestLogger implements RequestLogger by appending one JSON object per request to a
+// rotating requests.jsonl. Sensitive headers, JSON fields and bearer tokens are redacted and
+// every body is capped, so the log can be shipped to external log stores.
+type JSONLRequestLogger struct {
+	enabled  atomic.Bool
+	writer   *lumberjack.Logger
+	redactor *requestLogRedactor
+}
+
+// NewJSONLRequestLogger creates a JSONL request logger writing under logsDir, which is
+// resolved relative to configDir when it is not absolute.
+func NewJSONLRequestLogger(enabled bool, logsDir string, configDir string, cfg config.RequestLogSinkConfig) *JSONLRequestLogger {
+	if !filepath.IsAbs(logsDir) && configDir != "" {
+		logsDir = filepath.Join(configDir, logsDir)
+	}
+	maxSize := cfg.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultJSONLMaxSizeMB
+	}
+	maxBackups := cfg.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultJSONLMaxBackups
+	}
+	maxAge := cfg.MaxAgeDays
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	l := &JSONLRequestLogger{
+		writer: &lumberjack.Logger{
+			Filename:   filepath.Join(logsDir, JSONLRequestLogFileName),
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAge,
+			Compress:   cfg.Compress,
+		},
+		redactor: newRequestLogRedactor(cfg),
+	}
+	l.enabled.Store(enabled)
+	return l
+}
+
+// IsEnabled returns whether request logging is currently enabled.
+func (l *JSONLRequestLogger) IsEnabled() bool {
+	return l.enabled.Load()
+}
+
+// SetEnabled updates the request logging enabled state.
+func (l *JSONLRequestLogger) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+// Close closes the current log file. Later writes reopen it.
+func (l *JSONLRequestLogger) Close() error {
+	return l.writer.Close()
+}
+
+// LogRequest appends a non-streaming request/response cycle.
+func (l *JSONLRequestLogger) LogRequest(url, method string, requestHeaders map[string][]string, body []byte, statusCode int, responseHeaders map[string][]string, response, apiRequest, apiResponse []byte, apiResponseErrors []*interfaces.ErrorMessage, requestID string) error {
+	return l.LogRequestWithDetails(url, method, requestHeaders, body, statusCode, responseHeaders, response, apiRequest, apiResponse, apiResponseErrors, false, RequestLogDetails{RequestID: requestID})
+}
+
+// LogRequestWithOptions appends a request, writing error entries even when logging is
+// disabled if force is set.
+func (l *JSONLRequestLogger) LogRequestWithOptions(url, method string, requestHeaders map[string][]string, body []byte, statusCode int, responseHeaders map[string][]string, response, apiRequest, apiResponse []byte, apiResponseErrors []*interfaces.ErrorMessage, force bool, requestID string) error {
+	return l.LogRequestWithDetails(url, method, requestHeaders, body, statusCode, responseHeaders, response, apiRequest, apiResponse, apiResponseErrors, force, RequestLogDetails{RequestID: requestID})
+}
+
+// LogRequestWithDetails implements DetailedRequestLogger.
+func (l *JSONLRequestLogger) LogRequestWithDetails(url, method string, requestHeaders map[string][]string, body []byte, statusCode int, responseHeaders map[string][]string, response, apiRequest, apiResponse []byte, apiResponseErrors []*interfaces.ErrorMessage, force bool, details RequestLogDetails) error {
+	if !l.enabled.Load() && !force {
+		return nil
+	}
+	now := time.Now()
+	entry := l.newEntry(url, method, requestHeaders, body, details, now)
+	entry.ErrorOnly = force && !l.enabled.Load()
+	entry.Status = statusCode
+	entry.UpstreamRequest = l.redactor.text(apiRequest)
+	entry.UpstreamResponse = l.redactor.text(apiResponse)
+	entry.Errors = formatLoggedErrors(apiResponseErrors)
+	entry.Response = l.response(responseHeaders, response)
+	return l.write(entry)
+}
+
+// LogStreamingRequest starts a streaming entry. Chunks are buffered up to the body cap and
+// the entry is written when the returned writer is closed.
+func (l *JSONLRequestLogger) LogStreamingRequest(url, method string, headers map[string][]string, body []byte, requestID string) (StreamingLogWriter, error) {
+	if !l.enabled.Load() {
+		return &NoOpStreamingLogWriter{}, nil
+	}
+	now := time.Now()
+	return &JSONLStreamingLogWriter{
+		logger:      l,
+		entry:       l.newEntry(url, method, headers, body, RequestLogDetails{RequestID: requestID}, now),
+		firstByteAt: now,
+	}, nil
+}
+
+func (l *JSONLRequestLogger) newEntry(url, method string, headers map[string][]string, body []byte, details RequestLogDetails, now time.Time) *jsonlRequestEntry {
+	entry := &jsonlRequestEntry{
+		Timestamp: now,
+		RequestID: details.RequestID,
+		Method:    method,
+		URL:       url,
+		AuthIndex: details.AuthIndex,
+		Request:   l.redactor.message(headers, body),
+	}
+	entry.applyTimings(details.StartedAt, time.Time{}, now)
+	return entry
+}
+
+func (l *JSONLRequestLogger) response(headers map[string][]string, body []byte) jsonlMessage {
+	decoded, err := (&FileRequestLogger{}).decompressResponse(headers, body)
+	if err != nil {
+		decoded = body
+	}
+	return l.redactor.message(headers, decoded)
+}
+
+func (l *JSONLRequestLogger) write(entry *jsonlRequestEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode request log entry: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err = l.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write request log entry: %w", err)
+	}
+	return nil
+}
+
+// jsonlRequestEntry is one line of requests.jsonl.
+type jsonlRequestEntry struct {
+	Timestamp        time.Time    `json:"timestamp"`
+	RequestID        string       `json:"request_id,omitempty"`
+	Method           string       `json:"method"`
+	URL              string       `json:"url"`
+	Status           int          `json:"status"`
+	Streaming        bool         `json:"streaming,omitempty"`
+	ErrorOnly        bool         `json:"error_only,omitempty"`
+	AuthIndex        string       `json:"auth_index,omitempty"`
+	Timings          jsonlTimings `json:"timings"`
+	Request          jsonlMessage `json:"request"`
+	UpstreamRequest  *jsonlBody   `json:"upstream_request,omitempty"`
+	UpstreamResponse *jsonlBody   `json:"upstream_response,omitempty"`
+	Errors           []jsonlError `json:"errors,omitempty"`
+	Response         jsonlMessage `json:"response"`
+}
+
+// jsonlTimings reports durations relative to StartedAt. They are omitted when the start
+// time is unknown.
+type jsonlTimings struct {
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FirstByteMs *int64     `json:"first_byte_ms,omitempty"`
+	DurationMs  *int64     `json:"duration_ms,omitempty"`
+}
+
+type jsonlMessage struct {
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    *jsonlBody          `json:"body,omitempty"`
+}
+
+// jsonlBody holds a payload inline as JSON when it is valid JSON, otherwise as a string.
+type jsonlBody struct {
+	content   []byte
+	json      bool
+	truncated int
+}
+
+type jsonlError struct {
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *jsonlRequestEntry) applyTimings(startedAt, firstByteAt, finishedAt time.Time) {
+	if startedAt.IsZero() {
+		return
+	}
+	started := startedAt
+	e.Timings.StartedAt = &started
+	if !firstByteAt.IsZero() {
+		ms := firstByteAt.Sub(startedAt).Milliseconds()
+		e.Timings.FirstByteMs = &ms
+	}
+	duration := finishedAt.Sub(startedAt).Milliseconds()
+	e.Timings.DurationMs = &duration
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b *jsonlBody) MarshalJSON() ([]byte, error) {
+	if b.json {
+		return b.content, nil
+	}
+	text := string(b.content)
+	if b.truncated > 0 {
+		text += fmt.Sprintf("...[truncated %d bytes]", b.truncated)
+	}
+	return json.Marshal(text)
+}
+
+func formatLoggedErrors(errs []*interfaces.ErrorMessage) []jsonlError {
+	var out []jsonlError
+	for _, errMsg := range errs {
+		if errMsg == nil {
+			continue
+		}
+		message := ""
+		if errMsg.Error != nil {
+			message = errMsg.Error.Error()
+		}
+		out = append(out, jsonlError{Status: errMsg.StatusCode, Message: message})
+	}
+	return out
+}
+
+// JSONLStreamingLogWriter buffers a streaming response for JSONLRequestLogger.
+type JSONLStreamingLogWriter struct {
+	logger      *JSONLRequestLogger
+	firstByteAt time.Time
+
+	mu        sync.Mutex
+	entry     *jsonlRequestEntry
+	startedAt time.Time
+	headers   map[string][]string
+	body      bytes.Buffer
+	dropped   int
+	closed    bool
+}
+
+// WriteChunkAsync appends a chunk, dropping bytes beyond the body cap.
+func (w *JSONLStreamingLogWriter) WriteChunkAsync(chunk []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	limit := w.logger.redactor.maxBodyBytes
+	if limit < 0 {
+		w.body.Write(chunk)
+		return
+	}
+	room := limit - w.body.Len()
+	if room <= 0 {
+		w.dropped += len(chunk)
+		return
+	}
+	if len(chunk) > room {
+		w.dropped += len(chunk) - room
+		chunk = chunk[:room]
+	}
+	w.body.Write(chunk)
+}
+
+// WriteStatus records the response status and headers.
+func (w *JSONLStreamingLogWriter) WriteStatus(status int, headers map[string][]string) error {
+	if status == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entry.Status = status
+	w.headers = headers
+	return nil
+}
+
+// WriteAPIRequest records the upstream request.
+func (w *JSONLStreamingLogWriter) WriteAPIRequest(apiRequest []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entry.UpstreamRequest = w.logger.redactor.text(apiRequest)
+	return nil
+}
+
+// WriteAPIResponse records the upstream response.
+func (w *JSONLStreamingLogWriter) WriteAPIResponse(apiResponse []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entry.UpstreamResponse = w.logger.redactor.text(apiResponse)
+	return nil
+}
+
+// WriteDetails implements DetailedStreamingLogWriter.
+func (w *JSONLStreamingLogWriter) WriteDetails(details RequestLogDetails) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if details.RequestID != "" {
+		w.entry.RequestID = details.RequestID
+	}
+	if details.AuthIndex != "" {
+		w.entry.AuthIndex = details.AuthIndex
+	}
+	w.startedAt = details.StartedAt
+}
+
+// Close writes the buffered entry.
+func (w *JSONLStreamingLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	entry := w.entry
+	entry.Streaming = true
+	entry.applyTimings(w.startedAt, w.firstByteAt, time.Now())
+	entry.Response = jsonlMessage{Headers: w.logger.redactor.headers(w.headers)}
+	if w.body.Len() > 0 {
+		entry.Response.Body = w.logger.redactor.body(w.body.Bytes())
+		entry.Response.Body.truncated += w.dropped
+		entry.Response.Body.json = entry.Response.Body.json && entry.Response.Body.truncated == 0
+	}
+	return w.logger.write(entry)
+}
+
+// requestLogRedactor removes credentials from logged headers and payloads and caps bodies.
+type requestLogRedactor struct {
+	headerNames  map[string]struct{}
+	headerLine   *regexp.Regexp
+	fieldValue   *regexp.Regexp
+	maxBodyBytes int
+}
+
+func newRequestLogRedactor(cfg config.RequestLogSinkConfig) *requestLogRedactor {
+	r := &requestLogRedactor{
+		headerNames:  make(map[string]struct{}),
+		maxBodyBytes: cfg.MaxBodyBytes,
+	}
+	if r.maxBodyBytes == 0 {
+		r.maxBodyBytes = defaultJSONLMaxBodyBytes
+	}
+	var headerPatterns []string
+	for _, name := range append(append([]string{}, defaultRedactedHeaders...), cfg.RedactHeaders...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, seen := r.headerNames[name]; seen {
+			continue
+		}
+		r.headerNames[name] = struct{}{}
+		headerPatterns = append(headerPatterns, regexp.QuoteMeta(name))
+	}
+	sort.Strings(headerPatterns)
+	r.headerLine = regexp.MustCompile(`(?im)^(` + strings.Join(headerPatterns, "|") + `)[ \t]*:[^\r\n]*`)
+
+	var fieldPatterns []string
+	seenFields := make(map[string]struct{})
+	for _, field := range append(append([]string{}, defaultRedactedFields...), cfg.RedactFields...) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, seen := seenFields[field]; seen {
+			continue
+		}
+		seenFields[field] = struct{}{}
+		fieldPatterns = append(fieldPatterns, regexp.QuoteMeta(field))
+	}
+	sort.Strings(fieldPatterns)
+	r.fieldValue = regexp.MustCompile(`("(?:` + strings.Join(fieldPatterns, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	return r
+}
+
+// headers returns a copy of headers with sensitive values replaced.
+func (r *requestLogRedactor) headers(headers map[string][]string) map[string][]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		if _, sensitive := r.headerNames[strings.ToLower(key)]; sensitive {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = redactedValue
+			}
+			out[key] = redacted
+			continue
+		}
+		copied := make([]string, len(values))
+		for i, value := range values {
+			copied[i] = bearerTokenPattern.ReplaceAllString(value, "${1}"+redactedValue)
+		}
+		out[key] = copied
+	}
+	return out
+}
+
+func (r *requestLogRedactor) message(headers map[string][]string, body []byte) jsonlMessage {
+	return jsonlMessage{Headers: r.headers(headers), Body: r.body(body)}
+}
+
+// text redacts a free-form payload such as the upstream request transcript, which embeds
+// "Name: value" header lines as well as bodies.
+func (r *requestLogRedactor) text(payload []byte) *jsonlBody {
+	if len(payload) == 0 {
+		return nil
+	}
+	payload = r.headerLine.ReplaceAllFunc(payload, func(line []byte) []byte {
+		name := line[:bytes.IndexByte(line, ':')]
+		return append(append([]byte{}, bytes.TrimRight(name, " \t")...), []byte(": "+redactedValue)...)
+	})
+	return r.body(payload)
+}
+
+// body redacts sensitive JSON fields and bearer tokens and applies the size cap. Payloads
+// that are still valid JSON are kept inline.
+func (r *requestLogRedactor) body(payload []byte) *jsonlBody {
+	if len(payload) == 0 {
+		return nil
+	}
+	payload = r.fieldValue.ReplaceAll(payload, []byte(`${1}"`+redactedValue+`"`))
+	payload = bearerTokenPattern.ReplaceAll(payload, []byte("${1}"+redactedValue))
+	out := &jsonlBody{}
+	if r.maxBodyBytes > 0 && len(payload) > r.maxBodyBytes {
+		out.truncated = len(payload) - r.maxBodyBytes
+		payload = payload[:r.maxBodyBytes]
+	}
+	out.content = payload
+	out.json = out.truncated == 0 && json.Valid(payload)
+	if out.json {
+		var compact bytes.Buffer
+		if json.Compact(&compact, payload) == nil {
+			out.content = compact.Bytes()
+		}
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+)
+
+func readJSONLEntries(t *testing.T, dir string) []map[string]any {
+	t.Helper()
+	file, err := os.Open(filepath.Join(dir, JSONLRequestLogFileName))
+	if err != nil {
+		t.Fatalf("open request log: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestJSONLRequestLoggerRedactsAndCaps(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewJSONLRequestLogger(true, dir, "", config.RequestLogSinkConfig{
+		MaxBodyBytes:  80,
+		RedactHeaders: []string{"X-Internal"},
+		RedactFields:  []string{"session_id"},
+	})
+	defer func() { _ = logger.Close() }()
+
+	requestBody := []byte(`{"model":"gpt-5","api_key":"sk-secret-value","session_id":"abc"}`)
+	upstream := []byte("=== API REQUEST 1 ===\nHeaders:\nAuthorization: Bearer sk-upstream-secret\nCookie: a=b\n\nBody:\n{}")
+	err := logger.LogRequestWithDetails("/v1/chat/completions", "POST",
+		map[string][]string{"Authorization": {"Bearer sk-client-secret"}, "X-Internal": {"x"}, "Content-Type": {"application/json"}},
+		requestBody, 502, map[string][]string{"Content-Type": {"application/json"}},
+		[]byte(strings.Repeat("a", 100)), upstream, nil,
+		[]*interfaces.ErrorMessage{{StatusCode: 502, Error: errors.New("bad gateway")}},
+		false, RequestLogDetails{RequestID: "req-1", StartedAt: time.Now().Add(-time.Second), AuthIndex: "7"})
+	if err != nil {
+		t.Fatalf("LogRequestWithDetails: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, JSONLRequestLogFileName))
+	if err != nil {
+		t.Fatalf("read request log: %v", err)
+	}
+	for _, secret := range []string{"sk-secret-value", "sk-client-secret", "sk-upstream-secret", "a=b", `"abc"`} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("request log leaks %q: %s", secret, raw)
+		}
+	}
+
+	entries := readJSONLEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry["request_id"] != "req-1" || entry["auth_index"] != "7" || entry["status"] != float64(502) {
+		t.Fatalf("unexpected entry metadata: %v", entry)
+	}
+	request := entry["request"].(map[string]any)
+	if body, ok := request["body"].(map[string]any); !ok || body["model"] != "gpt-5" || body["api_key"] != redactedValue {
+		t.Fatalf("request body should stay inline JSON with redacted keys: %v", request["body"])
+	}
+	response := entry["response"].(map[string]any)
+	if body, _ := response["body"].(string); !strings.HasSuffix(body, "...[truncated 20 bytes]") {
+		t.Fatalf("response body should be truncated: %q", body)
+	}
+	timings := entry["timings"].(map[string]any)
+	if duration, _ := timings["duration_ms"].(float64); duration < 1000 {
+		t.Fatalf("duration_ms = %v, want >= 1000", timings["duration_ms"])
+	}
+}
+
+func TestJSONLRequestLoggerStreaming(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewJSONLRequestLogger(true, dir, "", config.RequestLogSinkConfig{MaxBodyBytes: 16})
+	defer func() { _ = logger.Close() }()
+
+	writer, err := logger.LogStreamingRequest("/v1/messages", "POST", nil, []byte(`{"stream":true}`), "req-2")
+	if err != nil {
+		t.Fatalf("LogStreamingRequest: %v", err)
+	}
+	_ = writer.WriteStatus(200, map[string][]string{"Set-Cookie": {"s=1"}})
+	writer.WriteChunkAsync([]byte("data: one\n\n"))
+	writer.WriteChunkAsync([]byte("data: two\n\n"))
+	writer.(DetailedStreamingLogWriter).WriteDetails(RequestLogDetails{StartedAt: time.Now(), AuthIndex: "3"})
+	if err = writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries := readJSONLEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry["streaming"] != true || entry["auth_index"] != "3" {
+		t.Fatalf("unexpected streaming entry: %v", entry)
+	}
+	response := entry["response"].(map[string]any)
+	if body, _ := response["body"].(string); body != "data: one\n\ndata:...[truncated 6 bytes]" {
+		t.Fatalf("streamed body = %q", body)
+	}
+	if cookie := response["headers"].(map[string]any)["Set-Cookie"].([]any)[0]; cookie != redactedValue {
+		t.Fatalf("Set-Cookie should be redacted, got %v", cookie)
+	}
+}
+
+func TestJSONLRequestLoggerDisabled(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewJSONLRequestLogger(false, dir, "", config.RequestLogSinkConfig{})
+	if err := logger.LogRequest("/v1/models", "GET", nil, nil, 200, nil, nil, nil, nil, nil, ""); err != nil {
+		t.Fatalf("LogRequest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, JSONLRequestLogFileName)); !os.IsNotExist(err) {
+		t.Fatalf("disabled logger should not create a file, stat error: %v", err)
+	}
+	if err := logger.LogRequestWithOptions("/v1/models", "GET", nil, nil, 500, nil, nil, nil, nil, nil, true, "req-3"); err != nil {
+		t.Fatalf("LogRequestWithOptions: %v", err)
+	}
+	defer func() { _ = logger.Close() }()
+	if entries := readJSONLEntries(t, dir); len(entries) != 1 || entries[0]["error_only"] != true {
+		t.Fatalf("forced entry missing: %v", entries)
+	}
+}
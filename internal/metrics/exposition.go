@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelSeparator joins label values into map keys; it cannot appear in valid UTF-8 text.
+const labelSeparator = "\xff"
+
+// latencyBuckets are the upper bounds, in seconds, shared by the latency histograms.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+// counterVec is a counter family partitioned by label values.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+// add increments the series identified by values, which must match the label names.
+func (c *counterVec) add(delta float64, values ...string) {
+	if delta <= 0 {
+		return
+	}
+	key := strings.Join(values, labelSeparator)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	keys := sortedKeys(c.values)
+	samples := make([]float64, len(keys))
+	for i, key := range keys {
+		samples[i] = c.values[key]
+	}
+	c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for i, key := range keys {
+		writeSample(w, c.name, c.labels, strings.Split(key, labelSeparator), "", "", samples[i])
+	}
+}
+
+// histogram holds the cumulative state of one histogram series.
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// histogramVec is a histogram family partitioned by label values.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	bounds  []float64
+	mu      sync.Mutex
+	entries map[string]*histogram
+}
+
+func newHistogramVec(name, help string, bounds []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, bounds: bounds, entries: make(map[string]*histogram)}
+}
+
+// observe records a value in the series identified by values.
+func (h *histogramVec) observe(value float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.entries[key]
+	if !ok {
+		entry = &histogram{buckets: make([]uint64, len(h.bounds))}
+		h.entries[key] = entry
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			entry.buckets[i]++
+		}
+	}
+	entry.count++
+	entry.sum += value
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	keys := sortedKeys(h.entries)
+	snapshot := make([]histogram, len(keys))
+	for i, key := range keys {
+		entry := h.entries[key]
+		snapshot[i] = histogram{buckets: append([]uint64(nil), entry.buckets...), count: entry.count, sum: entry.sum}
+	}
+	h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, key := range keys {
+		values := strings.Split(key, labelSeparator)
+		for j, bound := range h.bounds {
+			writeSample(w, h.name+"_bucket", h.labels, values, "le", formatFloat(bound), float64(snapshot[i].buckets[j]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, values, "le", "+Inf", float64(snapshot[i].count))
+		writeSample(w, h.name+"_sum", h.labels, values, "", "", snapshot[i].sum)
+		writeSample(w, h.name+"_count", h.labels, values, "", "", float64(snapshot[i].count))
+	}
+}
+
+// gaugeSample is one series of a gauge computed at scrape time.
+type gaugeSample struct {
+	values []string
+	value  float64
+}
+
+func writeGauge(w io.Writer, name, help string, labels []string, samples []gaugeSample) {
+	writeHeader(w, name, help, "gauge")
+	for _, sample := range samples {
+		writeSample(w, name, labels, sample.values, "", "", sample.value)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes one exposition line. extraName/extraValue append a label such as le.
+func writeSample(w io.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		b.WriteByte('{')
+		first := true
+		writeLabel := func(label, labelValue string) {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(labelValue))
+			b.WriteByte('"')
+		}
+		for i, label := range labels {
+			labelValue := ""
+			if i < len(values) {
+				labelValue = values[i]
+			}
+			writeLabel(label, labelValue)
+		}
+		if extraName != "" {
+			writeLabel(extraName, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+	_, _ = io.WriteString(w, b.String())
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
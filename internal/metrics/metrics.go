@@ -0,0 +1,227 @@
+// Package metrics exports proxy metrics in the Prometheus text exposition format.
+// The collector receives usage records as a coreusage.Plugin, execution results and
+// retries as an auth manager Hook, and samples credential and model state when scraped.
+package metrics
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var defaultCollector = NewCollector()
+
+func init() {
+	coreusage.RegisterPlugin(defaultCollector)
+}
+
+// Default returns the process-wide collector registered as a usage plugin.
+func Default() *Collector { return defaultCollector }
+
+// Collector aggregates proxy metrics. It implements coreusage.Plugin, coreauth.Hook and
+// coreauth.RetryObserver.
+type Collector struct {
+	coreauth.NoopHook
+
+	requests     *counterVec
+	tokens       *counterVec
+	attempts     *counterVec
+	retries      *counterVec
+	cooldowns    *counterVec
+	firstByte    *histogramVec
+	duration     *histogramVec
+	httpDuration *histogramVec
+}
+
+// NewCollector returns an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		requests: newCounterVec("cliproxy_requests_total",
+			"Completed upstream requests by client API key and credential.",
+			"provider", "model", "api_key", "auth_index", "status"),
+		tokens: newCounterVec("cliproxy_tokens_total",
+			"Tokens reported by upstream providers.",
+			"provider", "model", "api_key", "auth_index", "type"),
+		attempts: newCounterVec("cliproxy_upstream_attempts_total",
+			"Upstream attempts recorded by the auth manager, including ones that were retried on another credential.",
+			"provider", "model", "outcome"),
+		retries: newCounterVec("cliproxy_retries_total",
+			"Retries started after every credential of a model failed.",
+			"model"),
+		cooldowns: newCounterVec("cliproxy_cooldowns_total",
+			"Credential cooldowns applied after upstream failures.",
+			"provider", "model", "reason"),
+		firstByte: newHistogramVec("cliproxy_upstream_first_byte_seconds",
+			"Time until a streaming upstream produced its first chunk.",
+			latencyBuckets, "provider", "model"),
+		duration: newHistogramVec("cliproxy_upstream_duration_seconds",
+			"Time until the upstream response completed.",
+			latencyBuckets, "provider", "model"),
+		httpDuration: newHistogramVec("cliproxy_http_request_duration_seconds",
+			"Total time spent serving HTTP requests.",
+			latencyBuckets, "method", "route", "status"),
+	}
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (c *Collector) HandleUsage(_ context.Context, record coreusage.Record) {
+	if c == nil {
+		return
+	}
+	apiKey := ""
+	if record.APIKey != "" {
+		apiKey = util.HideAPIKey(record.APIKey)
+	}
+	status := "success"
+	if record.Failed {
+		status = "failure"
+	}
+	c.requests.add(1, record.Provider, record.Model, apiKey, record.AuthIndex, status)
+	c.tokens.add(float64(record.Detail.InputTokens), record.Provider, record.Model, apiKey, record.AuthIndex, "input")
+	c.tokens.add(float64(record.Detail.OutputTokens), record.Provider, record.Model, apiKey, record.AuthIndex, "output")
+	c.tokens.add(float64(record.Detail.ReasoningTokens), record.Provider, record.Model, apiKey, record.AuthIndex, "reasoning")
+	c.tokens.add(float64(record.Detail.CachedTokens), record.Provider, record.Model, apiKey, record.AuthIndex, "cached")
+	if record.Failed {
+		return
+	}
+	// TimeToFirstToken is only set for streams; non-streaming requests have no first byte
+	// distinct from their full duration.
+	if record.TimeToFirstToken > 0 {
+		c.firstByte.observe(record.TimeToFirstToken.Seconds(), record.Provider, record.Model)
+	}
+	if record.Latency > 0 {
+		c.duration.observe(record.Latency.Seconds(), record.Provider, record.Model)
+	}
+}
+
+// OnResult implements coreauth.Hook.
+func (c *Collector) OnResult(_ context.Context, result coreauth.Result) {
+	if c == nil {
+		return
+	}
+	outcome := "success"
+	if !result.Success {
+		outcome = "failure"
+	}
+	c.attempts.add(1, result.Provider, result.Model, outcome)
+	if result.Cooldown > 0 {
+		c.cooldowns.add(1, result.Provider, result.Model, result.CooldownReason)
+	}
+}
+
+// OnRetry implements coreauth.RetryObserver.
+func (c *Collector) OnRetry(_ context.Context, model string, _ int, _ time.Duration) {
+	if c == nil {
+		return
+	}
+	c.retries.add(1, model)
+}
+
+// Middleware records the total duration of every routed HTTP request.
+func (c *Collector) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		route := ctx.FullPath()
+		if route == "" {
+			// Unmatched paths would make the label set unbounded.
+			return
+		}
+		c.httpDuration.observe(time.Since(start).Seconds(), ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status()))
+	}
+}
+
+// Write renders every metric. Credential gauges are omitted when manager is nil.
+func (c *Collector) Write(w io.Writer, manager *coreauth.Manager) {
+	c.requests.write(w)
+	c.tokens.write(w)
+	c.attempts.write(w)
+	c.retries.write(w)
+	c.cooldowns.write(w)
+	c.firstByte.write(w)
+	c.duration.write(w)
+	c.httpDuration.write(w)
+	if manager != nil {
+		writeAuthGauges(w, manager.List())
+	}
+	writeModelGauges(w, registry.GetGlobalRegistry().GetModelAvailability())
+}
+
+// writeAuthGauges reports credentials by provider and status, plus those currently cooling down.
+func writeAuthGauges(w io.Writer, auths []*coreauth.Auth) {
+	byStatus := make(map[[2]string]int)
+	coolingDown := make(map[string]int)
+	now := time.Now()
+	for _, auth := range auths {
+		if auth == nil {
+			continue
+		}
+		status := string(auth.Status)
+		if status == "" {
+			status = string(coreauth.StatusUnknown)
+		}
+		byStatus[[2]string{auth.Provider, status}]++
+		if auth.NextRetryAfter.After(now) {
+			coolingDown[auth.Provider]++
+		}
+		for _, state := range auth.ModelStates {
+			if state != nil && state.NextRetryAfter.After(now) {
+				coolingDown[auth.Provider]++
+				break
+			}
+		}
+	}
+
+	statusSamples := make([]gaugeSample, 0, len(byStatus))
+	for key, count := range byStatus {
+		statusSamples = append(statusSamples, gaugeSample{values: []string{key[0], key[1]}, value: float64(count)})
+	}
+	sortSamples(statusSamples)
+	writeGauge(w, "cliproxy_auths", "Registered credentials by provider and status.",
+		[]string{"provider", "status"}, statusSamples)
+
+	coolingSamples := make([]gaugeSample, 0, len(coolingDown))
+	for provider, count := range coolingDown {
+		coolingSamples = append(coolingSamples, gaugeSample{values: []string{provider}, value: float64(count)})
+	}
+	sortSamples(coolingSamples)
+	writeGauge(w, "cliproxy_auths_cooling_down", "Credentials with the whole credential or at least one model in cooldown.",
+		[]string{"provider"}, coolingSamples)
+}
+
+// writeModelGauges reports how many clients serve each registered model.
+func writeModelGauges(w io.Writer, models []registry.ModelAvailability) {
+	registered := make([]gaugeSample, 0, len(models))
+	available := make([]gaugeSample, 0, len(models))
+	for _, model := range models {
+		registered = append(registered, gaugeSample{values: []string{model.Model}, value: float64(model.Registered)})
+		available = append(available, gaugeSample{values: []string{model.Model}, value: float64(model.Available)})
+	}
+	writeGauge(w, "cliproxy_model_clients_registered", "Clients that registered the model.",
+		[]string{"model"}, registered)
+	writeGauge(w, "cliproxy_model_clients_available", "Clients that can serve the model right now.",
+		[]string{"model"}, available)
+}
+
+func sortSamples(samples []gaugeSample) {
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].values, samples[j].values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestCollectorExposition(t *testing.T) {
+	c := NewCollector()
+	ctx := context.Background()
+	c.HandleUsage(ctx, coreusage.Record{
+		Provider:  "gemini",
+		Model:     "gemini-2.5-pro",
+		APIKey:    "sk-client-secret-key",
+		AuthIndex: "3",
+		Detail:    coreusage.Detail{InputTokens: 10, OutputTokens: 5},
+		Latency:   2 * time.Second,
+	})
+	c.HandleUsage(ctx, coreusage.Record{
+		Provider:         "gemini",
+		Model:            "gemini-2.5-pro",
+		Latency:          3 * time.Second,
+		TimeToFirstToken: 300 * time.Millisecond,
+	})
+	c.OnResult(ctx, coreauth.Result{Provider: "gemini", Model: "gemini-2.5-pro", Success: true, Latency: 2 * time.Second})
+	c.OnResult(ctx, coreauth.Result{Provider: "gemini", Model: "gemini-2.5-pro", Cooldown: time.Minute, CooldownReason: "quota"})
+	c.OnRetry(ctx, "gemini-2.5-pro", 1, time.Second)
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	if _, err := manager.Register(ctx, &coreauth.Auth{ID: "a", Provider: "gemini", Status: coreauth.StatusActive}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var out strings.Builder
+	c.Write(&out, manager)
+	text := out.String()
+	for _, want := range []string{
+		`cliproxy_requests_total{provider="gemini",model="gemini-2.5-pro",api_key="sk-c...-key",auth_index="3",status="success"} 1`,
+		`cliproxy_tokens_total{provider="gemini",model="gemini-2.5-pro",api_key="sk-c...-key",auth_index="3",type="input"} 10`,
+		`cliproxy_upstream_attempts_total{provider="gemini",model="gemini-2.5-pro",outcome="failure"} 1`,
+		`cliproxy_upstream_first_byte_seconds_bucket{provider="gemini",model="gemini-2.5-pro",le="0.5"} 1`,
+		`cliproxy_upstream_first_byte_seconds_count{provider="gemini",model="gemini-2.5-pro"} 1`,
+		`cliproxy_upstream_duration_seconds_bucket{provider="gemini",model="gemini-2.5-pro",le="2.5"} 1`,
+		`cliproxy_upstream_duration_seconds_count{provider="gemini",model="gemini-2.5-pro"} 2`,
+		`cliproxy_cooldowns_total{provider="gemini",model="gemini-2.5-pro",reason="quota"} 1`,
+		`cliproxy_retries_total{model="gemini-2.5-pro"} 1`,
+		`cliproxy_auths{provider="gemini",status="active"} 1`,
+		"# TYPE cliproxy_model_clients_available gauge",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exposition is missing %q\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "sk-client-secret-key") {
+		t.Error("exposition leaks the client API key")
+	}
+	if strings.Contains(text, `type="reasoning"`) {
+		t.Error("zero token counts should not create series")
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("escapeLabelValue = %q", got)
+	}
+}
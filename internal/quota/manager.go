@@ -96,8 +96,35 @@ func (m *Manager) GetUsageReadOnly(apiKey string) *QuotaUsage {
 // CheckQuota checks if a request is allowed based on the API key's policy.
 // This should be called BEFORE processing the request.
 func (m *Manager) CheckQuota(apiKey, model string) *CheckResult {
-	policy := m.GetPolicy(apiKey)
-	usage := m.GetUsageReadOnly(apiKey)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.checkLocked(apiKey, model, time.Now())
+}
+
+// AdmitRequest checks the quota like CheckQuota and, when the request is allowed,
+// counts it against the API key's request windows in the same critical section.
+func (m *Manager) AdmitRequest(apiKey, model string) *CheckResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	result := m.checkLocked(apiKey, model, now)
+	if !result.Allowed || result.Policy == nil || !result.Policy.HasWindowLimits() {
+		return result
+	}
+
+	usage := m.usageLocked(apiKey)
+	for _, w := range allWindows {
+		usage.advanceWindow(w, now).Requests++
+	}
+	result.Usage = usage
+	return result
+}
+
+// checkLocked evaluates the policy for an API key. The caller must hold m.mu.
+func (m *Manager) checkLocked(apiKey, model string, now time.Time) *CheckResult {
+	policy := m.policies[apiKey]
+	usage := m.usage[apiKey]
 
 	// No policy means no restrictions
 	if policy == nil {
@@ -144,15 +171,19 @@ func (m *Manager) CheckQuota(apiKey, model string) *CheckResult {
 		}
 	}
 
+	// Check 5: Windowed limits
+	if policy.HasWindowLimits() {
+		if err := checkWindows(policy, usage, now); err != nil {
+			return NewDeniedResult(err, policy, usage)
+		}
+	}
+
 	return NewAllowedResult(policy, usage)
 }
 
-// UpdateUsage updates the usage for an API key after a request.
-// This should be called AFTER the request is processed.
-func (m *Manager) UpdateUsage(apiKey, model string, inputTokens, outputTokens, cachedTokens int64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+// usageLocked returns the usage record for an API key, creating it when missing.
+// The caller must hold m.mu for writing.
+func (m *Manager) usageLocked(apiKey string) *QuotaUsage {
 	usage, ok := m.usage[apiKey]
 	if !ok {
 		usage = &QuotaUsage{
@@ -161,6 +192,17 @@ func (m *Manager) UpdateUsage(apiKey, model string, inputTokens, outputTokens, c
 		}
 		m.usage[apiKey] = usage
 	}
+	return usage
+}
+
+// UpdateUsage updates the usage for an API key after a request.
+// This should be called AFTER the request is processed.
+func (m *Manager) UpdateUsage(apiKey, model string, inputTokens, outputTokens, cachedTokens int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.usageLocked(apiKey)
+	now := time.Now()
 
 	// Calculate total tokens
 	totalTokens := inputTokens + outputTokens
@@ -172,7 +214,14 @@ func (m *Manager) UpdateUsage(apiKey, model string, inputTokens, outputTokens, c
 	usage.TotalTokens += totalTokens
 	usage.TotalCostUSD += cost
 	usage.TotalRequests++
-	usage.LastUsedAt = time.Now()
+	usage.LastUsedAt = now
+
+	// Update windowed usage; requests are counted on admission by AdmitRequest.
+	for _, w := range allWindows {
+		wu := usage.advanceWindow(w, now)
+		wu.Tokens += totalTokens
+		wu.CostUSD += cost
+	}
 
 	log.Debugf("Updated usage for API key %s: +%d tokens, +$%.4f (total: %d tokens, $%.2f)",
 		maskAPIKey(apiKey), totalTokens, cost, usage.TotalTokens, usage.TotalCostUSD)
@@ -193,6 +242,7 @@ func (m *Manager) AllUsage() map[string]*QuotaUsage {
 	result := make(map[string]*QuotaUsage, len(m.usage))
 	for k, v := range m.usage {
 		usageCopy := *v
+		usageCopy.Windows = cloneWindows(v.Windows)
 		result[k] = &usageCopy
 	}
 	return result
@@ -244,6 +294,10 @@ func (m *Manager) GetQuotaStatus(apiKey string) map[string]any {
 			"max_cost_usd":   policy.MaxCostUSD,
 			"expires_at":     policy.ExpiresAt,
 			"is_expired":     policy.IsExpired(),
+			"limits":         policy.Limits,
+		}
+		if policy.HasWindowLimits() {
+			status["windows"] = windowStatus(policy, usage, time.Now())
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -48,8 +49,8 @@ func Middleware(manager *Manager) gin.HandlerFunc {
 			c.Set(ContextKeyRequestModel, model)
 		}
 
-		// Check quota
-		result := manager.CheckQuota(apiKeyStr, model)
+		// Check quota and count the request against its windows
+		result := manager.AdmitRequest(apiKeyStr, model)
 		c.Set(ContextKeyQuotaResult, result)
 
 		if !result.Allowed {
@@ -63,49 +64,6 @@ func Middleware(manager *Manager) gin.HandlerFunc {
 	}
 }
 
-// PostRequestMiddleware returns a middleware that updates usage after request completion.
-// It should be placed at the end of the middleware chain.
-func PostRequestMiddleware(manager *Manager) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Process request first
-		c.Next()
-
-		if manager == nil {
-			return
-		}
-
-		// Only update usage for successful requests
-		if c.Writer.Status() >= 400 {
-			return
-		}
-
-		// Get API key
-		apiKey, exists := c.Get(ContextKeyAPIKey)
-		if !exists || apiKey == nil {
-			return
-		}
-
-		apiKeyStr, ok := apiKey.(string)
-		if !ok || apiKeyStr == "" {
-			return
-		}
-
-		// Get model
-		model := ""
-		if m, exists := c.Get(ContextKeyRequestModel); exists {
-			model, _ = m.(string)
-		}
-
-		// Get token usage from response (if available)
-		// This will be set by the handler after processing
-		inputTokens, outputTokens, cachedTokens := extractTokenUsageFromContext(c)
-
-		if inputTokens > 0 || outputTokens > 0 {
-			manager.UpdateUsage(apiKeyStr, model, inputTokens, outputTokens, cachedTokens)
-		}
-	}
-}
-
 // extractModelFromRequest extracts the model name from the request body.
 func extractModelFromRequest(c *gin.Context) string {
 	// Only process POST requests with JSON body
@@ -135,27 +93,6 @@ func extractModelFromRequest(c *gin.Context) string {
 	return model
 }
 
-// extractTokenUsageFromContext extracts token usage from the gin context.
-// These values should be set by the handler after processing the response.
-func extractTokenUsageFromContext(c *gin.Context) (inputTokens, outputTokens, cachedTokens int64) {
-	if v, exists := c.Get("inputTokens"); exists {
-		if tokens, ok := v.(int64); ok {
-			inputTokens = tokens
-		}
-	}
-	if v, exists := c.Get("outputTokens"); exists {
-		if tokens, ok := v.(int64); ok {
-			outputTokens = tokens
-		}
-	}
-	if v, exists := c.Get("cachedTokens"); exists {
-		if tokens, ok := v.(int64); ok {
-			cachedTokens = tokens
-		}
-	}
-	return
-}
-
 // respondWithQuotaError sends a quota error response.
 func respondWithQuotaError(c *gin.Context, err *QuotaError) {
 	if err == nil {
@@ -182,6 +119,10 @@ func respondWithQuotaError(c *gin.Context, err *QuotaError) {
 	case QuotaErrorTypeTokenLimitExceeded, QuotaErrorTypeCostLimitExceeded:
 		statusCode = http.StatusForbidden
 		errorType = "quota_exceeded"
+	case QuotaErrorTypeWindowLimitExceeded:
+		statusCode = http.StatusTooManyRequests
+		errorType = "rate_limit_error"
+		c.Header("Retry-After", strconv.FormatInt(retryAfterSeconds(err.RetryAfter), 10))
 	}
 
 	c.JSON(statusCode, gin.H{
@@ -196,14 +137,6 @@ func respondWithQuotaError(c *gin.Context, err *QuotaError) {
 	log.Warnf("Quota check failed: %s - %s", err.Code, err.Message)
 }
 
-// SetTokenUsage is a helper function to set token usage in the gin context.
-// This should be called by handlers after processing the response.
-func SetTokenUsage(c *gin.Context, inputTokens, outputTokens, cachedTokens int64) {
-	c.Set("inputTokens", inputTokens)
-	c.Set("outputTokens", outputTokens)
-	c.Set("cachedTokens", cachedTokens)
-}
-
 // GetQuotaResult returns the quota check result from the gin context.
 func GetQuotaResult(c *gin.Context) *CheckResult {
 	if result, exists := c.Get(ContextKeyQuotaResult); exists {
@@ -108,7 +108,7 @@ func SaveUsageToFile() error {
 	persisted := persistedUsageData{
 		Usage:     allUsage,
 		LastSaved: time.Now(),
-		Version:   1,
+		Version:   2,
 	}
 
 	data, err := json.MarshalIndent(persisted, "", "  ")
@@ -0,0 +1,50 @@
+package quota
+
+import (
+	"context"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(NewUsagePlugin(GetManager()))
+}
+
+// UsagePlugin charges usage records emitted by executors against API key quotas.
+// It implements coreusage.Plugin so streamed and non-streamed responses are
+// accounted from the same records, independently of usage statistics.
+type UsagePlugin struct {
+	manager *Manager
+}
+
+// NewUsagePlugin constructs a quota usage plugin bound to the given manager.
+func NewUsagePlugin(manager *Manager) *UsagePlugin { return &UsagePlugin{manager: manager} }
+
+// HandleUsage implements coreusage.Plugin.
+// Failed records and records without a caller API key are ignored.
+func (p *UsagePlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	if p == nil || p.manager == nil {
+		return
+	}
+	if record.APIKey == "" || record.Failed {
+		return
+	}
+	detail := record.Detail
+	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.TotalTokens == 0 {
+		return
+	}
+	p.manager.UpdateUsage(record.APIKey, record.Model, detail.InputTokens, billableOutputTokens(detail), detail.CachedTokens)
+}
+
+// billableOutputTokens returns the output tokens including reasoning tokens.
+// Providers differ in whether reasoning is folded into the output count, so the
+// reported total is preferred when it accounts for more than input + output.
+func billableOutputTokens(detail coreusage.Detail) int64 {
+	output := detail.OutputTokens
+	if detail.TotalTokens > 0 {
+		if fromTotal := detail.TotalTokens - detail.InputTokens; fromTotal > output {
+			output = fromTotal
+		}
+	}
+	return output
+}
@@ -1,10 +1,12 @@
 package quota
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 )
 
 func TestAPIKeyPolicy_IsExpired(t *testing.T) {
@@ -328,3 +330,133 @@ func TestQuotaErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestManager_WindowLimits(t *testing.T) {
+	InitDefaultPricing()
+
+	manager := &Manager{
+		policies: make(map[string]*Policy),
+		usage:    make(map[string]*QuotaUsage),
+		pricing:  GetPricingManager(),
+	}
+	manager.policies["key-windowed"] = &Policy{
+		Limits: &config.APIKeyWindowLimits{
+			Minute: &config.APIKeyWindowLimit{Requests: 2},
+			Day:    &config.APIKeyWindowLimit{Tokens: 1000},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if result := manager.AdmitRequest("key-windowed", "gpt-4o"); !result.Allowed {
+			t.Fatalf("request %d denied: %v", i+1, result.Error)
+		}
+	}
+
+	result := manager.AdmitRequest("key-windowed", "gpt-4o")
+	if result.Allowed {
+		t.Fatal("expected third request within the minute to be denied")
+	}
+	if result.Error.Type != QuotaErrorTypeWindowLimitExceeded {
+		t.Errorf("Error.Type = %v, want %v", result.Error.Type, QuotaErrorTypeWindowLimitExceeded)
+	}
+	if result.Error.Code != "minute_requests_limit_exceeded" {
+		t.Errorf("Error.Code = %v, want minute_requests_limit_exceeded", result.Error.Code)
+	}
+	if result.Error.RetryAfter <= 0 || result.Error.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v, want within (0, 1m]", result.Error.RetryAfter)
+	}
+
+	// Roll the minute window back so only the daily token limit applies.
+	usage := manager.GetUsageReadOnly("key-windowed")
+	usage.Windows[WindowMinute].Start = usage.Windows[WindowMinute].Start.Add(-time.Minute)
+	if result := manager.CheckQuota("key-windowed", "gpt-4o"); !result.Allowed {
+		t.Fatalf("expected request after minute rollover to be allowed, got %v", result.Error)
+	}
+
+	manager.UpdateUsage("key-windowed", "gpt-4o", 800, 200, 0)
+	result = manager.CheckQuota("key-windowed", "gpt-4o")
+	if result.Allowed {
+		t.Fatal("expected daily token limit to deny the request")
+	}
+	if result.Error.Code != "day_tokens_limit_exceeded" {
+		t.Errorf("Error.Code = %v, want day_tokens_limit_exceeded", result.Error.Code)
+	}
+	if result.Error.RetryAfter > 24*time.Hour {
+		t.Errorf("RetryAfter = %v, want at most 24h", result.Error.RetryAfter)
+	}
+
+	// With both the minute and the day exhausted, the day reset is reported.
+	usage.Windows[WindowMinute] = &WindowUsage{Start: WindowMinute.Start(time.Now()), Requests: 2}
+	result = manager.CheckQuota("key-windowed", "gpt-4o")
+	if result.Allowed {
+		t.Fatal("expected exhausted windows to deny the request")
+	}
+	if result.Error.Code != "day_tokens_limit_exceeded" {
+		t.Errorf("Error.Code = %v, want day_tokens_limit_exceeded", result.Error.Code)
+	}
+}
+
+func TestWindow_Bounds(t *testing.T) {
+	now := time.Date(2025, 2, 14, 13, 45, 30, 0, time.UTC)
+	tests := []struct {
+		window    Window
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{WindowMinute, time.Date(2025, 2, 14, 13, 45, 0, 0, time.UTC), time.Date(2025, 2, 14, 13, 46, 0, 0, time.UTC)},
+		{WindowHour, time.Date(2025, 2, 14, 13, 0, 0, 0, time.UTC), time.Date(2025, 2, 14, 14, 0, 0, 0, time.UTC)},
+		{WindowDay, time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{WindowMonth, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.window), func(t *testing.T) {
+			if got := tt.window.Start(now); !got.Equal(tt.wantStart) {
+				t.Errorf("Start() = %v, want %v", got, tt.wantStart)
+			}
+			if got := tt.window.End(now); !got.Equal(tt.wantEnd) {
+				t.Errorf("End() = %v, want %v", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestUsagePlugin_HandleUsage(t *testing.T) {
+	InitDefaultPricing()
+
+	manager := &Manager{
+		policies: make(map[string]*Policy),
+		usage:    make(map[string]*QuotaUsage),
+		pricing:  GetPricingManager(),
+	}
+	plugin := NewUsagePlugin(manager)
+
+	// Streamed Gemini responses report thoughts separately from candidates.
+	plugin.HandleUsage(context.Background(), coreusage.Record{
+		APIKey: "key-stream",
+		Model:  "gemini-2.5-pro",
+		Detail: coreusage.Detail{InputTokens: 100, OutputTokens: 50, ReasoningTokens: 30, TotalTokens: 180},
+	})
+	// Failed records and anonymous callers are not charged.
+	plugin.HandleUsage(context.Background(), coreusage.Record{
+		APIKey: "key-stream",
+		Failed: true,
+		Detail: coreusage.Detail{InputTokens: 1000},
+	})
+	plugin.HandleUsage(context.Background(), coreusage.Record{
+		Detail: coreusage.Detail{InputTokens: 1000},
+	})
+
+	usage := manager.GetUsageReadOnly("key-stream")
+	if usage == nil {
+		t.Fatal("expected usage to be recorded")
+	}
+	if usage.TotalTokens != 180 {
+		t.Errorf("TotalTokens = %d, want 180", usage.TotalTokens)
+	}
+	if usage.TotalRequests != 1 {
+		t.Errorf("TotalRequests = %d, want 1", usage.TotalRequests)
+	}
+	if len(manager.AllUsage()) != 1 {
+		t.Errorf("expected only one usage record, got %d", len(manager.AllUsage()))
+	}
+}
@@ -32,6 +32,9 @@ type QuotaUsage struct {
 
 	// CreatedAt is when this usage record was created.
 	CreatedAt time.Time `json:"created_at"`
+
+	// Windows tracks consumption within the current minute/hour/day/month windows.
+	Windows map[Window]*WindowUsage `json:"windows,omitempty"`
 }
 
 // QuotaErrorType represents the type of quota violation.
@@ -49,6 +52,9 @@ const (
 
 	// QuotaErrorTypeCostLimitExceeded indicates the cost limit was exceeded.
 	QuotaErrorTypeCostLimitExceeded QuotaErrorType = "cost_limit_exceeded"
+
+	// QuotaErrorTypeWindowLimitExceeded indicates a windowed limit was reached.
+	QuotaErrorTypeWindowLimitExceeded QuotaErrorType = "window_limit_exceeded"
 )
 
 // QuotaError represents a quota violation error.
@@ -64,6 +70,9 @@ type QuotaError struct {
 
 	// Details contains additional error details.
 	Details map[string]any `json:"details,omitempty"`
+
+	// RetryAfter is how long the client should wait before retrying (windowed limits only).
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error implements the error interface.
@@ -122,6 +131,44 @@ func NewCostLimitExceededError(used, limit float64) *QuotaError {
 	}
 }
 
+// NewWindowLimitExceededError creates a new windowed limit exceeded error.
+// Metric is one of "requests", "tokens" or "cost_usd".
+func NewWindowLimitExceededError(window Window, metric string, used, limit float64, resetAt, now time.Time) *QuotaError {
+	retryAfter := resetAt.Sub(now)
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	var message string
+	switch metric {
+	case "cost_usd":
+		message = fmt.Sprintf("Per-%s cost limit exceeded. Used: $%.2f / Limit: $%.2f. Resets at %s", window, used, limit, resetAt.Format(time.RFC3339))
+	default:
+		message = fmt.Sprintf("Per-%s %s limit exceeded. Used: %d / Limit: %d. Resets at %s", window, metric, int64(used), int64(limit), resetAt.Format(time.RFC3339))
+	}
+	return &QuotaError{
+		Type:    QuotaErrorTypeWindowLimitExceeded,
+		Code:    fmt.Sprintf("%s_%s_limit_exceeded", window, metric),
+		Message: message,
+		Details: map[string]any{
+			"window":              string(window),
+			"metric":              metric,
+			"used":                used,
+			"limit":               limit,
+			"reset_at":            resetAt.Format(time.RFC3339),
+			"retry_after_seconds": retryAfterSeconds(retryAfter),
+		},
+		RetryAfter: retryAfter,
+	}
+}
+
+// retryAfterSeconds rounds a duration up to whole seconds for the Retry-After header.
+func retryAfterSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
 // CheckResult represents the result of a quota check.
 type CheckResult struct {
 	// Allowed indicates whether the request is allowed.
@@ -0,0 +1,199 @@
+package quota
+
+import (
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// Window identifies a windowed quota period.
+type Window string
+
+const (
+	// WindowMinute covers the current UTC minute.
+	WindowMinute Window = "minute"
+
+	// WindowHour covers the current UTC hour.
+	WindowHour Window = "hour"
+
+	// WindowDay covers the current UTC day.
+	WindowDay Window = "day"
+
+	// WindowMonth covers the current UTC calendar month.
+	WindowMonth Window = "month"
+)
+
+// allWindows lists the supported windows from shortest to longest.
+var allWindows = []Window{WindowMinute, WindowHour, WindowDay, WindowMonth}
+
+// WindowUsage tracks consumption within a single window.
+type WindowUsage struct {
+	// Start is the beginning of the window this usage belongs to.
+	Start time.Time `json:"start"`
+
+	// Requests is the number of requests admitted within the window.
+	Requests int64 `json:"requests"`
+
+	// Tokens is the total tokens consumed within the window.
+	Tokens int64 `json:"tokens"`
+
+	// CostUSD is the total cost in USD incurred within the window.
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// Start returns the beginning of the window containing t.
+func (w Window) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch w {
+	case WindowMinute:
+		return t.Truncate(time.Minute)
+	case WindowHour:
+		return t.Truncate(time.Hour)
+	case WindowDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case WindowMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return t
+	}
+}
+
+// End returns the instant the window containing t resets.
+func (w Window) End(t time.Time) time.Time {
+	start := w.Start(t)
+	switch w {
+	case WindowMinute:
+		return start.Add(time.Minute)
+	case WindowHour:
+		return start.Add(time.Hour)
+	case WindowDay:
+		return start.AddDate(0, 0, 1)
+	case WindowMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start
+	}
+}
+
+// windowLimit returns the configured limit for a window, or nil when unlimited.
+func windowLimit(policy *Policy, w Window) *config.APIKeyWindowLimit {
+	if policy == nil || policy.Limits == nil {
+		return nil
+	}
+	var limit *config.APIKeyWindowLimit
+	switch w {
+	case WindowMinute:
+		limit = policy.Limits.Minute
+	case WindowHour:
+		limit = policy.Limits.Hour
+	case WindowDay:
+		limit = policy.Limits.Day
+	case WindowMonth:
+		limit = policy.Limits.Month
+	}
+	if limit.IsZero() {
+		return nil
+	}
+	return limit
+}
+
+// currentWindow returns the usage of the window containing now without mutating u.
+// Stale windows are reported as empty.
+func (u *QuotaUsage) currentWindow(w Window, now time.Time) WindowUsage {
+	start := w.Start(now)
+	if u != nil && u.Windows != nil {
+		if wu, ok := u.Windows[w]; ok && wu != nil && wu.Start.Equal(start) {
+			return *wu
+		}
+	}
+	return WindowUsage{Start: start}
+}
+
+// advanceWindow returns the usage of the window containing now, resetting it when
+// the stored window has rolled over. The caller must hold the manager lock.
+func (u *QuotaUsage) advanceWindow(w Window, now time.Time) *WindowUsage {
+	start := w.Start(now)
+	if u.Windows == nil {
+		u.Windows = make(map[Window]*WindowUsage, len(allWindows))
+	}
+	wu, ok := u.Windows[w]
+	if !ok || wu == nil || !wu.Start.Equal(start) {
+		wu = &WindowUsage{Start: start}
+		u.Windows[w] = wu
+	}
+	return wu
+}
+
+// cloneWindows deep-copies the windows of a usage record.
+func cloneWindows(windows map[Window]*WindowUsage) map[Window]*WindowUsage {
+	if windows == nil {
+		return nil
+	}
+	out := make(map[Window]*WindowUsage, len(windows))
+	for w, wu := range windows {
+		if wu == nil {
+			continue
+		}
+		wuCopy := *wu
+		out[w] = &wuCopy
+	}
+	return out
+}
+
+// checkWindows returns an error for the exceeded window that resets last, so the reported
+// Retry-After is the earliest time the request can actually succeed.
+func checkWindows(policy *Policy, usage *QuotaUsage, now time.Time) *QuotaError {
+	var exceeded *QuotaError
+	var exceededReset time.Time
+	for _, w := range allWindows {
+		limit := windowLimit(policy, w)
+		if limit == nil {
+			continue
+		}
+		resetAt := w.End(now)
+		if exceeded != nil && !resetAt.After(exceededReset) {
+			continue
+		}
+		if err := checkWindow(w, limit, usage.currentWindow(w, now), resetAt, now); err != nil {
+			exceeded, exceededReset = err, resetAt
+		}
+	}
+	return exceeded
+}
+
+// checkWindow returns an error for the first limit of a window that has been reached.
+func checkWindow(w Window, limit *config.APIKeyWindowLimit, current WindowUsage, resetAt, now time.Time) *QuotaError {
+	if limit.Requests > 0 && current.Requests >= limit.Requests {
+		return NewWindowLimitExceededError(w, "requests", float64(current.Requests), float64(limit.Requests), resetAt, now)
+	}
+	if limit.Tokens > 0 && current.Tokens >= limit.Tokens {
+		return NewWindowLimitExceededError(w, "tokens", float64(current.Tokens), float64(limit.Tokens), resetAt, now)
+	}
+	if limit.CostUSD > 0 && current.CostUSD >= limit.CostUSD {
+		return NewWindowLimitExceededError(w, "cost_usd", current.CostUSD, limit.CostUSD, resetAt, now)
+	}
+	return nil
+}
+
+// windowStatus summarises configured window limits and their current usage.
+func windowStatus(policy *Policy, usage *QuotaUsage, now time.Time) map[string]any {
+	out := make(map[string]any)
+	for _, w := range allWindows {
+		limit := windowLimit(policy, w)
+		if limit == nil {
+			continue
+		}
+		current := usage.currentWindow(w, now)
+		out[string(w)] = map[string]any{
+			"window_start": current.Start,
+			"reset_at":     w.End(now),
+			"requests":     current.Requests,
+			"tokens":       current.Tokens,
+			"cost_usd":     current.CostUSD,
+			"max_requests": limit.Requests,
+			"max_tokens":   limit.Tokens,
+			"max_cost_usd": limit.CostUSD,
+		}
+	}
+	return out
+}
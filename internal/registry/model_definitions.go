@@ -192,6 +192,118 @@ func GetGeminiModels() []*ModelInfo {
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true, Levels: []string{"low", "high"}},
 		},
+		{
+			ID:                         "gemini-2.5-flash-image",
+			Object:                     "model",
+			Created:                    1759363200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-flash-image",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Flash Image",
+			Description:                "State-of-the-art image generation and editing model.",
+			InputTokenLimit:            1048576,
+			OutputTokenLimit:           8192,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
+			// image models don't support thinkingConfig; leave Thinking nil
+		},
+		{
+			ID:                         "gemini-2.5-flash-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-flash-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Flash Preview TTS",
+			Description:                "Controllable text-to-speech model with single and multi-speaker output.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
+		{
+			ID:                         "gemini-2.5-pro-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-pro-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Pro Preview TTS",
+			Description:                "Highest quality controllable text-to-speech model.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
+	}
+}
+
+// GetGeminiEmbeddingModels returns the Gemini embedding models. They are registered
+// alongside GetGeminiModels but kept out of it so chat model lists stay chat-only.
+func GetGeminiEmbeddingModels() []*ModelInfo {
+	return []*ModelInfo{
+		{
+			ID:                         "gemini-embedding-001",
+			Object:                     "model",
+			Created:                    1752019200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"},
+		},
+		{
+			ID:                         "text-embedding-004",
+			Object:                     "model",
+			Created:                    1715644800,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/text-embedding-004",
+			Version:                    "004",
+			DisplayName:                "Text Embedding 004",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"},
+		},
+	}
+}
+
+// GetGeminiVertexEmbeddingModels returns the Vertex AI embedding models.
+func GetGeminiVertexEmbeddingModels() []*ModelInfo {
+	return []*ModelInfo{
+		{
+			ID:                         "gemini-embedding-001",
+			Object:                     "model",
+			Created:                    1752019200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"},
+		},
+		{
+			ID:                         "text-embedding-005",
+			Object:                     "model",
+			Created:                    1731974400,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/text-embedding-005",
+			Version:                    "005",
+			DisplayName:                "Text Embedding 005",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			OutputTokenLimit:           1,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"},
+		},
 	}
 }
 
@@ -287,6 +399,49 @@ func GetGeminiVertexModels() []*ModelInfo {
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true, Levels: []string{"low", "high"}},
 		},
+		{
+			ID:                         "gemini-2.5-flash-image",
+			Object:                     "model",
+			Created:                    1759363200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-flash-image",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Flash Image",
+			Description:                "State-of-the-art image generation and editing model.",
+			InputTokenLimit:            1048576,
+			OutputTokenLimit:           8192,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
+			// image models don't support thinkingConfig; leave Thinking nil
+		},
+		{
+			ID:                         "gemini-2.5-flash-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-flash-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Flash Preview TTS",
+			Description:                "Controllable text-to-speech model with single and multi-speaker output.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
+		{
+			ID:                         "gemini-2.5-pro-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-pro-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Pro Preview TTS",
+			Description:                "Highest quality controllable text-to-speech model.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
 	}
 }
 
@@ -524,6 +679,34 @@ func GetAIStudioModels() []*ModelInfo {
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			// image models don't support thinkingConfig; leave Thinking nil
 		},
+		{
+			ID:                         "gemini-2.5-flash-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-flash-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Flash Preview TTS",
+			Description:                "Controllable text-to-speech model with single and multi-speaker output.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
+		{
+			ID:                         "gemini-2.5-pro-preview-tts",
+			Object:                     "model",
+			Created:                    1747699200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-2.5-pro-preview-tts",
+			Version:                    "2.5",
+			DisplayName:                "Gemini 2.5 Pro Preview TTS",
+			Description:                "Highest quality controllable text-to-speech model.",
+			InputTokenLimit:            8192,
+			OutputTokenLimit:           16384,
+			SupportedGenerationMethods: []string{"generateContent", "countTokens"},
+		},
 	}
 }
 
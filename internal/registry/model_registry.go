@@ -53,6 +53,19 @@ type ModelInfo struct {
 	Thinking *ThinkingSupport `json:"thinking,omitempty"`
 }
 
+// SupportsEmbeddings reports whether the model serves embedding requests.
+func (m *ModelInfo) SupportsEmbeddings() bool {
+	if m == nil {
+		return false
+	}
+	for _, method := range m.SupportedGenerationMethods {
+		if method == "embedContent" {
+			return true
+		}
+	}
+	return false
+}
+
 // ThinkingSupport describes a model family's supported internal reasoning budget range.
 // Values are interpreted in provider-native token units.
 type ThinkingSupport struct {
@@ -840,27 +853,59 @@ func (r *ModelRegistry) GetModelCount(modelID string) int {
 	defer r.mutex.RUnlock()
 
 	if registration, exists := r.models[modelID]; exists {
-		now := time.Now()
-		quotaExpiredDuration := 5 * time.Minute
+		return availableClientCount(registration, time.Now())
+	}
+	return 0
+}
 
-		// Count clients that have exceeded quota but haven't recovered yet
-		expiredClients := 0
-		for _, quotaTime := range registration.QuotaExceededClients {
-			if quotaTime != nil && now.Sub(*quotaTime) < quotaExpiredDuration {
-				expiredClients++
-			}
-		}
-		suspendedClients := 0
-		if registration.SuspendedClients != nil {
-			suspendedClients = len(registration.SuspendedClients)
-		}
-		result := registration.Count - expiredClients - suspendedClients
-		if result < 0 {
-			return 0
+// ModelAvailability reports how many clients serve a model.
+type ModelAvailability struct {
+	// Model is the model identifier.
+	Model string
+	// Registered is the number of clients that registered the model.
+	Registered int
+	// Available excludes clients that are suspended or recently exceeded their quota.
+	Available int
+}
+
+// GetModelAvailability returns the client counts of every registered model, sorted by model ID.
+func (r *ModelRegistry) GetModelAvailability() []ModelAvailability {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	now := time.Now()
+	out := make([]ModelAvailability, 0, len(r.models))
+	for modelID, registration := range r.models {
+		out = append(out, ModelAvailability{
+			Model:      modelID,
+			Registered: registration.Count,
+			Available:  availableClientCount(registration, now),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// availableClientCount subtracts suspended and quota-exceeded clients from a registration.
+func availableClientCount(registration *ModelRegistration, now time.Time) int {
+	quotaExpiredDuration := 5 * time.Minute
+
+	// Count clients that have exceeded quota but haven't recovered yet
+	expiredClients := 0
+	for _, quotaTime := range registration.QuotaExceededClients {
+		if quotaTime != nil && now.Sub(*quotaTime) < quotaExpiredDuration {
+			expiredClients++
 		}
-		return result
 	}
-	return 0
+	suspendedClients := 0
+	if registration.SuspendedClients != nil {
+		suspendedClients = len(registration.SuspendedClients)
+	}
+	result := registration.Count - expiredClients - suspendedClients
+	if result < 0 {
+		return 0
+	}
+	return result
 }
 
 // GetModelProviders returns provider identifiers that currently supply the given model
@@ -0,0 +1,363 @@
+// Package requesthistory keeps a searchable index of proxied requests. Metadata for every
+// logged request is appended to request-history.jsonl next to the request logs and held in
+// memory for filtering; the full request and response stay in the request log itself.
+package requesthistory
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FileName is the append-only index written under the logs directory.
+	FileName = "request-history.jsonl"
+
+	// DefaultMaxEntries bounds the number of requests kept in the index.
+	DefaultMaxEntries = 50000
+
+	// DefaultLimit and MaxLimit bound the page size of Query.
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Entry is the indexed metadata of one request.
+type Entry struct {
+	ID         string    `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	LatencyMs  int64     `json:"latency_ms"`
+	APIKey     string    `json:"api_key,omitempty"`
+	APIKeyHash string    `json:"api_key_hash,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	AuthIndex  string    `json:"auth_index,omitempty"`
+	Streaming  bool      `json:"streaming,omitempty"`
+}
+
+// NewEntry builds an entry for a request made with apiKey. Only a masked form and a hash
+// of the key are kept, so the index can be filtered by key without storing it.
+func NewEntry(id string, startedAt time.Time, method, path string, status int, apiKey string) Entry {
+	entry := Entry{
+		ID:        id,
+		Timestamp: startedAt,
+		Method:    method,
+		Path:      path,
+		Status:    status,
+		LatencyMs: time.Since(startedAt).Milliseconds(),
+	}
+	if apiKey != "" {
+		entry.APIKey = util.HideAPIKey(apiKey)
+		entry.APIKeyHash = HashAPIKey(apiKey)
+	}
+	return entry
+}
+
+// HashAPIKey returns the digest stored for apiKey.
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Query filters the index. Zero values do not filter.
+type Query struct {
+	From time.Time
+	To   time.Time
+	// APIKey matches either the full client key or its masked form.
+	APIKey    string
+	Model     string
+	Provider  string
+	AuthIndex string
+	// Status matches an exact code; StatusClass matches its hundreds digit (e.g. 5 for 5xx).
+	Status      int
+	StatusClass int
+	MinLatency  time.Duration
+	MaxLatency  time.Duration
+	Limit       int
+	Offset      int
+}
+
+// Page is one page of Query results, newest first.
+type Page struct {
+	Total    int     `json:"total"`
+	Limit    int     `json:"limit"`
+	Offset   int     `json:"offset"`
+	Requests []Entry `json:"requests"`
+}
+
+// Store is the request history index.
+type Store struct {
+	mu         sync.RWMutex
+	path       string
+	file       *os.File
+	entries    []Entry
+	byID       map[string]int
+	lines      int
+	maxEntries int
+}
+
+var defaultStore = NewStore(DefaultMaxEntries)
+
+// Default returns the process-wide store.
+func Default() *Store { return defaultStore }
+
+// NewStore returns an empty store keeping at most maxEntries requests.
+func NewStore(maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Store{byID: make(map[string]int), maxEntries: maxEntries}
+}
+
+// Open loads the index stored in dir and appends new entries to it. The file is created on
+// the first Record. Reopening the current directory is a no-op.
+func (s *Store) Open(dir string) error {
+	if s == nil || strings.TrimSpace(dir) == "" {
+		return nil
+	}
+	path := filepath.Join(dir, FileName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == path {
+		return nil
+	}
+	_ = s.closeLocked()
+	s.path = path
+	s.entries = nil
+	s.byID = make(map[string]int)
+	s.lines = 0
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("request history: open index: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.ID == "" {
+			continue
+		}
+		s.lines++
+		s.appendLocked(entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("request history: read index: %w", err)
+	}
+	return nil
+}
+
+// Close closes the index file. Later records reopen it.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeLocked()
+}
+
+func (s *Store) closeLocked() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Record indexes entry and appends it to the index file, when one is open.
+func (s *Store) Record(entry Entry) {
+	if s == nil || entry.ID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appendLocked(entry)
+	if s.path == "" {
+		return
+	}
+	if err := s.persistLocked(entry); err != nil {
+		log.WithError(err).Warn("request history: failed to append entry")
+	}
+}
+
+// appendLocked adds entry to the in-memory index, dropping the oldest entries beyond the
+// limit. A repeated ID replaces the earlier entry.
+func (s *Store) appendLocked(entry Entry) {
+	if idx, ok := s.byID[entry.ID]; ok {
+		s.entries[idx] = entry
+		return
+	}
+	s.byID[entry.ID] = len(s.entries)
+	s.entries = append(s.entries, entry)
+	if len(s.entries) <= s.maxEntries+s.maxEntries/10 {
+		return
+	}
+	kept := make([]Entry, s.maxEntries)
+	copy(kept, s.entries[len(s.entries)-s.maxEntries:])
+	s.entries = kept
+	s.byID = make(map[string]int, len(kept))
+	for i := range kept {
+		s.byID[kept[i].ID] = i
+	}
+}
+
+// persistLocked appends entry to the index file. Once the file holds twice the entry limit
+// it is rewritten from memory instead, which already includes entry.
+func (s *Store) persistLocked(entry Entry) error {
+	if s.lines >= 2*s.maxEntries {
+		return s.compactLocked()
+	}
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lines++
+	return nil
+}
+
+// compactLocked rewrites the index file with the entries still held in memory.
+func (s *Store) compactLocked() error {
+	_ = s.closeLocked()
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for i := range s.entries {
+		if err = encoder.Encode(s.entries[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	s.lines = len(s.entries)
+	return nil
+}
+
+// Get returns the entry recorded for id.
+func (s *Store) Get(id string) (Entry, bool) {
+	if s == nil {
+		return Entry{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	idx, ok := s.byID[id]
+	if !ok {
+		return Entry{}, false
+	}
+	return s.entries[idx], true
+}
+
+// Query returns the entries matching q, newest first.
+func (s *Store) Query(q Query) Page {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	page := Page{Limit: q.Limit, Offset: q.Offset, Requests: []Entry{}}
+	if s == nil {
+		return page
+	}
+	keyHash := ""
+	if q.APIKey != "" {
+		keyHash = HashAPIKey(q.APIKey)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := &s.entries[i]
+		if !q.matches(entry, keyHash) {
+			continue
+		}
+		if page.Total >= q.Offset && len(page.Requests) < q.Limit {
+			page.Requests = append(page.Requests, *entry)
+		}
+		page.Total++
+	}
+	return page
+}
+
+func (q *Query) matches(entry *Entry, keyHash string) bool {
+	if !q.From.IsZero() && entry.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && entry.Timestamp.After(q.To) {
+		return false
+	}
+	if q.APIKey != "" && entry.APIKeyHash != keyHash && entry.APIKey != q.APIKey {
+		return false
+	}
+	if q.Model != "" && !strings.EqualFold(entry.Model, q.Model) {
+		return false
+	}
+	if q.Provider != "" && !strings.EqualFold(entry.Provider, q.Provider) {
+		return false
+	}
+	if q.AuthIndex != "" && entry.AuthIndex != q.AuthIndex {
+		return false
+	}
+	if q.Status != 0 && entry.Status != q.Status {
+		return false
+	}
+	if q.StatusClass != 0 && entry.Status/100 != q.StatusClass {
+		return false
+	}
+	latency := time.Duration(entry.LatencyMs) * time.Millisecond
+	if q.MinLatency > 0 && latency < q.MinLatency {
+		return false
+	}
+	if q.MaxLatency > 0 && latency > q.MaxLatency {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package requesthistory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEntry(id string, at time.Time, status int, latency time.Duration, model, provider, authIndex, apiKey string) Entry {
+	entry := NewEntry(id, at, "POST", "/v1/chat/completions", status, apiKey)
+	entry.LatencyMs = latency.Milliseconds()
+	entry.Model = model
+	entry.Provider = provider
+	entry.AuthIndex = authIndex
+	return entry
+}
+
+func TestStoreQueryFilters(t *testing.T) {
+	store := NewStore(100)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Record(testEntry("a", base, 200, 100*time.Millisecond, "gpt-5", "codex", "1", "sk-client-one-aaaa"))
+	store.Record(testEntry("b", base.Add(time.Minute), 429, 2*time.Second, "gpt-5", "codex", "2", "sk-client-two-bbbb"))
+	store.Record(testEntry("c", base.Add(2*time.Minute), 502, 5*time.Second, "claude-sonnet-4", "claude", "3", "sk-client-one-aaaa"))
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all newest first", Query{}, []string{"c", "b", "a"}},
+		{"model", Query{Model: "GPT-5"}, []string{"b", "a"}},
+		{"provider", Query{Provider: "claude"}, []string{"c"}},
+		{"auth index", Query{AuthIndex: "2"}, []string{"b"}},
+		{"full api key", Query{APIKey: "sk-client-one-aaaa"}, []string{"c", "a"}},
+		{"masked api key", Query{APIKey: store.entries[1].APIKey}, []string{"b"}},
+		{"status", Query{Status: 429}, []string{"b"}},
+		{"status class", Query{StatusClass: 5}, []string{"c"}},
+		{"latency", Query{MinLatency: time.Second, MaxLatency: 3 * time.Second}, []string{"b"}},
+		{"time range", Query{From: base.Add(30 * time.Second), To: base.Add(90 * time.Second)}, []string{"b"}},
+		{"pagination", Query{Limit: 1, Offset: 1}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := store.Query(tt.query)
+			var got []string
+			for _, entry := range page.Requests {
+				got = append(got, entry.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if page := store.Query(Query{Limit: 1}); page.Total != 3 {
+		t.Fatalf("total = %d, want 3", page.Total)
+	}
+}
+
+func TestStorePersistsAndCompacts(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(2)
+	if err := store.Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		store.Record(testEntry(id, base.Add(time.Duration(i)*time.Second), 200, time.Second, "m", "p", "", "sk-secret-client-key"))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	if strings.Contains(string(raw), "sk-secret-client-key") {
+		t.Fatal("index must not store client API keys")
+	}
+	if lines := strings.Count(string(raw), "\n"); lines > 4 {
+		t.Fatalf("index holds %d lines, want compaction to at most 4", lines)
+	}
+
+	reloaded := NewStore(2)
+	if err = reloaded.Open(dir); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, ok := reloaded.Get("e"); !ok {
+		t.Fatal("latest entry missing after reload")
+	}
+	if _, ok := reloaded.Get("a"); ok {
+		t.Fatal("oldest entry should have been dropped")
+	}
+}
@@ -0,0 +1,262 @@
+// Package responsecache provides an exact-match cache for deterministic model requests, so
+// identical prompts replayed by CI jobs are answered without calling the upstream again.
+// Both non-streaming bodies and the chunk sequence of streamed responses are cached.
+package responsecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// ErrNotFound is returned when no entry exists for a key or it has expired.
+var ErrNotFound = errors.New("cache entry not found")
+
+const (
+	// BackendMemory keeps entries in process memory (default).
+	BackendMemory = "memory"
+	// BackendFile stores one file per entry in a directory.
+	BackendFile = "file"
+	// BackendRedis stores entries in a Redis-compatible server.
+	BackendRedis = "redis"
+
+	// ScopeKey shares cached responses only between requests made with the same client API
+	// key (default).
+	ScopeKey = "key"
+	// ScopeGlobal shares cached responses between all clients.
+	ScopeGlobal = "global"
+
+	defaultTTL           = time.Hour
+	defaultMaxEntryBytes = 1 << 20
+)
+
+// volatileFields are removed before hashing because they do not change the model output.
+var volatileFields = []string{"stream", "stream_options", "user", "metadata", "store", "safety_identifier", "prompt_cache_key"}
+
+// Entry is a cached response.
+type Entry struct {
+	// Body is the response of a non-streaming request.
+	Body []byte `json:"body,omitempty"`
+	// Chunks are the payloads of a streamed response in the order they were produced.
+	Chunks [][]byte `json:"chunks,omitempty"`
+	// CreatedAt is when the entry was stored.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// size returns the number of payload bytes held by the entry.
+func (e *Entry) size() int {
+	n := len(e.Body)
+	for _, chunk := range e.Chunks {
+		n += len(chunk)
+	}
+	return n
+}
+
+// Store is a pluggable backend for cache entries.
+type Store interface {
+	// Get returns the entry stored under key or ErrNotFound.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Put stores an entry that expires after ttl.
+	Put(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+	// Close releases resources held by the backend.
+	Close() error
+}
+
+// Cache applies the cache policy on top of a Store.
+type Cache struct {
+	store            Store
+	ttl              time.Duration
+	maxEntryBytes    int
+	nondeterministic bool
+	// global ignores the request owner, sharing entries between all clients.
+	global bool
+}
+
+// Request identifies a cacheable request. Every field that changes the upstream request
+// or the shape of the response is part of the key.
+type Request struct {
+	// Owner identifies the client, e.g. a hash of its API key. Caches with global scope
+	// ignore it.
+	Owner string
+	// Format is the source format the payload is translated from.
+	Format string
+	// Model is the resolved model the payload is translated for.
+	Model string
+	// Metadata holds request options derived from the model name, such as a thinking
+	// budget suffix, which the translated payload depends on.
+	Metadata map[string]any
+	// Alt is the alternate response format requested by Gemini clients.
+	Alt string
+	// Stream distinguishes streamed from non-streaming responses.
+	Stream bool
+	// Payload is the request body after handler-side rewrites.
+	Payload []byte
+}
+
+// NewCache wraps a store. Non-positive ttl and maxEntryBytes use 1 hour and 1 MiB.
+func NewCache(store Store, ttl time.Duration, maxEntryBytes int, nondeterministic bool) *Cache {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if maxEntryBytes <= 0 {
+		maxEntryBytes = defaultMaxEntryBytes
+	}
+	return &Cache{store: store, ttl: ttl, maxEntryBytes: maxEntryBytes, nondeterministic: nondeterministic}
+}
+
+// New builds the cache described by the configuration. It returns nil when caching is disabled.
+func New(cfg config.ResponseCacheConfig) (*Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	var store Store
+	var err error
+	switch backend := strings.ToLower(strings.TrimSpace(cfg.Backend)); backend {
+	case "", BackendMemory:
+		store = NewMemoryStore(cfg.MaxEntries)
+	case BackendFile:
+		store, err = NewFileStore(cfg.Dir)
+	case BackendRedis:
+		store, err = NewRedisStore(cfg.RedisURL, cfg.KeyPrefix)
+	default:
+		err = fmt.Errorf("response cache: unknown backend %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache := NewCache(store, time.Duration(cfg.TTLSeconds)*time.Second, cfg.MaxEntryBytes, cfg.CacheNondeterministic)
+	switch scope := strings.ToLower(strings.TrimSpace(cfg.Scope)); scope {
+	case "", ScopeKey:
+	case ScopeGlobal:
+		cache.global = true
+	default:
+		_ = store.Close()
+		return nil, fmt.Errorf("response cache: unknown scope %q", scope)
+	}
+	return cache, nil
+}
+
+// Cacheable reports whether a request payload may be served from the cache. Unless the cache
+// accepts nondeterministic requests, only requests with temperature 0 qualify.
+func (c *Cache) Cacheable(payload []byte) bool {
+	if c == nil {
+		return false
+	}
+	if c.nondeterministic {
+		return true
+	}
+	for _, path := range []string{"temperature", "generationConfig.temperature", "request.generationConfig.temperature"} {
+		if value := gjson.GetBytes(payload, path); value.Exists() {
+			return value.Type == gjson.Number && value.Float() == 0
+		}
+	}
+	return false
+}
+
+// Get returns the entry stored under key or ErrNotFound.
+func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
+	return c.store.Get(ctx, key)
+}
+
+// Put stores entry unless it exceeds the size limit.
+func (c *Cache) Put(ctx context.Context, key string, entry *Entry) error {
+	if entry == nil || entry.size() == 0 || entry.size() > c.maxEntryBytes {
+		return nil
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return c.store.Put(ctx, key, entry, c.ttl)
+}
+
+// MaxEntryBytes returns the payload size above which entries are not stored.
+func (c *Cache) MaxEntryBytes() int {
+	return c.maxEntryBytes
+}
+
+// Key derives the cache key of req, dropping the owner when entries are shared globally.
+func (c *Cache) Key(req Request) (string, error) {
+	if c.global {
+		req.Owner = ""
+	}
+	return Key(req)
+}
+
+// Close closes the underlying store.
+func (c *Cache) Close() error {
+	if c == nil || c.store == nil {
+		return nil
+	}
+	return c.store.Close()
+}
+
+// Key derives the cache key of a request from its owner, source format, resolved model,
+// model metadata, alt parameter, streaming mode and normalized payload. Translation to the
+// provider format is deterministic for these inputs, so together they identify the upstream
+// request.
+func Key(req Request) (string, error) {
+	normalized, err := Normalize(req.Payload)
+	if err != nil {
+		return "", err
+	}
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		return "", fmt.Errorf("response cache: encode metadata: %w", err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%t\x00", req.Owner, req.Format, req.Model, req.Alt, req.Stream)
+	h.Write(metadata)
+	h.Write([]byte{0})
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Normalize returns a canonical encoding of a JSON payload: object keys are sorted,
+// insignificant whitespace is removed and fields that do not affect the output are dropped.
+func Normalize(payload []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("response cache: decode payload: %w", err)
+	}
+	if object, ok := value.(map[string]any); ok {
+		for _, field := range volatileFields {
+			delete(object, field)
+		}
+	}
+	return json.Marshal(value)
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultCache *Cache
+)
+
+// Default returns the cache used by the API handlers, or nil when caching is disabled.
+func Default() *Cache {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCache
+}
+
+// SetDefault replaces the cache used by the API handlers and closes the previous one.
+func SetDefault(cache *Cache) {
+	defaultMu.Lock()
+	previous := defaultCache
+	defaultCache = cache
+	defaultMu.Unlock()
+	if previous != nil && previous != cache {
+		_ = previous.Close()
+	}
+}
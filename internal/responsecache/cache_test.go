@@ -0,0 +1,203 @@
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestKeyNormalizesPayload(t *testing.T) {
+	req := func(format string, stream bool, payload string) Request {
+		return Request{Owner: "owner-a", Format: format, Model: "m", Stream: stream, Payload: []byte(payload)}
+	}
+	a, err := Key(req("openai", false, `{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}],"user":"ci-1"}`))
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	b, _ := Key(req("openai", false, `{ "messages":[{"content":"hi","role":"user"}], "temperature":0, "model":"m", "user":"ci-2" }`))
+	if a != b {
+		t.Fatal("equivalent payloads produced different keys")
+	}
+	if c, _ := Key(req("openai", true, `{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)); c == a {
+		t.Fatal("streaming and non-streaming requests share a key")
+	}
+	if d, _ := Key(req("claude", false, `{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)); d == a {
+		t.Fatal("different source formats share a key")
+	}
+	thinking := req("openai", false, `{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)
+	thinking.Metadata = map[string]any{"thinking_budget": 1024}
+	if e, _ := Key(thinking); e == a {
+		t.Fatal("requests with different model metadata share a key")
+	}
+}
+
+func TestCacheKeyScope(t *testing.T) {
+	payload := []byte(`{"model":"m","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)
+	keys := func(cache *Cache) (string, string) {
+		a, _ := cache.Key(Request{Owner: "owner-a", Format: "openai", Model: "m", Payload: payload})
+		b, _ := cache.Key(Request{Owner: "owner-b", Format: "openai", Model: "m", Payload: payload})
+		return a, b
+	}
+	perKey, err := New(config.ResponseCacheConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if a, b := keys(perKey); a == b {
+		t.Fatal("default scope shares entries between API keys")
+	}
+	global, err := New(config.ResponseCacheConfig{Enabled: true, Scope: "global"})
+	if err != nil {
+		t.Fatalf("New global: %v", err)
+	}
+	if a, b := keys(global); a != b {
+		t.Fatal("global scope keeps entries per API key")
+	}
+	if _, err = New(config.ResponseCacheConfig{Enabled: true, Scope: "team"}); err == nil {
+		t.Fatal("unknown scope was accepted")
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	cache := NewCache(NewMemoryStore(0), 0, 0, false)
+	cases := map[string]bool{
+		`{"temperature":0}`:                      true,
+		`{"temperature":0.7}`:                    false,
+		`{}`:                                     false,
+		`{"generationConfig":{"temperature":0}}`: true,
+		`{"request":{"generationConfig":{"temperature":0}}}`: true,
+	}
+	for payload, want := range cases {
+		if got := cache.Cacheable([]byte(payload)); got != want {
+			t.Errorf("Cacheable(%s) = %t, want %t", payload, got, want)
+		}
+	}
+	if !NewCache(NewMemoryStore(0), 0, 0, true).Cacheable([]byte(`{}`)) {
+		t.Error("nondeterministic cache rejected a request without temperature")
+	}
+}
+
+func TestMemoryStoreEvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = store.Put(ctx, key, &Entry{Body: []byte(key)}, time.Hour)
+	}
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("oldest entry was not evicted: %v", err)
+	}
+	_ = store.Put(ctx, "short", &Entry{Body: []byte("x")}, -time.Second)
+	if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expired entry was returned: %v", err)
+	}
+}
+
+func TestCachePutSkipsOversizedEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache(NewMemoryStore(0), time.Hour, 4, false)
+	_ = cache.Put(ctx, "big", &Entry{Chunks: [][]byte{[]byte("abc"), []byte("def")}})
+	if _, err := cache.Get(ctx, "big"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("oversized entry was stored: %v", err)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	if err = store.Put(ctx, "abc123", &Entry{Chunks: [][]byte{[]byte("one"), []byte("two")}}, time.Hour); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	entry, err := store.Get(ctx, "abc123")
+	if err != nil || len(entry.Chunks) != 2 || string(entry.Chunks[1]) != "two" {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+	if _, err = store.Get(ctx, "../etc"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("path traversal key was accepted: %v", err)
+	}
+}
+
+// fakeRedis implements GET and SET (ignoring expiry) for a single test.
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	var mu sync.Mutex
+	data := map[string]string{}
+	go func() {
+		for {
+			conn, errAccept := listener.Accept()
+			if errAccept != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+				r := bufio.NewReader(conn)
+				for {
+					line, errRead := r.ReadString('\n')
+					if errRead != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						header, _ := r.ReadString('\n')
+						size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+						buf := make([]byte, size+2)
+						_, _ = io.ReadFull(r, buf)
+						args[i] = string(buf[:size])
+					}
+					mu.Lock()
+					switch strings.ToUpper(args[0]) {
+					case "SET":
+						data[args[1]] = args[2]
+						_, _ = conn.Write([]byte("+OK\r\n"))
+					case "GET":
+						if value, ok := data[args[1]]; ok {
+							_, _ = fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+						} else {
+							_, _ = conn.Write([]byte("$-1\r\n"))
+						}
+					default:
+						_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+					}
+					mu.Unlock()
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewRedisStore("redis://"+fakeRedis(t), "")
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if _, err = store.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if err = store.Put(ctx, "k", &Entry{Body: []byte(`{"ok":true}`)}, time.Minute); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	entry, err := store.Get(ctx, "k")
+	if err != nil || string(entry.Body) != `{"ok":true}` {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+}
@@ -0,0 +1,128 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// fileEntry is the on-disk form of an entry.
+type fileEntry struct {
+	Entry
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileStore persists each entry as <key>.json inside a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file-backed store rooted at dir. An empty dir uses
+// "response-cache" under the writable base path or the working directory.
+func NewFileStore(dir string) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		base := util.WritablePath()
+		if base == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("response cache: resolve working directory: %w", err)
+			}
+			base = wd
+		}
+		dir = filepath.Join(base, "response-cache")
+	}
+	resolved, err := util.ResolveAuthDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("response cache: resolve directory: %w", err)
+	}
+	if err = os.MkdirAll(resolved, 0o700); err != nil {
+		return nil, fmt.Errorf("response cache: create directory: %w", err)
+	}
+	store := &FileStore{dir: resolved}
+	store.prune()
+	return store, nil
+}
+
+// prune removes entries that expired while the server was not running.
+func (s *FileStore) prune() {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		key, ok := strings.CutSuffix(file.Name(), ".json")
+		if !ok || file.IsDir() {
+			continue
+		}
+		_, _ = s.Get(context.Background(), key)
+	}
+}
+
+// Get reads the entry stored under key, deleting it when expired.
+func (s *FileStore) Get(_ context.Context, key string) (*Entry, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("response cache: read entry: %w", err)
+	}
+	var stored fileEntry
+	if err = json.Unmarshal(data, &stored); err != nil {
+		_ = os.Remove(path)
+		return nil, ErrNotFound
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		_ = os.Remove(path)
+		return nil, ErrNotFound
+	}
+	return &stored.Entry, nil
+}
+
+// Put writes the entry atomically.
+func (s *FileStore) Put(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	if entry == nil {
+		return nil
+	}
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(fileEntry{Entry: *entry, ExpiresAt: time.Now().Add(ttl)})
+	if err != nil {
+		return fmt.Errorf("response cache: marshal entry: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("response cache: write entry: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("response cache: commit entry: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op for the file store.
+func (s *FileStore) Close() error { return nil }
+
+// path maps a key to its file, rejecting keys that could escape the directory.
+func (s *FileStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\.`) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, key+".json"), nil
+}
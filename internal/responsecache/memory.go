@@ -0,0 +1,78 @@
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultMemoryMaxEntries bounds the in-memory cache when no limit is configured.
+const defaultMemoryMaxEntries = 1000
+
+type memoryItem struct {
+	key     string
+	entry   *Entry
+	expires time.Time
+}
+
+// MemoryStore keeps entries in process memory, evicting the least recently used entry once
+// the entry limit is reached.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	items      map[string]*list.Element
+}
+
+// NewMemoryStore creates an in-memory store. Non-positive maxEntries uses 1000.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryMaxEntries
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	item := elem.Value.(*memoryItem)
+	if time.Now().After(item.expires) {
+		s.order.Remove(elem)
+		delete(s.items, key)
+		return nil, ErrNotFound
+	}
+	s.order.MoveToBack(elem)
+	return item.entry, nil
+}
+
+// Put stores or replaces an entry.
+func (s *MemoryStore) Put(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	if entry == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		s.order.Remove(elem)
+	}
+	s.items[key] = s.order.PushBack(&memoryItem{key: key, entry: entry, expires: time.Now().Add(ttl)})
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+// Close is a no-op for the memory store.
+func (s *MemoryStore) Close() error { return nil }
@@ -0,0 +1,264 @@
+package responsecache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRedisKeyPrefix = "cliproxy:response-cache:"
+	redisDialTimeout      = 5 * time.Second
+	redisIOTimeout        = 5 * time.Second
+	redisMaxIdle          = 4
+)
+
+// errRedisNil is the RESP null bulk reply returned for missing keys.
+var errRedisNil = errors.New("redis: nil")
+
+// RedisStore stores entries in a Redis-compatible server using GET and SET with PX expiry.
+// It speaks RESP directly and keeps a few idle connections for reuse.
+type RedisStore struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+	prefix   string
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedisStore parses a redis:// or rediss:// URL (or a bare host:port) and returns a store
+// that prefixes every key with prefix.
+func NewRedisStore(rawURL, prefix string) (*RedisStore, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, errors.New("response cache: redis-url is required for the redis backend")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "redis://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("response cache: parse redis-url: %w", err)
+	}
+	if parsed.Scheme != "redis" && parsed.Scheme != "rediss" {
+		return nil, fmt.Errorf("response cache: unsupported redis-url scheme %q", parsed.Scheme)
+	}
+	store := &RedisStore{
+		addr:   parsed.Host,
+		useTLS: parsed.Scheme == "rediss",
+		prefix: prefix,
+		idle:   make(chan *redisConn, redisMaxIdle),
+	}
+	if parsed.Port() == "" {
+		store.addr = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if store.prefix == "" {
+		store.prefix = defaultRedisKeyPrefix
+	}
+	if parsed.User != nil {
+		store.username = parsed.User.Username()
+		store.password, _ = parsed.User.Password()
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" {
+		if store.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("response cache: invalid redis database %q", db)
+		}
+	}
+	return store, nil
+}
+
+// Get returns the entry stored under key.
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	reply, err := s.do(ctx, "GET", s.prefix+key)
+	if errors.Is(err, errRedisNil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("response cache: unexpected redis reply %T", reply)
+	}
+	var entry Entry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+// Put stores the entry with a PX expiry.
+func (s *RedisStore) Put(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	if entry == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("response cache: marshal entry: %w", err)
+	}
+	_, err = s.do(ctx, "SET", s.prefix+key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case conn := <-s.idle:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs one command, reusing an idle connection when available.
+func (s *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(redisIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	reply, err := conn.command(args...)
+	var serverErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &serverErr) {
+		// The connection state is unknown after an I/O error.
+		_ = conn.Close()
+		return nil, fmt.Errorf("response cache: redis %s: %w", args[0], err)
+	}
+	select {
+	case s.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection or dials, authenticates and selects the database.
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var raw net.Conn
+	var err error
+	if s.useTLS {
+		raw, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", s.addr)
+	} else {
+		raw, err = dialer.DialContext(ctx, "tcp", s.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("response cache: dial redis: %w", err)
+	}
+	conn := &redisConn{Conn: raw, r: bufio.NewReader(raw)}
+	_ = conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err = conn.command(args...); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("response cache: redis AUTH: %w", err)
+		}
+	}
+	if s.db != 0 {
+		if _, err = conn.command("SELECT", strconv.Itoa(s.db)); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("response cache: redis SELECT: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply sent by the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// command writes a RESP array of bulk strings and reads one reply.
+func (c *redisConn) command(args ...string) (any, error) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.Write([]byte(buf.String())); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply parses a single RESP2 reply.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, errParse := strconv.Atoi(line[1:])
+		if errParse != nil {
+			return nil, errParse
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, errParse := strconv.Atoi(line[1:])
+		if errParse != nil {
+			return nil, errParse
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			item, errItem := c.readReply()
+			if errItem != nil && !errors.Is(errItem, errRedisNil) {
+				return nil, errItem
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
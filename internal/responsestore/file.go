@@ -0,0 +1,121 @@
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// FileStore persists each record as <id>.json inside a directory.
+type FileStore struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewFileStore creates a file-backed store rooted at dir. An empty dir uses
+// "responses" under the writable base path or the working directory.
+func NewFileStore(dir string, ttl time.Duration) (*FileStore, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		base := util.WritablePath()
+		if base == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("response store: resolve working directory: %w", err)
+			}
+			base = wd
+		}
+		dir = filepath.Join(base, "responses")
+	}
+	resolved, err := util.ResolveAuthDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("response store: resolve directory: %w", err)
+	}
+	if err = os.MkdirAll(resolved, 0o700); err != nil {
+		return nil, fmt.Errorf("response store: create directory: %w", err)
+	}
+	return &FileStore{dir: resolved, ttl: ttl}, nil
+}
+
+// Put writes the record atomically.
+func (s *FileStore) Put(_ context.Context, record *Record) error {
+	if record == nil || record.ID == "" {
+		return nil
+	}
+	path, err := s.path(record.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("response store: marshal record: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("response store: write record: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("response store: commit record: %w", err)
+	}
+	return nil
+}
+
+// Get reads the record with the given ID, deleting it when expired.
+func (s *FileStore) Get(_ context.Context, id string) (*Record, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("response store: read record: %w", err)
+	}
+	var record Record
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("response store: decode record: %w", err)
+	}
+	if expired(record.CreatedAt, s.ttl, time.Now()) {
+		_ = os.Remove(path)
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// Delete removes the record file.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("response store: delete record: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op for the file store.
+func (s *FileStore) Close() error { return nil }
+
+// path maps a response ID to its file, rejecting IDs that could escape the directory.
+func (s *FileStore) path(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
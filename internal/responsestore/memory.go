@@ -0,0 +1,89 @@
+package responsestore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultMemoryMaxEntries bounds the in-memory store when no limit is configured.
+const defaultMemoryMaxEntries = 10000
+
+// MemoryStore keeps records in process memory, evicting the least recently stored
+// record once MaxEntries is reached.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// NewMemoryStore creates an in-memory store. Non-positive maxEntries uses 10000;
+// a non-positive ttl keeps records until they are evicted.
+func NewMemoryStore(maxEntries int, ttl time.Duration) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryMaxEntries
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Put stores or replaces a record.
+func (s *MemoryStore) Put(_ context.Context, record *Record) error {
+	if record == nil || record.ID == "" {
+		return nil
+	}
+	clone := *record
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[record.ID]; ok {
+		s.order.Remove(elem)
+	}
+	s.entries[record.ID] = s.order.PushBack(&clone)
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*Record).ID)
+	}
+	return nil
+}
+
+// Get returns the record with the given ID.
+func (s *MemoryStore) Get(_ context.Context, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := elem.Value.(*Record)
+	if expired(record.CreatedAt, s.ttl, time.Now()) {
+		s.order.Remove(elem)
+		delete(s.entries, id)
+		return nil, ErrNotFound
+	}
+	clone := *record
+	return &clone, nil
+}
+
+// Delete removes the record with the given ID.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s.order.Remove(elem)
+	delete(s.entries, id)
+	return nil
+}
+
+// Close is a no-op for the in-memory store.
+func (s *MemoryStore) Close() error { return nil }
@@ -0,0 +1,149 @@
+package responsestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const defaultResponseTable = "response_store"
+
+// PostgresConfig captures the settings of the PostgreSQL-backed store.
+type PostgresConfig struct {
+	DSN    string
+	Schema string
+	Table  string
+	TTL    time.Duration
+}
+
+// PostgresStore keeps records in a PostgreSQL table with a JSONB payload.
+type PostgresStore struct {
+	db  *sql.DB
+	cfg PostgresConfig
+}
+
+// NewPostgresStore connects to PostgreSQL and ensures the response table exists.
+func NewPostgresStore(ctx context.Context, cfg PostgresConfig) (*PostgresStore, error) {
+	cfg.DSN = strings.TrimSpace(cfg.DSN)
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("response store: postgres DSN is required")
+	}
+	if strings.TrimSpace(cfg.Table) == "" {
+		cfg.Table = defaultResponseTable
+	}
+	db, err := sql.Open("pgx", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("response store: open database connection: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("response store: ping database: %w", err)
+	}
+	s := &PostgresStore{db: db, cfg: cfg}
+	if err = s.ensureSchema(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) ensureSchema(ctx context.Context) error {
+	if schema := strings.TrimSpace(s.cfg.Schema); schema != "" {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", quoteIdentifier(schema))); err != nil {
+			return fmt.Errorf("response store: create schema: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			content JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`, s.table())); err != nil {
+		return fmt.Errorf("response store: create table: %w", err)
+	}
+	return nil
+}
+
+// Put upserts the record.
+func (s *PostgresStore) Put(ctx context.Context, record *Record) error {
+	if record == nil || record.ID == "" {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("response store: marshal record: %w", err)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, content, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id)
+		DO UPDATE SET content = EXCLUDED.content, created_at = EXCLUDED.created_at
+	`, s.table())
+	if _, err = s.db.ExecContext(ctx, query, record.ID, string(data), record.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("response store: upsert record: %w", err)
+	}
+	if s.cfg.TTL > 0 {
+		// Opportunistically purge expired rows so the table does not grow without bound.
+		purge := fmt.Sprintf("DELETE FROM %s WHERE created_at < $1", s.table())
+		_, _ = s.db.ExecContext(ctx, purge, time.Now().Add(-s.cfg.TTL).UTC())
+	}
+	return nil
+}
+
+// Get loads the record with the given ID.
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Record, error) {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.table())
+	var content string
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&content); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("response store: query record: %w", err)
+	}
+	var record Record
+	if err := json.Unmarshal([]byte(content), &record); err != nil {
+		return nil, fmt.Errorf("response store: decode record: %w", err)
+	}
+	if expired(record.CreatedAt, s.cfg.TTL, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// Delete removes the record with the given ID.
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.table()), id)
+	if err != nil {
+		return fmt.Errorf("response store: delete record: %w", err)
+	}
+	if n, errRows := result.RowsAffected(); errRows == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close releases the database connection.
+func (s *PostgresStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *PostgresStore) table() string {
+	if strings.TrimSpace(s.cfg.Schema) == "" {
+		return quoteIdentifier(s.cfg.Table)
+	}
+	return quoteIdentifier(s.cfg.Schema) + "." + quoteIdentifier(s.cfg.Table)
+}
+
+func quoteIdentifier(identifier string) string {
+	return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
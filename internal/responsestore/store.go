@@ -0,0 +1,121 @@
+// Package responsestore persists OpenAI Responses API objects so that requests chained with
+// previous_response_id can be rehydrated for any backend provider, and stored responses can be
+// retrieved or deleted through /v1/responses/{id}.
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// ErrNotFound is returned when a response does not exist or has expired.
+var ErrNotFound = errors.New("response not found")
+
+// Record is a stored response together with the input items of the turn that produced it.
+type Record struct {
+	// ID is the response identifier returned to the client.
+	ID string `json:"id"`
+	// Model is the model requested by the client.
+	Model string `json:"model,omitempty"`
+	// PreviousResponseID links the record to the previous turn of the conversation.
+	PreviousResponseID string `json:"previous_response_id,omitempty"`
+	// Instructions holds the system instructions of the turn. They are not carried over to later turns.
+	Instructions string `json:"instructions,omitempty"`
+	// Input holds the input items sent with this turn only, as a JSON array.
+	Input json.RawMessage `json:"input"`
+	// Response holds the full response object as returned to the client.
+	Response json.RawMessage `json:"response"`
+	// CreatedAt is when the record was stored.
+	CreatedAt time.Time `json:"created_at"`
+	// Owner identifies the client that created the record, as a digest of its API key.
+	// Records are only visible to the same owner.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store is a pluggable backend for response records.
+type Store interface {
+	// Put stores or replaces a record.
+	Put(ctx context.Context, record *Record) error
+	// Get returns the record with the given ID or ErrNotFound.
+	Get(ctx context.Context, id string) (*Record, error)
+	// Delete removes the record with the given ID or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+	// Close releases resources held by the backend.
+	Close() error
+}
+
+const (
+	// BackendMemory keeps records in process memory.
+	BackendMemory = "memory"
+	// BackendFile stores one JSON file per record in a directory.
+	BackendFile = "file"
+	// BackendPostgres stores records in a PostgreSQL table.
+	BackendPostgres = "postgres"
+	// BackendNone disables response storage (default).
+	BackendNone = "none"
+)
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore Store
+)
+
+// Default returns the store used by the Responses API handlers, or nil when storage is disabled.
+func Default() Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
+
+// SetDefault replaces the store used by the Responses API handlers and closes the previous one.
+func SetDefault(store Store) {
+	defaultMu.Lock()
+	previous := defaultStore
+	defaultStore = store
+	defaultMu.Unlock()
+	if previous != nil && previous != store {
+		_ = previous.Close()
+	}
+}
+
+// New builds the backend described by the configuration. It returns nil when storage is disabled.
+func New(ctx context.Context, cfg config.ResponseStoreConfig) (Store, error) {
+	ttl := time.Duration(cfg.TTLHours) * time.Hour
+	switch backend := strings.ToLower(strings.TrimSpace(cfg.Backend)); backend {
+	case BackendMemory:
+		return NewMemoryStore(cfg.MaxEntries, ttl), nil
+	case BackendFile:
+		return NewFileStore(cfg.Dir, ttl)
+	case BackendPostgres:
+		return NewPostgresStore(ctx, PostgresConfig{DSN: cfg.DSN, Schema: cfg.Schema, Table: cfg.Table, TTL: ttl})
+	case "", BackendNone, "disabled", "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("response store: unknown backend %q", backend)
+	}
+}
+
+// GetOwned returns the record with the given ID when it belongs to owner. Records of other
+// owners are reported as ErrNotFound so that their existence is not revealed.
+func GetOwned(ctx context.Context, store Store, id, owner string) (*Record, error) {
+	record, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// expired reports whether a record created at createdAt has outlived ttl.
+func expired(createdAt time.Time, ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && !createdAt.IsZero() && now.Sub(createdAt) > ttl
+}
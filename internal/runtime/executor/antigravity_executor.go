@@ -621,6 +621,7 @@ func (e *AntigravityExecutor) ExecuteStream(ctx context.Context, auth *cliproxya
 			for scanner.Scan() {
 				line := scanner.Bytes()
 				appendAPIResponseChunk(ctx, e.cfg, line)
+				reporter.observeStreamChunk(line)
 
 				// Filter usage metadata for all models
 				// Only retain usage statistics in the terminal chunk
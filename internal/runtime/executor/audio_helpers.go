@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"bytes"
+	"mime/multipart"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// isAudioRequest reports whether the request arrived through an Audio API endpoint.
+func isAudioRequest(opts cliproxyexecutor.Options) bool {
+	return opts.SourceFormat == sdktranslator.FormatOpenAITranscription || opts.SourceFormat == sdktranslator.FormatOpenAISpeech
+}
+
+// buildTranscriptionForm re-encodes a transcription request, whose audio is carried as
+// base64 in JSON, into the multipart form expected by /audio/transcriptions.
+func buildTranscriptionForm(payload []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	root := gjson.ParseBytes(payload)
+
+	if err := writeFormFields(writer, root, "file"); err != nil {
+		return nil, "", err
+	}
+	if file := root.Get("file"); file.IsObject() {
+		if err := writeFilePart(writer, "file", file, "audio.mp3", "audio/mpeg"); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
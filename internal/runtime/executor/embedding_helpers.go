@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// isEmbeddingRequest reports whether the request arrived through an embeddings endpoint.
+func isEmbeddingRequest(opts cliproxyexecutor.Options) bool {
+	return opts.SourceFormat == sdktranslator.FormatOpenAIEmbedding || opts.SourceFormat == sdktranslator.FormatGeminiEmbedding
+}
+
+// postUpstream sends a non-streaming request upstream and returns the response body.
+// The prepare callback sets provider-specific authentication headers and may replace the
+// default JSON content type.
+func postUpstream(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, provider, url string, body []byte, prepare func(*http.Request)) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if prepare != nil {
+		prepare(httpReq)
+	}
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    http.MethodPost,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  provider,
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("%s executor: close response body error: %v", provider, errClose)
+		}
+	}()
+	recordAPIResponseMetadata(ctx, cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		b, _ := io.ReadAll(httpResp.Body)
+		appendAPIResponseChunk(ctx, cfg, b)
+		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(b)}
+	}
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	appendAPIResponseChunk(ctx, cfg, data)
+	return data, nil
+}
+
+// setGeminiEmbeddingModel points every request of a batchEmbedContents body at the upstream model,
+// which Gemini requires to match the model in the URL.
+func setGeminiEmbeddingModel(body []byte, model string) []byte {
+	count := len(gjson.GetBytes(body, "requests").Array())
+	for i := 0; i < count; i++ {
+		body, _ = sjson.SetBytes(body, fmt.Sprintf("requests.%d.model", i), "models/"+model)
+	}
+	return body
+}
+
+// vertexPredictFromGeminiEmbedding converts a batchEmbedContents body into a Vertex AI predict body.
+func vertexPredictFromGeminiEmbedding(body []byte) []byte {
+	out := `{"instances":[]}`
+	var dimensions int64
+	gjson.GetBytes(body, "requests").ForEach(func(_, request gjson.Result) bool {
+		var texts []string
+		request.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			if text := part.Get("text"); text.Exists() {
+				texts = append(texts, text.String())
+			}
+			return true
+		})
+		instance := `{"content":""}`
+		instance, _ = sjson.Set(instance, "content", strings.Join(texts, "\n"))
+		if taskType := request.Get("taskType").String(); taskType != "" {
+			instance, _ = sjson.Set(instance, "task_type", taskType)
+		}
+		if title := request.Get("title").String(); title != "" {
+			instance, _ = sjson.Set(instance, "title", title)
+		}
+		out, _ = sjson.SetRaw(out, "instances.-1", instance)
+		if dimensions == 0 {
+			dimensions = request.Get("outputDimensionality").Int()
+		}
+		return true
+	})
+	if dimensions > 0 {
+		out, _ = sjson.Set(out, "parameters.outputDimensionality", dimensions)
+	}
+	return []byte(out)
+}
+
+// geminiEmbeddingFromVertexPredict converts a Vertex AI predict response into a batchEmbedContents
+// response, reporting the summed token statistics as usage metadata.
+func geminiEmbeddingFromVertexPredict(data []byte) []byte {
+	out := `{"embeddings":[]}`
+	var tokens int64
+	gjson.GetBytes(data, "predictions").ForEach(func(_, prediction gjson.Result) bool {
+		embedding := `{"values":[]}`
+		if values := prediction.Get("embeddings.values"); values.IsArray() {
+			embedding, _ = sjson.SetRaw(embedding, "values", values.Raw)
+		}
+		tokens += prediction.Get("embeddings.statistics.token_count").Int()
+		out, _ = sjson.SetRaw(out, "embeddings.-1", embedding)
+		return true
+	})
+	if tokens > 0 {
+		out, _ = sjson.Set(out, "usageMetadata.promptTokenCount", tokens)
+		out, _ = sjson.Set(out, "usageMetadata.totalTokenCount", tokens)
+	}
+	return []byte(out)
+}
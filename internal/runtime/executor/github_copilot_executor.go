@@ -246,6 +246,7 @@ func (e *GitHubCopilotExecutor) ExecuteStream(ctx context.Context, auth *cliprox
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			appendAPIResponseChunk(ctx, e.cfg, line)
+			reporter.observeStreamChunk(line)
 
 			// Parse SSE data
 			if bytes.HasPrefix(line, dataTag) {
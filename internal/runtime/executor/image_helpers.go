@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// isImageRequest reports whether the request arrived through an Images API endpoint.
+func isImageRequest(opts cliproxyexecutor.Options) bool {
+	return opts.SourceFormat == sdktranslator.FormatOpenAIImage
+}
+
+// buildImageEditForm re-encodes an image edit request, whose source images and mask are
+// carried as base64 in JSON, into the multipart form expected by /images/edits.
+func buildImageEditForm(payload []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	root := gjson.ParseBytes(payload)
+
+	if err := writeFormFields(writer, root, "images", "mask"); err != nil {
+		return nil, "", err
+	}
+	images := root.Get("images").Array()
+	field := "image"
+	if len(images) > 1 {
+		field = "image[]"
+	}
+	for i, image := range images {
+		fallback := fmt.Sprintf("image_%d.%s", i, imageExtension(image.Get("mime_type").String()))
+		if err := writeFilePart(writer, field, image, fallback, "image/png"); err != nil {
+			return nil, "", err
+		}
+	}
+	if mask := root.Get("mask"); mask.IsObject() {
+		if err := writeFilePart(writer, "mask", mask, "mask.png", "image/png"); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// writeFormFields writes the scalar top-level fields of root as form fields, skipping the
+// named keys and any nested values.
+func writeFormFields(writer *multipart.Writer, root gjson.Result, skip ...string) error {
+	var errField error
+	root.ForEach(func(key, value gjson.Result) bool {
+		for _, name := range skip {
+			if key.String() == name {
+				return true
+			}
+		}
+		if value.IsObject() || value.IsArray() {
+			return true
+		}
+		errField = writer.WriteField(key.String(), value.String())
+		return errField == nil
+	})
+	return errField
+}
+
+// writeFilePart decodes a {"data","mime_type","filename"} object into a multipart file part.
+func writeFilePart(writer *multipart.Writer, field string, file gjson.Result, fallbackName, defaultMime string) error {
+	data, err := base64.StdEncoding.DecodeString(file.Get("data").String())
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", field, err)
+	}
+	mimeType := file.Get("mime_type").String()
+	if mimeType == "" {
+		mimeType = defaultMime
+	}
+	filename := file.Get("filename").String()
+	if filename == "" {
+		filename = fallbackName
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, filename))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+// imageExtension returns the file extension for an image MIME type.
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return "jpg"
+	case "image/webp":
+		return "webp"
+	case "image/gif":
+		return "gif"
+	default:
+		return "png"
+	}
+}
+
+// parseOpenAIMediaUsage reads the token usage reported by the OpenAI Images and Audio APIs.
+func parseOpenAIMediaUsage(data []byte) usage.Detail {
+	usageNode := gjson.GetBytes(data, "usage")
+	if !usageNode.Exists() {
+		return usage.Detail{}
+	}
+	return usage.Detail{
+		InputTokens:  usageNode.Get("input_tokens").Int(),
+		OutputTokens: usageNode.Get("output_tokens").Int(),
+		TotalTokens:  usageNode.Get("total_tokens").Int(),
+	}
+}
@@ -845,6 +845,7 @@ func (e *KiroExecutor) executeStreamWithRetry(ctx context.Context, auth *cliprox
 
 			go func(resp *http.Response, thinkingEnabled bool) {
 				defer close(out)
+				defer reporter.ensurePublished(ctx)
 				defer func() {
 					if r := recover(); r != nil {
 						log.Errorf("kiro: panic in stream handler: %v", r)
@@ -2021,6 +2022,7 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 			continue
 		}
 		appendAPIResponseChunk(ctx, e.cfg, payload)
+		reporter.observeStreamChunk(payload)
 
 		var event map[string]interface{}
 		if err := json.Unmarshal(payload, &event); err != nil {
@@ -19,6 +19,11 @@ const (
 	apiAttemptsKey = "API_UPSTREAM_ATTEMPTS"
 	apiRequestKey  = "API_REQUEST"
 	apiResponseKey = "API_RESPONSE"
+	// apiProviderKey, apiModelKey and apiAuthIndexKey describe the upstream serving the
+	// request; they are recorded by structured request logs and the request history.
+	apiProviderKey  = "API_PROVIDER"
+	apiModelKey     = "API_MODEL"
+	apiAuthIndexKey = "API_AUTH_INDEX"
 )
 
 // upstreamRequestLog captures the outbound upstream request details for logging.
@@ -93,6 +98,18 @@ func recordAPIRequest(ctx context.Context, cfg *config.Config, info upstreamRequ
 	updateAggregatedRequest(ginCtx, attempts)
 }
 
+// recordAPIUpstream stores the provider, model and credential index serving the request.
+// Later attempts overwrite earlier ones, so the final upstream is kept.
+func recordAPIUpstream(ctx context.Context, provider, model, authIndex string) {
+	ginCtx := ginContextFrom(ctx)
+	if ginCtx == nil {
+		return
+	}
+	ginCtx.Set(apiProviderKey, provider)
+	ginCtx.Set(apiModelKey, model)
+	ginCtx.Set(apiAuthIndexKey, authIndex)
+}
+
 // recordAPIResponseMetadata captures upstream response status/header information for the latest attempt.
 func recordAPIResponseMetadata(ctx context.Context, cfg *config.Config, status int, headers http.Header) {
 	if cfg == nil || !cfg.RequestLog {
@@ -16,6 +16,7 @@ import (
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
@@ -41,6 +42,18 @@ func (e *OpenAICompatExecutor) PrepareRequest(_ *http.Request, _ *cliproxyauth.A
 }
 
 func (e *OpenAICompatExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	if isEmbeddingRequest(opts) {
+		return e.executeEmbeddings(ctx, auth, req, opts)
+	}
+	if isImageRequest(opts) {
+		return e.executeImages(ctx, auth, req, opts)
+	}
+	if isAudioRequest(opts) {
+		return e.executeAudio(ctx, auth, req, opts)
+	}
+	if protocol := compatProtocol(auth); protocol != "" {
+		return e.executeNative(ctx, auth, req, opts, protocol)
+	}
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
 
@@ -139,7 +152,141 @@ func (e *OpenAICompatExecutor) Execute(ctx context.Context, auth *cliproxyauth.A
 	return resp, nil
 }
 
+// executeEmbeddings performs an embeddings request against the provider's /embeddings endpoint.
+func (e *OpenAICompatExecutor) executeEmbeddings(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	baseURL, apiKey := e.resolveCredentials(auth)
+	if baseURL == "" {
+		err = statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+		return
+	}
+
+	from := opts.SourceFormat
+	to := sdktranslator.FormatOpenAIEmbedding
+	translated := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+	if modelOverride := e.resolveUpstreamModel(req.Model, auth); modelOverride != "" {
+		translated = e.overrideModel(translated, modelOverride)
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/embeddings"
+	body, err := postUpstream(ctx, e.cfg, auth, e.Identifier(), url, translated, func(httpReq *http.Request) {
+		if apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+		var attrs map[string]string
+		if auth != nil {
+			attrs = auth.Attributes
+		}
+		util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	})
+	if err != nil {
+		return resp, err
+	}
+	reporter.publish(ctx, parseOpenAIUsage(body))
+	reporter.ensurePublished(ctx)
+	var param any
+	out := sdktranslator.TranslateNonStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), translated, body, &param)
+	resp = cliproxyexecutor.Response{Payload: []byte(out)}
+	return resp, nil
+}
+
+// executeImages forwards an Images API request to the provider. Requests carrying source
+// images go to /images/edits as multipart form data, all others to /images/generations.
+func (e *OpenAICompatExecutor) executeImages(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	baseURL, apiKey := e.resolveCredentials(auth)
+	if baseURL == "" {
+		err = statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+		return
+	}
+
+	body := bytes.Clone(req.Payload)
+	if modelOverride := e.resolveUpstreamModel(req.Model, auth); modelOverride != "" {
+		body = e.overrideModel(body, modelOverride)
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/images/generations"
+	contentType := "application/json"
+	if gjson.GetBytes(body, "images").IsArray() {
+		url = strings.TrimSuffix(baseURL, "/") + "/images/edits"
+		body, contentType, err = buildImageEditForm(body)
+		if err != nil {
+			return resp, statusErr{code: http.StatusBadRequest, msg: err.Error()}
+		}
+	}
+
+	data, err := e.postMedia(ctx, auth, apiKey, url, contentType, body)
+	if err != nil {
+		return resp, err
+	}
+	reporter.publish(ctx, parseOpenAIMediaUsage(data))
+	reporter.ensurePublished(ctx)
+	resp = cliproxyexecutor.Response{Payload: data}
+	return resp, nil
+}
+
+// executeAudio forwards an Audio API request to the provider. Transcriptions are sent as
+// multipart form data; speech requests are sent as JSON and answered with raw audio bytes.
+func (e *OpenAICompatExecutor) executeAudio(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	baseURL, apiKey := e.resolveCredentials(auth)
+	if baseURL == "" {
+		err = statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+		return
+	}
+
+	body := bytes.Clone(req.Payload)
+	if modelOverride := e.resolveUpstreamModel(req.Model, auth); modelOverride != "" {
+		body = e.overrideModel(body, modelOverride)
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/audio/speech"
+	contentType := "application/json"
+	if opts.SourceFormat == sdktranslator.FormatOpenAITranscription {
+		url = strings.TrimSuffix(baseURL, "/") + "/audio/transcriptions"
+		body, contentType, err = buildTranscriptionForm(body)
+		if err != nil {
+			return resp, statusErr{code: http.StatusBadRequest, msg: err.Error()}
+		}
+	}
+
+	data, err := e.postMedia(ctx, auth, apiKey, url, contentType, body)
+	if err != nil {
+		return resp, err
+	}
+	if opts.SourceFormat == sdktranslator.FormatOpenAITranscription {
+		reporter.publish(ctx, parseOpenAIMediaUsage(data))
+	}
+	reporter.ensurePublished(ctx)
+	resp = cliproxyexecutor.Response{Payload: data}
+	return resp, nil
+}
+
+// postMedia sends an Images or Audio API request with the provider credentials.
+func (e *OpenAICompatExecutor) postMedia(ctx context.Context, auth *cliproxyauth.Auth, apiKey, url, contentType string, body []byte) ([]byte, error) {
+	return postUpstream(ctx, e.cfg, auth, e.Identifier(), url, body, func(httpReq *http.Request) {
+		httpReq.Header.Set("Content-Type", contentType)
+		if apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+		var attrs map[string]string
+		if auth != nil {
+			attrs = auth.Attributes
+		}
+		util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	})
+}
+
 func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
+	if protocol := compatProtocol(auth); protocol != "" {
+		return e.executeNativeStream(ctx, auth, req, opts, protocol)
+	}
 	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
 	defer reporter.trackFailure(ctx, &err)
 
@@ -235,6 +382,7 @@ func (e *OpenAICompatExecutor) ExecuteStream(ctx context.Context, auth *cliproxy
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			appendAPIResponseChunk(ctx, e.cfg, line)
+			reporter.observeStreamChunk(line)
 			if detail, ok := parseOpenAIStreamUsage(line); ok {
 				reporter.publish(ctx, detail)
 			}
@@ -270,12 +418,7 @@ func (e *OpenAICompatExecutor) CountTokens(ctx context.Context, auth *cliproxyau
 		modelForCounting = modelOverride
 	}
 
-	enc, err := tokenizerForModel(modelForCounting)
-	if err != nil {
-		return cliproxyexecutor.Response{}, fmt.Errorf("openai compat executor: tokenizer init failed: %w", err)
-	}
-
-	count, err := countOpenAIChatTokens(enc, translated)
+	count, err := countPromptTokens(ctx, e.cfg, TokenCountRequest{Model: modelForCounting, Payload: translated, Auth: auth})
 	if err != nil {
 		return cliproxyexecutor.Response{}, fmt.Errorf("openai compat executor: token counting failed: %w", err)
 	}
@@ -0,0 +1,271 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/sjson"
+)
+
+// anthropicVersion is sent to Anthropic-compatible providers.
+const anthropicVersion = "2023-06-01"
+
+// compatProtocol returns the native protocol configured for a compatibility provider
+// ("claude" or "gemini"), or "" when requests go through /chat/completions.
+func compatProtocol(auth *cliproxyauth.Auth) string {
+	if auth == nil || auth.Attributes == nil {
+		return ""
+	}
+	switch protocol := strings.ToLower(strings.TrimSpace(auth.Attributes["protocol"])); protocol {
+	case "claude", "gemini":
+		return protocol
+	default:
+		return ""
+	}
+}
+
+// newNativeRequest builds the upstream request for a provider speaking the Claude or Gemini
+// protocol. The payload is translated only when the source format differs from the protocol.
+func (e *OpenAICompatExecutor) newNativeRequest(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, protocol string, stream bool) (*http.Request, []byte, error) {
+	baseURL, apiKey := e.resolveCredentials(auth)
+	if baseURL == "" {
+		return nil, nil, statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+	}
+	model := req.Model
+	if override := e.resolveUpstreamModel(req.Model, auth); override != "" {
+		model = override
+	}
+
+	from := opts.SourceFormat
+	to := sdktranslator.FromString(protocol)
+	originalPayload := bytes.Clone(req.Payload)
+	if len(opts.OriginalRequest) > 0 {
+		originalPayload = bytes.Clone(opts.OriginalRequest)
+	}
+	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, stream)
+	body := sdktranslator.TranslateRequest(from, to, model, bytes.Clone(req.Payload), stream)
+	body = applyPayloadConfigWithRoot(e.cfg, req.Model, to.String(), "", body, originalTranslated)
+
+	base := strings.TrimSuffix(baseURL, "/")
+	var url string
+	if protocol == "claude" {
+		body, _ = sjson.SetBytes(body, "model", model)
+		if !strings.HasSuffix(base, "/v1") {
+			base += "/v1"
+		}
+		url = base + "/messages"
+	} else {
+		body, _ = sjson.DeleteBytes(body, "model")
+		body, _ = sjson.DeleteBytes(body, "session_id")
+		if !strings.HasSuffix(base, "/v1beta") && !strings.HasSuffix(base, "/v1") {
+			base += "/" + glAPIVersion
+		}
+		action := "generateContent"
+		if stream {
+			action = "streamGenerateContent?alt=sse"
+		}
+		url = fmt.Sprintf("%s/models/%s:%s", base, model, action)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		if protocol == "claude" {
+			// Anthropic-compatible providers differ in which of the two headers they read.
+			httpReq.Header.Set("x-api-key", apiKey)
+			httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		} else {
+			httpReq.Header.Set("x-goog-api-key", apiKey)
+		}
+	}
+	if protocol == "claude" {
+		httpReq.Header.Set("anthropic-version", anthropicVersion)
+	}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("Cache-Control", "no-cache")
+	}
+	httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    http.MethodPost,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  e.Identifier(),
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+	return httpReq, body, nil
+}
+
+// doNativeRequest sends the request and converts non-2xx responses into status errors.
+func (e *OpenAICompatExecutor) doNativeRequest(ctx context.Context, auth *cliproxyauth.Auth, httpReq *http.Request) (*http.Response, error) {
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return nil, err
+	}
+	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		b, _ := io.ReadAll(httpResp.Body)
+		appendAPIResponseChunk(ctx, e.cfg, b)
+		log.Debugf("request error, error status: %d, error body: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("openai compat executor: close response body error: %v", errClose)
+		}
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(b)}
+	}
+	return httpResp, nil
+}
+
+// executeNative performs a non-streaming request against a Claude or Gemini protocol provider.
+func (e *OpenAICompatExecutor) executeNative(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, protocol string) (resp cliproxyexecutor.Response, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	// Like the Claude executor, translated Claude responses are requested as a stream, which
+	// is the input the Claude response translators expect.
+	stream := protocol == "claude" && opts.SourceFormat != sdktranslator.FromString(protocol)
+	httpReq, body, err := e.newNativeRequest(ctx, auth, req, opts, protocol, stream)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := e.doNativeRequest(ctx, auth, httpReq)
+	if err != nil {
+		return resp, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("openai compat executor: close response body error: %v", errClose)
+		}
+	}()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return resp, err
+	}
+	appendAPIResponseChunk(ctx, e.cfg, data)
+	if stream {
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if detail, ok := parseClaudeStreamUsage(line); ok {
+				reporter.publish(ctx, detail)
+			}
+		}
+	} else if protocol == "claude" {
+		reporter.publish(ctx, parseClaudeUsage(data))
+	} else {
+		reporter.publish(ctx, parseGeminiUsage(data))
+	}
+	reporter.ensurePublished(ctx)
+
+	var param any
+	out := sdktranslator.TranslateNonStream(ctx, sdktranslator.FromString(protocol), opts.SourceFormat, req.Model, bytes.Clone(opts.OriginalRequest), body, data, &param)
+	resp = cliproxyexecutor.Response{Payload: []byte(out)}
+	return resp, nil
+}
+
+// executeNativeStream performs a streaming request against a Claude or Gemini protocol
+// provider. Claude streams in the Claude source format are forwarded line by line unchanged.
+func (e *OpenAICompatExecutor) executeNativeStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, protocol string) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
+	reporter := newUsageReporter(ctx, e.Identifier(), req.Model, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	httpReq, body, err := e.newNativeRequest(ctx, auth, req, opts, protocol, true)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := e.doNativeRequest(ctx, auth, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	from := opts.SourceFormat
+	to := sdktranslator.FromString(protocol)
+	out := make(chan cliproxyexecutor.StreamChunk)
+	stream = out
+	go func() {
+		defer close(out)
+		defer func() {
+			if errClose := httpResp.Body.Close(); errClose != nil {
+				log.Errorf("openai compat executor: close response body error: %v", errClose)
+			}
+		}()
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(nil, streamScannerBuffer)
+		var param any
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			appendAPIResponseChunk(ctx, e.cfg, line)
+			reporter.observeStreamChunk(line)
+			if protocol == "claude" {
+				if detail, ok := parseClaudeStreamUsage(line); ok {
+					reporter.publish(ctx, detail)
+				}
+				if from == to {
+					// Forward the line as-is to preserve SSE format
+					cloned := make([]byte, len(line)+1)
+					copy(cloned, line)
+					cloned[len(line)] = '\n'
+					out <- cliproxyexecutor.StreamChunk{Payload: cloned}
+					continue
+				}
+				chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, bytes.Clone(line), &param)
+				for i := range chunks {
+					out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
+				}
+				continue
+			}
+			payload := jsonPayload(FilterSSEUsageMetadata(line))
+			if len(payload) == 0 {
+				continue
+			}
+			if detail, ok := parseGeminiStreamUsage(payload); ok {
+				reporter.publish(ctx, detail)
+			}
+			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, bytes.Clone(payload), &param)
+			for i := range chunks {
+				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
+			}
+		}
+		if protocol == "gemini" {
+			chunks := sdktranslator.TranslateStream(ctx, to, from, req.Model, bytes.Clone(opts.OriginalRequest), body, []byte("[DONE]"), &param)
+			for i := range chunks {
+				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
+			}
+		}
+		if errScan := scanner.Err(); errScan != nil {
+			recordAPIResponseError(ctx, e.cfg, errScan)
+			reporter.publishFailure(ctx)
+			out <- cliproxyexecutor.StreamChunk{Err: errScan}
+		}
+		reporter.ensurePublished(ctx)
+	}()
+	return stream, nil
+}
@@ -0,0 +1,353 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// nativeUpstream records the last request it received and answers with a fixed body.
+type nativeUpstream struct {
+	mu     sync.Mutex
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func (u *nativeUpstream) serve(t *testing.T, contentType, response string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.path = r.URL.RequestURI()
+		u.header = r.Header.Clone()
+		u.body = body
+		u.mu.Unlock()
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func nativeAuth(protocol, baseURL string) *cliproxyauth.Auth {
+	return &cliproxyauth.Auth{
+		ID:       "compat-1",
+		Provider: "compat",
+		Attributes: map[string]string{
+			"base_url": baseURL,
+			"api_key":  "sk-upstream",
+			"protocol": protocol,
+		},
+	}
+}
+
+func collectStream(t *testing.T, stream <-chan cliproxyexecutor.StreamChunk) string {
+	t.Helper()
+	var out strings.Builder
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		out.Write(chunk.Payload)
+	}
+	return out.String()
+}
+
+func TestOpenAICompatNativeRequestURLAndHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		baseURL  string
+		stream   bool
+		wantURL  string
+		want     map[string]string
+		absent   []string
+	}{
+		{
+			name:     "claude appends v1",
+			protocol: "claude",
+			baseURL:  "https://upstream.example/",
+			wantURL:  "https://upstream.example/v1/messages",
+			want:     map[string]string{"x-api-key": "sk-upstream", "Authorization": "Bearer sk-upstream", "anthropic-version": anthropicVersion},
+			absent:   []string{"x-goog-api-key", "Accept"},
+		},
+		{
+			name:     "claude keeps v1",
+			protocol: "claude",
+			baseURL:  "https://upstream.example/v1",
+			stream:   true,
+			wantURL:  "https://upstream.example/v1/messages",
+			want:     map[string]string{"Accept": "text/event-stream", "Cache-Control": "no-cache"},
+		},
+		{
+			name:     "gemini appends v1beta",
+			protocol: "gemini",
+			baseURL:  "https://upstream.example",
+			wantURL:  "https://upstream.example/v1beta/models/native-model:generateContent",
+			want:     map[string]string{"x-goog-api-key": "sk-upstream"},
+			absent:   []string{"Authorization", "x-api-key", "anthropic-version"},
+		},
+		{
+			name:     "gemini keeps v1beta",
+			protocol: "gemini",
+			baseURL:  "https://upstream.example/v1beta/",
+			stream:   true,
+			wantURL:  "https://upstream.example/v1beta/models/native-model:streamGenerateContent?alt=sse",
+			want:     map[string]string{"Accept": "text/event-stream"},
+		},
+		{
+			name:     "gemini keeps v1",
+			protocol: "gemini",
+			baseURL:  "https://upstream.example/v1",
+			wantURL:  "https://upstream.example/v1/models/native-model:generateContent",
+		},
+	}
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := cliproxyexecutor.Request{Model: "native-model", Payload: []byte(`{"model":"native-model","messages":[{"role":"user","content":"hi"}]}`)}
+			opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Stream: tt.stream}
+			httpReq, body, err := executor.newNativeRequest(context.Background(), nativeAuth(tt.protocol, tt.baseURL), req, opts, tt.protocol, tt.stream)
+			if err != nil {
+				t.Fatalf("newNativeRequest: %v", err)
+			}
+			if got := httpReq.URL.String(); got != tt.wantURL {
+				t.Fatalf("url = %q, want %q", got, tt.wantURL)
+			}
+			for key, value := range tt.want {
+				if got := httpReq.Header.Get(key); got != value {
+					t.Fatalf("header %s = %q, want %q", key, got, value)
+				}
+			}
+			for _, key := range tt.absent {
+				if got := httpReq.Header.Get(key); got != "" {
+					t.Fatalf("header %s = %q, want none", key, got)
+				}
+			}
+			hasModel := gjson.GetBytes(body, "model").Exists()
+			if tt.protocol == "claude" && !hasModel {
+				t.Fatalf("claude body must carry the model: %s", body)
+			}
+			if tt.protocol == "gemini" && hasModel {
+				t.Fatalf("gemini body must not carry the model: %s", body)
+			}
+		})
+	}
+}
+
+const claudeThinkingRequest = `{"model":"claude-native","max_tokens":1024,"system":[{"type":"text","text":"Be terse.","cache_control":{"type":"ephemeral"}}],"messages":[{"role":"user","content":[{"type":"text","text":"first","cache_control":{"type":"ephemeral"}}]},{"role":"assistant","content":[{"type":"thinking","thinking":"Let me think.","signature":"EqQBCkYIBRgCKkA+sig/=="},{"type":"text","text":"answer"}]},{"role":"user","content":"second"}],"thinking":{"type":"enabled","budget_tokens":512}}`
+
+func TestOpenAICompatNativeClaudePassthrough(t *testing.T) {
+	const response = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-native","content":[{"type":"thinking","thinking":"Hmm.","signature":"EqQBsig=="},{"type":"text","text":"done"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3,"cache_read_input_tokens":8}}`
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "application/json", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	req := cliproxyexecutor.Request{Model: "claude-native", Payload: []byte(claudeThinkingRequest)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, OriginalRequest: []byte(claudeThinkingRequest)}
+	resp, err := executor.Execute(context.Background(), nativeAuth("claude", server.URL), req, opts)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if upstream.path != "/v1/messages" {
+		t.Fatalf("path = %q, want /v1/messages", upstream.path)
+	}
+	if string(upstream.body) != claudeThinkingRequest {
+		t.Fatalf("upstream body changed:\n got %s\nwant %s", upstream.body, claudeThinkingRequest)
+	}
+	if string(resp.Payload) != response {
+		t.Fatalf("response changed:\n got %s\nwant %s", resp.Payload, response)
+	}
+}
+
+func TestOpenAICompatNativeClaudeStreamPassthrough(t *testing.T) {
+	const response = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-native","usage":{"input_tokens":12,"output_tokens":0}}}` + "\n\n" +
+		"event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"EqQBsig=="}}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}` + "\n\n" +
+		"event: message_stop\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "text/event-stream", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	req := cliproxyexecutor.Request{Model: "claude-native", Payload: []byte(claudeThinkingRequest)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, OriginalRequest: []byte(claudeThinkingRequest), Stream: true}
+	stream, err := executor.ExecuteStream(context.Background(), nativeAuth("claude", server.URL+"/v1"), req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	if got := collectStream(t, stream); got != response {
+		t.Fatalf("stream changed:\n got %q\nwant %q", got, response)
+	}
+	if string(upstream.body) != claudeThinkingRequest {
+		t.Fatalf("upstream body changed:\n got %s\nwant %s", upstream.body, claudeThinkingRequest)
+	}
+	if upstream.header.Get("Accept") != "text/event-stream" {
+		t.Fatalf("Accept = %q, want text/event-stream", upstream.header.Get("Accept"))
+	}
+}
+
+func TestOpenAICompatNativeClaudeFromOpenAI(t *testing.T) {
+	const response = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-native","usage":{"input_tokens":5,"output_tokens":0}}}` + "\n\n" +
+		"event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hello there"}}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}` + "\n\n" +
+		"event: message_stop\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "text/event-stream", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	payload := []byte(`{"model":"claude-native","messages":[{"role":"system","content":"Be terse."},{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "claude-native", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload}
+	resp, err := executor.Execute(context.Background(), nativeAuth("claude", server.URL), req, opts)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := gjson.GetBytes(upstream.body, "messages.0.role").String(); got != "user" {
+		t.Fatalf("upstream messages[0].role = %q, want user: %s", got, upstream.body)
+	}
+	if got := gjson.GetBytes(upstream.body, "model").String(); got != "claude-native" {
+		t.Fatalf("upstream model = %q, want claude-native", got)
+	}
+	if !gjson.GetBytes(upstream.body, "stream").Bool() || upstream.header.Get("Accept") != "text/event-stream" {
+		t.Fatalf("translated claude requests must stream upstream: %s", upstream.body)
+	}
+	if got := gjson.GetBytes(resp.Payload, "object").String(); got != "chat.completion" {
+		t.Fatalf("object = %q, want chat.completion: %s", got, resp.Payload)
+	}
+	if got := gjson.GetBytes(resp.Payload, "choices.0.message.content").String(); got != "hello there" {
+		t.Fatalf("content = %q, want hello there: %s", got, resp.Payload)
+	}
+}
+
+func TestOpenAICompatNativeGeminiSameFormat(t *testing.T) {
+	const response = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1,"totalTokenCount":5}}`
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "application/json", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	payload := []byte(`{"model":"gemini-native","contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-native", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload}
+	resp, err := executor.Execute(context.Background(), nativeAuth("gemini", server.URL), req, opts)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if upstream.path != "/v1beta/models/gemini-native:generateContent" {
+		t.Fatalf("path = %q", upstream.path)
+	}
+	if upstream.header.Get("x-goog-api-key") != "sk-upstream" {
+		t.Fatalf("x-goog-api-key = %q", upstream.header.Get("x-goog-api-key"))
+	}
+	if gjson.GetBytes(upstream.body, "model").Exists() {
+		t.Fatalf("upstream body must not carry the model: %s", upstream.body)
+	}
+	if got := gjson.GetBytes(resp.Payload, "candidates.0.content.parts.0.text").String(); got != "hello" {
+		t.Fatalf("text = %q, want hello: %s", got, resp.Payload)
+	}
+}
+
+func TestOpenAICompatNativeGeminiStreamFlushesDone(t *testing.T) {
+	const response = `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"hel"}]}}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1,"totalTokenCount":5}}` + "\n\n" +
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6}}` + "\n\n"
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "text/event-stream", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	payload := []byte(`{"model":"gemini-native","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-native", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, OriginalRequest: payload, Stream: true}
+	stream, err := executor.ExecuteStream(context.Background(), nativeAuth("gemini", server.URL+"/v1beta"), req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	got := collectStream(t, stream)
+	if upstream.path != "/v1beta/models/gemini-native:streamGenerateContent?alt=sse" {
+		t.Fatalf("path = %q", upstream.path)
+	}
+	if !gjson.GetBytes(upstream.body, "contents").IsArray() {
+		t.Fatalf("upstream body was not translated to Gemini: %s", upstream.body)
+	}
+	if !strings.Contains(got, `"text":"hel"`) || !strings.Contains(got, `"text":"lo"`) {
+		t.Fatalf("stream lost text deltas: %q", got)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(got), `data: {"type":"message_stop"}`) {
+		t.Fatalf("stream must end with the message_stop flushed on [DONE]: %q", got)
+	}
+}
+
+func TestOpenAICompatNativeClaudeStreamFromOpenAI(t *testing.T) {
+	const response = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-native","usage":{"input_tokens":5,"output_tokens":0}}}` + "\n\n" +
+		"event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"streamed"}}` + "\n\n" +
+		"event: message_stop\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "text/event-stream", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	payload := []byte(`{"model":"claude-native","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "claude-native", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload, Stream: true}
+	stream, err := executor.ExecuteStream(context.Background(), nativeAuth("claude", server.URL), req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	got := collectStream(t, stream)
+	if strings.Contains(got, "event: ") {
+		t.Fatalf("claude events leaked into the OpenAI stream: %q", got)
+	}
+	if !strings.Contains(got, `"content":"streamed"`) {
+		t.Fatalf("stream lost the text delta: %q", got)
+	}
+}
+
+func TestOpenAICompatNativeGeminiFromOpenAI(t *testing.T) {
+	const response = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1,"totalTokenCount":5}}`
+	upstream := &nativeUpstream{}
+	server := upstream.serve(t, "application/json", response)
+	executor := NewOpenAICompatExecutor("compat", &config.Config{})
+
+	payload := []byte(`{"model":"gemini-native","messages":[{"role":"user","content":"hi"}]}`)
+	req := cliproxyexecutor.Request{Model: "gemini-native", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload}
+	resp, err := executor.Execute(context.Background(), nativeAuth("gemini", server.URL+"/v1"), req, opts)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if upstream.path != "/v1/models/gemini-native:generateContent" {
+		t.Fatalf("path = %q", upstream.path)
+	}
+	if got := gjson.GetBytes(upstream.body, "contents.0.parts.0.text").String(); got != "hi" {
+		t.Fatalf("upstream body was not translated to Gemini: %s", upstream.body)
+	}
+	if got := gjson.GetBytes(resp.Payload, "choices.0.message.content").String(); got != "hello" {
+		t.Fatalf("content = %q, want hello: %s", got, resp.Payload)
+	}
+}
@@ -0,0 +1,318 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// tokenCountCacheTTL is how long an upstream token count stays valid.
+	tokenCountCacheTTL = 10 * time.Minute
+
+	// tokenCountCacheSize bounds the number of cached token counts.
+	tokenCountCacheSize = 4096
+)
+
+// TokenCountRequest describes a prompt whose tokens should be counted.
+type TokenCountRequest struct {
+	// Model is the upstream model identifier.
+	Model string
+	// Payload is an OpenAI chat completions request body.
+	Payload []byte
+	// Auth is the credential selected for the request. It may be nil.
+	Auth *cliproxyauth.Auth
+}
+
+// TokenCounter counts prompt tokens for the models it understands. Counters only call
+// upstream with req.Auth, the credential selected for the request, so counting never spends
+// another credential's quota. CountTokens reports ok=false when it does not handle the model
+// or the credential, in which case the next registered counter is consulted.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, cfg *config.Config, req TokenCountRequest) (count int64, ok bool, err error)
+}
+
+type namedTokenCounter struct {
+	name    string
+	counter TokenCounter
+}
+
+var (
+	tokenCountersMu sync.RWMutex
+	tokenCounters   []namedTokenCounter
+)
+
+func init() {
+	RegisterTokenCounter("claude", claudeTokenCounter{})
+	RegisterTokenCounter("gemini", geminiTokenCounter{})
+}
+
+// RegisterTokenCounter adds a counter that is tried, in registration order, before the
+// local tiktoken approximation. Registering an existing name replaces that counter.
+func RegisterTokenCounter(name string, counter TokenCounter) {
+	if counter == nil {
+		return
+	}
+	tokenCountersMu.Lock()
+	defer tokenCountersMu.Unlock()
+	for i := range tokenCounters {
+		if tokenCounters[i].name == name {
+			tokenCounters[i].counter = counter
+			return
+		}
+	}
+	tokenCounters = append(tokenCounters, namedTokenCounter{name: name, counter: counter})
+}
+
+// countPromptTokens returns the prompt token count of an OpenAI chat completions payload.
+// Registered counters are asked first and their results are cached by content hash; when
+// none of them can count the model the local tokenizer is used.
+func countPromptTokens(ctx context.Context, cfg *config.Config, req TokenCountRequest) (int64, error) {
+	key := tokenCountKey(req.Model, req.Payload)
+	if count, ok := defaultTokenCountCache.get(key); ok {
+		return count, nil
+	}
+
+	tokenCountersMu.RLock()
+	counters := append([]namedTokenCounter(nil), tokenCounters...)
+	tokenCountersMu.RUnlock()
+	for _, entry := range counters {
+		count, ok, err := entry.counter.CountTokens(ctx, cfg, req)
+		if err != nil {
+			log.Debugf("token counter %s failed for model %s, falling back: %v", entry.name, req.Model, err)
+			continue
+		}
+		if ok {
+			defaultTokenCountCache.put(key, count)
+			return count, nil
+		}
+	}
+
+	enc, err := getTokenizer(req.Model)
+	if err != nil {
+		return 0, fmt.Errorf("tokenizer init failed: %w", err)
+	}
+	return countOpenAIChatTokens(enc, req.Payload)
+}
+
+// tokenCountKey hashes the model and payload into a cache key.
+func tokenCountKey(model string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type tokenCountEntry struct {
+	count   int64
+	expires time.Time
+}
+
+// tokenCountCache is a small TTL cache of upstream token counts.
+type tokenCountCache struct {
+	mu      sync.Mutex
+	entries map[string]tokenCountEntry
+}
+
+var defaultTokenCountCache = &tokenCountCache{entries: make(map[string]tokenCountEntry)}
+
+func (c *tokenCountCache) get(key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return 0, false
+	}
+	return entry.count, true
+}
+
+func (c *tokenCountCache) put(key string, count int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= tokenCountCacheSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: drop arbitrary entries to make room.
+		for k := range c.entries {
+			if len(c.entries) < tokenCountCacheSize {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = tokenCountEntry{count: count, expires: now.Add(tokenCountCacheTTL)}
+}
+
+// tokenCountCredential is the endpoint and key used for an upstream count request.
+type tokenCountCredential struct {
+	baseURL string
+	apiKey  string
+	auth    *cliproxyauth.Auth
+}
+
+// compatTokenCountCredential returns the credential of a compatibility provider that speaks
+// the given native protocol.
+func compatTokenCountCredential(auth *cliproxyauth.Auth, protocol string) (tokenCountCredential, bool) {
+	if compatProtocol(auth) != protocol {
+		return tokenCountCredential{}, false
+	}
+	baseURL := strings.TrimSpace(auth.Attributes["base_url"])
+	apiKey := strings.TrimSpace(auth.Attributes["api_key"])
+	if baseURL == "" || apiKey == "" {
+		return tokenCountCredential{}, false
+	}
+	return tokenCountCredential{baseURL: baseURL, apiKey: apiKey, auth: auth}, true
+}
+
+// claudeTokenCounter counts through the Anthropic count_tokens endpoint.
+type claudeTokenCounter struct{}
+
+func (claudeTokenCounter) CountTokens(ctx context.Context, cfg *config.Config, req TokenCountRequest) (int64, bool, error) {
+	cred, ok := compatTokenCountCredential(req.Auth, "claude")
+	if !ok {
+		return 0, false, nil
+	}
+
+	translated := sdktranslator.TranslateRequest(sdktranslator.FormatOpenAI, sdktranslator.FormatClaude, req.Model, bytes.Clone(req.Payload), false)
+	// count_tokens rejects generation parameters, so only the prompt fields are sent.
+	body := []byte(`{}`)
+	body, _ = sjson.SetBytes(body, "model", req.Model)
+	for _, field := range []string{"system", "messages", "tools", "tool_choice", "thinking"} {
+		if value := gjson.GetBytes(translated, field); value.Exists() {
+			body, _ = sjson.SetRawBytes(body, field, []byte(value.Raw))
+		}
+	}
+
+	base := strings.TrimSuffix(cred.baseURL, "/")
+	if !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	headers := map[string]string{
+		"x-api-key":         cred.apiKey,
+		"Authorization":     "Bearer " + cred.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	data, err := postTokenCount(ctx, cfg, cred, "claude", base+"/messages/count_tokens", headers, body)
+	if err != nil {
+		return 0, false, err
+	}
+	count := gjson.GetBytes(data, "input_tokens")
+	if !count.Exists() {
+		return 0, false, fmt.Errorf("claude count_tokens response has no input_tokens")
+	}
+	return count.Int(), true, nil
+}
+
+// geminiTokenCounter counts through the Gemini countTokens endpoint.
+type geminiTokenCounter struct{}
+
+func (geminiTokenCounter) CountTokens(ctx context.Context, cfg *config.Config, req TokenCountRequest) (int64, bool, error) {
+	cred, ok := compatTokenCountCredential(req.Auth, "gemini")
+	if !ok {
+		return 0, false, nil
+	}
+
+	translated := sdktranslator.TranslateRequest(sdktranslator.FormatOpenAI, sdktranslator.FormatGemini, req.Model, bytes.Clone(req.Payload), false)
+	// Wrapping the prompt in generateContentRequest lets system instructions and tools count too.
+	request := []byte(`{}`)
+	request, _ = sjson.SetBytes(request, "model", "models/"+req.Model)
+	// The translator writes snake_case names, which the API accepts alongside camelCase.
+	for _, field := range []string{"contents", "systemInstruction", "system_instruction", "tools", "toolConfig", "tool_config"} {
+		if value := gjson.GetBytes(translated, field); value.Exists() {
+			request, _ = sjson.SetRawBytes(request, field, []byte(value.Raw))
+		}
+	}
+	body, _ := sjson.SetRawBytes([]byte(`{}`), "generateContentRequest", request)
+
+	base := strings.TrimSuffix(cred.baseURL, "/")
+	if !strings.HasSuffix(base, "/v1beta") && !strings.HasSuffix(base, "/v1") {
+		base += "/" + glAPIVersion
+	}
+	url := fmt.Sprintf("%s/models/%s:countTokens", base, req.Model)
+	data, err := postTokenCount(ctx, cfg, cred, "gemini", url, map[string]string{"x-goog-api-key": cred.apiKey}, body)
+	if err != nil {
+		return 0, false, err
+	}
+	count := gjson.GetBytes(data, "totalTokens")
+	if !count.Exists() {
+		return 0, false, fmt.Errorf("gemini countTokens response has no totalTokens")
+	}
+	return count.Int(), true, nil
+}
+
+// postTokenCount sends an upstream count request and returns the response body.
+func postTokenCount(ctx context.Context, cfg *config.Config, cred tokenCountCredential, provider, url string, headers map[string]string, body []byte) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, cred.auth.Attributes)
+	var authID, authLabel, authType, authValue string
+	if cred.auth.ID != "" {
+		authID = cred.auth.ID
+		authLabel = cred.auth.Label
+		authType, authValue = cred.auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    http.MethodPost,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  provider,
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := newProxyAwareHTTPClient(ctx, cfg, cred.auth, 30*time.Second)
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("token counter: close response body error: %v", errClose)
+		}
+	}()
+	recordAPIResponseMetadata(ctx, cfg, resp.StatusCode, resp.Header.Clone())
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, cfg, err)
+		return nil, err
+	}
+	appendAPIResponseChunk(ctx, cfg, data)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, statusErr{code: resp.StatusCode, msg: string(data)}
+	}
+	return data, nil
+}
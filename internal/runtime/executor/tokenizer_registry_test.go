@@ -0,0 +1,278 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+const tokenCountPayload = `{"model":"m","max_tokens":64,"messages":[{"role":"system","content":"Be terse."},{"role":"user","content":"count me"}]}`
+
+// useTokenCounters replaces the registered counters and empties the cache for one test.
+func useTokenCounters(t *testing.T, counters ...namedTokenCounter) {
+	t.Helper()
+	tokenCountersMu.Lock()
+	saved := tokenCounters
+	tokenCounters = counters
+	tokenCountersMu.Unlock()
+	savedCache := defaultTokenCountCache
+	defaultTokenCountCache = &tokenCountCache{entries: make(map[string]tokenCountEntry)}
+	t.Cleanup(func() {
+		tokenCountersMu.Lock()
+		tokenCounters = saved
+		tokenCountersMu.Unlock()
+		defaultTokenCountCache = savedCache
+	})
+}
+
+type stubTokenCounter struct {
+	count int64
+	ok    bool
+	err   error
+	calls *int
+}
+
+func (s stubTokenCounter) CountTokens(context.Context, *config.Config, TokenCountRequest) (int64, bool, error) {
+	*s.calls++
+	return s.count, s.ok, s.err
+}
+
+func TestCountPromptTokensRegistryOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		counters  []stubTokenCounter
+		want      int64
+		wantCalls []int
+		cached    bool
+	}{
+		{
+			name:      "first counter wins",
+			counters:  []stubTokenCounter{{count: 11, ok: true}, {count: 22, ok: true}},
+			want:      11,
+			wantCalls: []int{1, 0},
+			cached:    true,
+		},
+		{
+			name:      "declined counter is skipped",
+			counters:  []stubTokenCounter{{ok: false}, {count: 22, ok: true}},
+			want:      22,
+			wantCalls: []int{1, 1},
+			cached:    true,
+		},
+		{
+			name:      "failed counter falls through",
+			counters:  []stubTokenCounter{{err: errors.New("upstream down")}, {count: 33, ok: true}},
+			want:      33,
+			wantCalls: []int{1, 1},
+			cached:    true,
+		},
+		{
+			name:      "local tokenizer when no counter answers",
+			counters:  []stubTokenCounter{{ok: false}, {err: errors.New("upstream down")}},
+			wantCalls: []int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]int, len(tt.counters))
+			var named []namedTokenCounter
+			for i := range tt.counters {
+				counter := tt.counters[i]
+				counter.calls = &calls[i]
+				named = append(named, namedTokenCounter{name: fmt.Sprintf("stub-%d", i), counter: counter})
+			}
+			useTokenCounters(t, named...)
+
+			req := TokenCountRequest{Model: "gpt-4o", Payload: []byte(tokenCountPayload)}
+			got, err := countPromptTokens(context.Background(), nil, req)
+			if err != nil {
+				t.Fatalf("countPromptTokens: %v", err)
+			}
+			if tt.want != 0 && got != tt.want {
+				t.Fatalf("count = %d, want %d", got, tt.want)
+			}
+			if tt.want == 0 && got <= 0 {
+				t.Fatalf("local count = %d, want > 0", got)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Fatalf("counter %d called %d times, want %d", i, calls[i], tt.wantCalls[i])
+				}
+			}
+
+			// A cached upstream count is served without asking the counters again; local
+			// counts are not cached.
+			if _, err = countPromptTokens(context.Background(), nil, req); err != nil {
+				t.Fatalf("second countPromptTokens: %v", err)
+			}
+			wantAgain := tt.wantCalls[0]
+			if !tt.cached {
+				wantAgain++
+			}
+			if calls[0] != wantAgain {
+				t.Fatalf("counter 0 called %d times after repeat, want %d", calls[0], wantAgain)
+			}
+		})
+	}
+}
+
+func TestTokenCountCacheExpiresAndEvicts(t *testing.T) {
+	cache := &tokenCountCache{entries: make(map[string]tokenCountEntry)}
+	cache.put("fresh", 5)
+	if count, ok := cache.get("fresh"); !ok || count != 5 {
+		t.Fatalf("get fresh = %d, %v; want 5, true", count, ok)
+	}
+	if entry := cache.entries["fresh"]; time.Until(entry.expires) > tokenCountCacheTTL {
+		t.Fatalf("entry expires in %v, want at most %v", time.Until(entry.expires), tokenCountCacheTTL)
+	}
+
+	cache.entries["stale"] = tokenCountEntry{count: 9, expires: time.Now().Add(-time.Second)}
+	if _, ok := cache.get("stale"); ok {
+		t.Fatal("expired entry was returned")
+	}
+	if _, ok := cache.entries["stale"]; ok {
+		t.Fatal("expired entry was not removed on read")
+	}
+
+	for i := 0; i < tokenCountCacheSize+10; i++ {
+		cache.put(fmt.Sprintf("key-%d", i), int64(i))
+	}
+	if len(cache.entries) > tokenCountCacheSize {
+		t.Fatalf("cache holds %d entries, want at most %d", len(cache.entries), tokenCountCacheSize)
+	}
+	last := fmt.Sprintf("key-%d", tokenCountCacheSize+9)
+	if _, ok := cache.get(last); !ok {
+		t.Fatal("newest entry was evicted")
+	}
+}
+
+func TestUpstreamTokenCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		counter  TokenCounter
+		suffix   string
+		response string
+		wantPath string
+		want     int64
+		check    func(t *testing.T, r *http.Request, body []byte)
+	}{
+		{
+			name:     "claude count_tokens",
+			protocol: "claude",
+			counter:  claudeTokenCounter{},
+			response: `{"input_tokens":42}`,
+			wantPath: "/v1/messages/count_tokens",
+			want:     42,
+			check: func(t *testing.T, r *http.Request, body []byte) {
+				if r.Header.Get("x-api-key") != "sk-upstream" || r.Header.Get("anthropic-version") != anthropicVersion {
+					t.Fatalf("headers = %v", r.Header)
+				}
+				if got := gjson.GetBytes(body, "model").String(); got != "claude-sonnet" {
+					t.Fatalf("model = %q", got)
+				}
+				if gjson.GetBytes(body, "max_tokens").Exists() {
+					t.Fatalf("count_tokens body must not carry generation parameters: %s", body)
+				}
+				if len(gjson.GetBytes(body, "messages").Array()) != 2 {
+					t.Fatalf("count_tokens body lost the prompt: %s", body)
+				}
+			},
+		},
+		{
+			name:     "gemini countTokens",
+			protocol: "gemini",
+			counter:  geminiTokenCounter{},
+			suffix:   "/v1beta",
+			response: `{"totalTokens":17}`,
+			wantPath: "/v1beta/models/claude-sonnet:countTokens",
+			want:     17,
+			check: func(t *testing.T, r *http.Request, body []byte) {
+				if r.Header.Get("x-goog-api-key") != "sk-upstream" {
+					t.Fatalf("headers = %v", r.Header)
+				}
+				request := gjson.GetBytes(body, "generateContentRequest")
+				if got := request.Get("model").String(); got != "models/claude-sonnet" {
+					t.Fatalf("generateContentRequest.model = %q", got)
+				}
+				if !request.Get("contents").IsArray() || !request.Get("system_instruction").Exists() {
+					t.Fatalf("countTokens body lost the prompt: %s", body)
+				}
+				if request.Get("generationConfig").Exists() {
+					t.Fatalf("countTokens body must not carry generation parameters: %s", body)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &nativeUpstream{}
+			server := upstream.serve(t, "application/json", tt.response)
+			auth := nativeAuth(tt.protocol, server.URL+tt.suffix)
+			req := TokenCountRequest{Model: "claude-sonnet", Payload: []byte(tokenCountPayload), Auth: auth}
+
+			count, ok, err := tt.counter.CountTokens(context.Background(), &config.Config{}, req)
+			if err != nil || !ok || count != tt.want {
+				t.Fatalf("CountTokens = %d, %v, %v; want %d", count, ok, err, tt.want)
+			}
+			if upstream.path != tt.wantPath {
+				t.Fatalf("path = %q, want %q", upstream.path, tt.wantPath)
+			}
+			tt.check(t, &http.Request{Header: upstream.header}, upstream.body)
+		})
+	}
+}
+
+func TestUpstreamTokenCountersUseOnlySelectedCredential(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"input_tokens":1,"totalTokens":1}`))
+	}))
+	defer server.Close()
+	cfg := &config.Config{
+		ClaudeKey: []config.ClaudeKey{{APIKey: "sk-other", BaseURL: server.URL}},
+		GeminiKey: []config.GeminiKey{{APIKey: "sk-other", BaseURL: server.URL}},
+	}
+	openAIAuth := &cliproxyauth.Auth{ID: "compat-1", Attributes: map[string]string{"base_url": server.URL, "api_key": "sk-upstream"}}
+	for _, counter := range []TokenCounter{claudeTokenCounter{}, geminiTokenCounter{}} {
+		for _, auth := range []*cliproxyauth.Auth{nil, openAIAuth} {
+			req := TokenCountRequest{Model: "claude-gemini-model", Payload: []byte(tokenCountPayload), Auth: auth}
+			if _, ok, err := counter.CountTokens(context.Background(), cfg, req); ok || err != nil {
+				t.Fatalf("%T with auth %v = ok %v, err %v; want declined", counter, auth, ok, err)
+			}
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("upstream called %d times without a matching selected credential", calls)
+	}
+}
+
+func TestCountPromptTokensFallsBackOnUpstreamError(t *testing.T) {
+	useTokenCounters(t, namedTokenCounter{name: "claude", counter: claudeTokenCounter{}})
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, `{"error":{"type":"overloaded_error"}}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req := TokenCountRequest{Model: "gpt-4o", Payload: []byte(tokenCountPayload), Auth: nativeAuth("claude", server.URL)}
+	for i := 0; i < 2; i++ {
+		count, err := countPromptTokens(context.Background(), &config.Config{}, req)
+		if err != nil || count <= 0 {
+			t.Fatalf("countPromptTokens = %d, %v; want a local count", count, err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("upstream called %d times, want 2: failures must not be cached", calls)
+	}
+}
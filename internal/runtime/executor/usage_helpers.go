@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -24,6 +26,15 @@ type usageReporter struct {
 	source      string
 	requestedAt time.Time
 	once        sync.Once
+
+	// firstChunkAt holds the UnixNano time of the first non-empty stream chunk.
+	firstChunkAt atomic.Int64
+
+	// pending holds the latest usage reported by a stream. It is published when the stream
+	// finishes, so the record measures the whole stream even when, as with Gemini, every
+	// chunk carries cumulative usage.
+	pendingMu sync.Mutex
+	pending   *usage.Detail
 }
 
 func newUsageReporter(ctx context.Context, provider, model string, auth *cliproxyauth.Auth) *usageReporter {
@@ -39,9 +50,19 @@ func newUsageReporter(ctx context.Context, provider, model string, auth *cliprox
 		reporter.authID = auth.ID
 		reporter.authIndex = auth.EnsureIndex()
 	}
+	recordAPIUpstream(ctx, provider, model, reporter.authIndex)
 	return reporter
 }
 
+// observeStreamChunk records the arrival of the first non-empty upstream stream chunk,
+// which is used as the time-to-first-token of the request.
+func (r *usageReporter) observeStreamChunk(chunk []byte) {
+	if r == nil || r.firstChunkAt.Load() != 0 || len(bytes.TrimSpace(chunk)) == 0 {
+		return
+	}
+	r.firstChunkAt.CompareAndSwap(0, time.Now().UnixNano())
+}
+
 func (r *usageReporter) publish(ctx context.Context, detail usage.Detail) {
 	r.publishWithOutcome(ctx, detail, false)
 }
@@ -72,51 +93,80 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
 		return
 	}
+	if !failed && r.firstChunkAt.Load() != 0 {
+		r.pendingMu.Lock()
+		r.pending = &detail
+		r.pendingMu.Unlock()
+		return
+	}
+	if pending := r.takePending(); failed && pending != nil {
+		detail = *pending
+	}
 	r.once.Do(func() {
-		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      failed,
-			Detail:      detail,
-		})
+		usage.PublishRecord(ctx, r.record(detail, failed))
 	})
 }
 
 // ensurePublished guarantees that a usage record is emitted exactly once.
 // It is safe to call multiple times; only the first call wins due to once.Do.
 // This is used to ensure request counting even when upstream responses do not
-// include any usage fields (tokens), especially for streaming paths.
+// include any usage fields (tokens), especially for streaming paths. Streams call it
+// when they finish to publish the usage held back while they were running.
 func (r *usageReporter) ensurePublished(ctx context.Context) {
 	if r == nil {
 		return
 	}
+	detail := usage.Detail{}
+	if pending := r.takePending(); pending != nil {
+		detail = *pending
+	}
 	r.once.Do(func() {
-		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      false,
-			Detail:      usage.Detail{},
-		})
+		usage.PublishRecord(ctx, r.record(detail, false))
 	})
 }
 
+// takePending returns and clears the usage held back for a running stream.
+func (r *usageReporter) takePending() *usage.Detail {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	pending := r.pending
+	r.pending = nil
+	return pending
+}
+
+// record builds the usage record, measuring latency up to now, which for streams is when
+// they finish. Streams that saw a first chunk also report time-to-first-token and the
+// output token rate after it.
+func (r *usageReporter) record(detail usage.Detail, failed bool) usage.Record {
+	now := time.Now()
+	rec := usage.Record{
+		Provider:    r.provider,
+		Model:       r.model,
+		Source:      r.source,
+		APIKey:      r.apiKey,
+		AuthID:      r.authID,
+		AuthIndex:   r.authIndex,
+		RequestedAt: r.requestedAt,
+		Latency:     now.Sub(r.requestedAt),
+		Failed:      failed,
+		Detail:      detail,
+	}
+	if first := r.firstChunkAt.Load(); first != 0 {
+		rec.TimeToFirstToken = time.Unix(0, first).Sub(r.requestedAt)
+		if generation := rec.Latency - rec.TimeToFirstToken; generation > 0 && detail.OutputTokens > 0 {
+			rec.OutputTokensPerSecond = float64(detail.OutputTokens) / generation.Seconds()
+		}
+	}
+	return rec
+}
+
 func apiKeyFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
 	}
 	ginCtx, ok := ctx.Value("gin").(*gin.Context)
 	if !ok || ginCtx == nil {
-		return ""
+		return cliproxyexecutor.ClientAPIKey(ctx)
 	}
 	if v, exists := ginCtx.Get("apiKey"); exists {
 		switch value := v.(type) {
@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// usageCapture forwards the records of one model to a channel.
+type usageCapture struct {
+	model   string
+	records chan usage.Record
+}
+
+func (c *usageCapture) HandleUsage(_ context.Context, record usage.Record) {
+	if record.Model == c.model {
+		c.records <- record
+	}
+}
+
+func captureUsage(t *testing.T, model string) <-chan usage.Record {
+	t.Helper()
+	capture := &usageCapture{model: model, records: make(chan usage.Record, 4)}
+	usage.RegisterPlugin(capture)
+	return capture.records
+}
+
+func TestStreamUsageMeasuredAtStreamEnd(t *testing.T) {
+	const (
+		model    = "gemini-latency-test"
+		chunks   = 3
+		interval = 40 * time.Millisecond
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for i := 1; i <= chunks; i++ {
+			if i > 1 {
+				time.Sleep(interval)
+			}
+			// Like Gemini, every chunk carries the cumulative usage so far.
+			finish := ""
+			if i == chunks {
+				finish = `,"finishReason":"STOP"`
+			}
+			_, _ = fmt.Fprintf(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"t%d"}]}%s}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":%d,"totalTokenCount":%d}}`+"\n\n", i, finish, i*10, 5+i*10)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+	records := captureUsage(t, model)
+
+	executor := NewGeminiVertexExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "vertex-latency", Provider: "vertex", Attributes: map[string]string{"api_key": "k", "base_url": server.URL}}
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	req := cliproxyexecutor.Request{Model: model, Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload, Stream: true}
+	stream, err := executor.ExecuteStream(context.Background(), auth, req, opts)
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	collectStream(t, stream)
+
+	var record usage.Record
+	select {
+	case record = <-records:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no usage record published")
+	}
+	if record.Detail.OutputTokens != chunks*10 {
+		t.Fatalf("output tokens = %d, want the final cumulative count %d", record.Detail.OutputTokens, chunks*10)
+	}
+	generation := record.Latency - record.TimeToFirstToken
+	if minimum := (chunks - 1) * interval; generation < minimum {
+		t.Fatalf("latency %v after first token %v covers %v, want at least %v", record.Latency, record.TimeToFirstToken, generation, minimum)
+	}
+	if want := float64(chunks*10) / generation.Seconds(); record.OutputTokensPerSecond != want {
+		t.Fatalf("tokens/s = %f, want %f", record.OutputTokensPerSecond, want)
+	}
+	select {
+	case extra := <-records:
+		t.Fatalf("stream published a second record: %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestUsageReporterStreamFailureKeepsPendingUsage(t *testing.T) {
+	const model = "usage-failure-test"
+	records := captureUsage(t, model)
+	reporter := newUsageReporter(context.Background(), "test", model, nil)
+	reporter.observeStreamChunk([]byte("data: {}"))
+	reporter.publish(context.Background(), usage.Detail{InputTokens: 3, OutputTokens: 4})
+	reporter.publishFailure(context.Background())
+	reporter.ensurePublished(context.Background())
+
+	select {
+	case record := <-records:
+		if !record.Failed || record.Detail.OutputTokens != 4 {
+			t.Fatalf("record = %+v, want a failed record with the usage seen so far", record)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no usage record published")
+	}
+}
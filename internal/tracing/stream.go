@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// translationStats accumulates the time spent re-translating the chunks of one stream.
+// A span per SSE chunk would dwarf the trace, so the totals are reported on the stream span.
+type translationStats struct {
+	chunks atomic.Int64
+	nanos  atomic.Int64
+}
+
+type translationStatsKey struct{}
+
+// WithTranslationStats attaches a translation accumulator to the context of a stream.
+func WithTranslationStats(ctx context.Context) context.Context {
+	if !Enabled() {
+		return ctx
+	}
+	return context.WithValue(ctx, translationStatsKey{}, &translationStats{})
+}
+
+// RecordTranslation adds the time since started to the stream's accumulator, if any.
+func RecordTranslation(ctx context.Context, started time.Time) {
+	if ctx == nil {
+		return
+	}
+	stats, ok := ctx.Value(translationStatsKey{}).(*translationStats)
+	if !ok {
+		return
+	}
+	stats.chunks.Add(1)
+	stats.nanos.Add(int64(time.Since(started)))
+}
+
+// AnnotateTranslation reports the accumulated translation totals on span.
+func AnnotateTranslation(ctx context.Context, span trace.Span) {
+	stats, ok := ctx.Value(translationStatsKey{}).(*translationStats)
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int64("cliproxy.translate.chunks", stats.chunks.Load()),
+		attribute.Float64("cliproxy.translate.duration_ms", float64(stats.nanos.Load())/float64(time.Millisecond)),
+	)
+}
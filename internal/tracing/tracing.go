@@ -0,0 +1,191 @@
+// Package tracing wires OpenTelemetry tracing into the proxy. Spans cover the gin handler,
+// auth manager retries and credential selection, executor calls, upstream HTTP round trips
+// and stream translation. Export is off by default; when disabled every helper degrades to
+// the no-op tracer.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// InstrumentationName identifies the tracer used by the proxy.
+	InstrumentationName = "github.com/router-for-me/CLIProxyAPI/v6"
+	defaultServiceName  = "cli-proxy-api"
+	shutdownTimeout     = 5 * time.Second
+)
+
+// Span attribute keys shared by every proxy span.
+const (
+	AttrProvider  = attribute.Key("cliproxy.provider")
+	AttrModel     = attribute.Key("cliproxy.model")
+	AttrAuthIndex = attribute.Key("cliproxy.auth_index")
+	AttrRequestID = attribute.Key("cliproxy.request_id")
+)
+
+var (
+	providerMu sync.Mutex
+	provider   *sdktrace.TracerProvider
+	enabled    atomic.Bool
+)
+
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool { return enabled.Load() }
+
+// Configure installs the tracer provider described by cfg, replacing and flushing the
+// previous one. A disabled configuration installs the no-op provider.
+func Configure(cfg config.TracingConfig) error {
+	var next *sdktrace.TracerProvider
+	if cfg.Enabled {
+		exporter, err := newExporter(cfg)
+		if err != nil {
+			return err
+		}
+		serviceName := strings.TrimSpace(cfg.ServiceName)
+		if serviceName == "" {
+			serviceName = defaultServiceName
+		}
+		res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(buildinfo.Version),
+		))
+		if err != nil {
+			res = resource.Default()
+		}
+		sampler := sdktrace.AlwaysSample()
+		if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+			sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+		}
+		next = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		)
+	}
+
+	setProvider(next)
+	return nil
+}
+
+// Shutdown flushes pending spans and disables tracing.
+func Shutdown() {
+	setProvider(nil)
+}
+
+// setProvider makes next the global tracer provider (nil disables tracing) and shuts the
+// previous one down.
+func setProvider(next *sdktrace.TracerProvider) {
+	providerMu.Lock()
+	previous := provider
+	provider = next
+	if next != nil {
+		otel.SetTracerProvider(next)
+	} else {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}
+	enabled.Store(next != nil)
+	providerMu.Unlock()
+
+	if previous != nil {
+		shutdownProvider(previous)
+	}
+}
+
+func shutdownProvider(p *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = p.Shutdown(ctx)
+}
+
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	endpoint := strings.TrimSpace(cfg.Endpoint)
+	switch strings.ToLower(strings.TrimSpace(cfg.Exporter)) {
+	case "", "otlp", "otlp-http", "http":
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			if strings.Contains(endpoint, "://") {
+				opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+			} else {
+				opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+			}
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case "otlp-grpc", "grpc":
+		var opts []otlptracegrpc.Option
+		if endpoint != "" {
+			if strings.Contains(endpoint, "://") {
+				opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+			} else {
+				opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+			}
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		return otlptracegrpc.New(context.Background(), opts...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("tracing: unsupported exporter %q", cfg.Exporter)
+	}
+}
+
+// Start opens an internal span named name under ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, when non-nil, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inherit copies the span of from into ctx, so work detached from a request context
+// still joins the request's trace.
+func Inherit(ctx, from context.Context) context.Context {
+	if from == nil {
+		return ctx
+	}
+	span := trace.SpanFromContext(from)
+	if !span.SpanContext().IsValid() || trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, span)
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTransportAndTranslationSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(Shutdown)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") != "" {
+			t.Error("trace context leaked to the upstream")
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	ctx, parent := Start(WithTranslationStats(context.Background()), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, upstream.URL+"/v1/messages", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	RecordTranslation(ctx, time.Now().Add(-time.Millisecond))
+	AnnotateTranslation(ctx, parent)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	client := spans[0]
+	if client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("upstream span is not a child of the caller's span")
+	}
+	attrs := attribute.NewSet(client.Attributes()...)
+	if status, _ := attrs.Value("http.response.status_code"); status.AsInt64() != http.StatusOK {
+		t.Fatalf("status attribute = %v", status)
+	}
+	parentAttrs := attribute.NewSet(spans[1].Attributes()...)
+	if chunks, _ := parentAttrs.Value("cliproxy.translate.chunks"); chunks.AsInt64() != 1 {
+		t.Fatalf("translate.chunks = %v, want 1", chunks)
+	}
+}
+
+func TestTransportSkipsUntracedRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(Shutdown)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer upstream.Close()
+	resp, err := (&http.Client{Transport: Transport(nil)}).Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Fatalf("ended spans = %d, want none without a parent span", len(spans))
+	}
+}
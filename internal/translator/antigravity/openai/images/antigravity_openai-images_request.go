@@ -0,0 +1,16 @@
+// Package images translates OpenAI Images API requests for Antigravity by wrapping the Gemini
+// image translation in the Antigravity envelope.
+package images
+
+import (
+	"bytes"
+
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/antigravity/gemini"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/images"
+)
+
+func ConvertOpenAIImageRequestToAntigravity(modelName string, inputRawJSON []byte, stream bool) []byte {
+	rawJSON := bytes.Clone(inputRawJSON)
+	rawJSON = ConvertOpenAIImageRequestToGemini(modelName, rawJSON, stream)
+	return ConvertGeminiRequestToAntigravity(modelName, rawJSON, stream)
+}
@@ -0,0 +1,16 @@
+package images
+
+import (
+	"context"
+
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/images"
+	"github.com/tidwall/gjson"
+)
+
+func ConvertAntigravityResponseToOpenAIImage(ctx context.Context, modelName string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) string {
+	responseResult := gjson.GetBytes(rawJSON, "response")
+	if responseResult.Exists() {
+		rawJSON = []byte(responseResult.Raw)
+	}
+	return ConvertGeminiResponseToOpenAIImage(ctx, modelName, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+}
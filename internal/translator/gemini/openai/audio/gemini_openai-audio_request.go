@@ -0,0 +1,100 @@
+// Package audio translates OpenAI Audio API requests into Gemini generateContent requests.
+// Transcription requests carry the uploaded audio in a "file" object of the form
+// {"data","mime_type","filename"} and become an instruction plus an inline audio part.
+// Speech requests become a text prompt answered with AUDIO output using a prebuilt voice.
+package audio
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// defaultVoice is used when the request names no voice.
+const defaultVoice = "Kore"
+
+// openAIVoices maps OpenAI voice names to the closest Gemini prebuilt voice. Any other
+// voice is passed through, so Gemini voice names can be used directly.
+var openAIVoices = map[string]string{
+	"alloy":   "Kore",
+	"ash":     "Charon",
+	"ballad":  "Algieba",
+	"coral":   "Aoede",
+	"echo":    "Puck",
+	"fable":   "Fenrir",
+	"onyx":    "Orus",
+	"nova":    "Leda",
+	"sage":    "Sulafat",
+	"shimmer": "Zephyr",
+	"verse":   "Achird",
+}
+
+// ConvertOpenAITranscriptionRequestToGemini converts an OpenAI transcription request into a
+// Gemini request that asks the model to transcribe the attached audio.
+func ConvertOpenAITranscriptionRequestToGemini(modelName string, inputRawJSON []byte, _ bool) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out := `{"contents":[{"role":"user","parts":[]}]}`
+	out, _ = sjson.Set(out, "model", modelName)
+
+	instruction := []string{"Generate a verbatim transcript of the speech in this audio."}
+	if language := root.Get("language").String(); language != "" {
+		instruction = append(instruction, "The audio is in the language with ISO-639-1 code \""+language+"\".")
+	}
+	if prompt := root.Get("prompt").String(); prompt != "" {
+		instruction = append(instruction, "Use this context for spelling and style: "+prompt)
+	}
+	switch root.Get("response_format").String() {
+	case "srt":
+		instruction = append(instruction, "Return the transcript as SubRip (SRT) subtitles with timestamps and nothing else.")
+	case "vtt":
+		instruction = append(instruction, "Return the transcript as WebVTT subtitles with timestamps and nothing else.")
+	default:
+		instruction = append(instruction, "Return only the transcript text, without any commentary.")
+	}
+	out, _ = sjson.Set(out, "contents.0.parts.-1.text", strings.Join(instruction, " "))
+
+	if data := root.Get("file.data").String(); data != "" {
+		mimeType := root.Get("file.mime_type").String()
+		if mimeType == "" {
+			mimeType = "audio/mpeg"
+		}
+		part := `{"inlineData":{"mime_type":"","data":""}}`
+		part, _ = sjson.Set(part, "inlineData.mime_type", mimeType)
+		part, _ = sjson.Set(part, "inlineData.data", data)
+		out, _ = sjson.SetRaw(out, "contents.0.parts.-1", part)
+	}
+	if temperature := root.Get("temperature"); temperature.Exists() {
+		out, _ = sjson.Set(out, "generationConfig.temperature", temperature.Float())
+	}
+	return []byte(out)
+}
+
+// ConvertOpenAISpeechRequestToGemini converts an OpenAI speech request into a Gemini request
+// with AUDIO output. Instructions are prepended to the input as a style prompt.
+func ConvertOpenAISpeechRequestToGemini(modelName string, inputRawJSON []byte, _ bool) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out := `{"contents":[{"role":"user","parts":[]}],"generationConfig":{"responseModalities":["AUDIO"]}}`
+	out, _ = sjson.Set(out, "model", modelName)
+
+	text := root.Get("input").String()
+	if instructions := strings.TrimSpace(root.Get("instructions").String()); instructions != "" {
+		text = instructions + ":\n" + text
+	}
+	out, _ = sjson.Set(out, "contents.0.parts.-1.text", text)
+	out, _ = sjson.Set(out, "generationConfig.speechConfig.voiceConfig.prebuiltVoiceConfig.voiceName", geminiVoice(root.Get("voice").String()))
+	return []byte(out)
+}
+
+func geminiVoice(voice string) string {
+	voice = strings.TrimSpace(voice)
+	if voice == "" {
+		return defaultVoice
+	}
+	if mapped, ok := openAIVoices[strings.ToLower(voice)]; ok {
+		return mapped
+	}
+	return voice
+}
@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiResponseToOpenAITranscription converts a Gemini response into an OpenAI
+// transcription object. verbose_json requests additionally get the task and language fields.
+func ConvertGeminiResponseToOpenAITranscription(_ context.Context, _ string, originalRequestRawJSON, _, rawJSON []byte, _ *any) string {
+	var texts []string
+	gjson.GetBytes(rawJSON, "candidates.0.content.parts").ForEach(func(_, part gjson.Result) bool {
+		if part.Get("thought").Bool() {
+			return true
+		}
+		if text := part.Get("text").String(); text != "" {
+			texts = append(texts, text)
+		}
+		return true
+	})
+
+	out := `{"text":""}`
+	out, _ = sjson.Set(out, "text", strings.TrimSpace(strings.Join(texts, "")))
+	original := gjson.ParseBytes(originalRequestRawJSON)
+	if original.Get("response_format").String() == "verbose_json" {
+		out, _ = sjson.Set(out, "task", "transcribe")
+		out, _ = sjson.Set(out, "language", original.Get("language").String())
+	}
+	if usage := gjson.GetBytes(rawJSON, "usageMetadata"); usage.Exists() {
+		out, _ = sjson.Set(out, "usage.type", "tokens")
+		out, _ = sjson.Set(out, "usage.input_tokens", usage.Get("promptTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.output_tokens", usage.Get("candidatesTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.total_tokens", usage.Get("totalTokenCount").Int())
+	}
+	return out
+}
+
+// ConvertGeminiResponseToOpenAISpeech extracts the generated audio from a Gemini response.
+// The result is {"audio":{"data","mime_type"},"usage":{...}}; the speech handler decodes it
+// and encodes the requested container, since the Audio API answers with raw audio bytes.
+func ConvertGeminiResponseToOpenAISpeech(_ context.Context, _ string, _, _, rawJSON []byte, _ *any) string {
+	out := `{}`
+	gjson.GetBytes(rawJSON, "candidates.0.content.parts").ForEach(func(_, part gjson.Result) bool {
+		inline := part.Get("inlineData")
+		if !inline.Exists() {
+			inline = part.Get("inline_data")
+		}
+		data := inline.Get("data").String()
+		if data == "" {
+			return true
+		}
+		mimeType := inline.Get("mimeType").String()
+		if mimeType == "" {
+			mimeType = inline.Get("mime_type").String()
+		}
+		out, _ = sjson.Set(out, "audio.data", data)
+		out, _ = sjson.Set(out, "audio.mime_type", mimeType)
+		return false
+	})
+	if usage := gjson.GetBytes(rawJSON, "usageMetadata"); usage.Exists() {
+		out, _ = sjson.Set(out, "usage.input_tokens", usage.Get("promptTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.output_tokens", usage.Get("candidatesTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.total_tokens", usage.Get("totalTokenCount").Int())
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAITranscriptionRequestToGemini(t *testing.T) {
+	raw := []byte(`{"model":"gemini-2.5-flash","language":"de","response_format":"srt","temperature":0.2,"file":{"data":"YXVkaW8=","mime_type":"audio/wav","filename":"a.wav"}}`)
+
+	out := ConvertOpenAITranscriptionRequestToGemini("gemini-2.5-flash", raw, false)
+	instruction := gjson.GetBytes(out, "contents.0.parts.0.text").String()
+	if !strings.Contains(instruction, `"de"`) || !strings.Contains(instruction, "SRT") {
+		t.Fatalf("instruction = %q", instruction)
+	}
+	if got := gjson.GetBytes(out, "contents.0.parts.1.inlineData.mime_type").String(); got != "audio/wav" {
+		t.Fatalf("audio mime_type = %q, want audio/wav: %s", got, out)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.temperature").Float(); got != 0.2 {
+		t.Fatalf("temperature = %v, want 0.2", got)
+	}
+}
+
+func TestConvertOpenAISpeechRequestToGemini(t *testing.T) {
+	raw := []byte(`{"model":"gemini-2.5-flash-preview-tts","input":"Hello there","voice":"nova","instructions":"Say cheerfully"}`)
+
+	out := ConvertOpenAISpeechRequestToGemini("gemini-2.5-flash-preview-tts", raw, false)
+	if got := gjson.GetBytes(out, "contents.0.parts.0.text").String(); got != "Say cheerfully:\nHello there" {
+		t.Fatalf("text = %q", got)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.speechConfig.voiceConfig.prebuiltVoiceConfig.voiceName").String(); got != "Leda" {
+		t.Fatalf("voiceName = %q, want Leda", got)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.responseModalities.0").String(); got != "AUDIO" {
+		t.Fatalf("responseModalities = %s", gjson.GetBytes(out, "generationConfig.responseModalities").Raw)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAITranscription(t *testing.T) {
+	raw := []byte(`{"candidates":[{"content":{"parts":[{"text":"Guten Tag. "}]}}],"usageMetadata":{"promptTokenCount":40,"candidatesTokenCount":4,"totalTokenCount":44}}`)
+
+	out := ConvertGeminiResponseToOpenAITranscription(context.Background(), "m", []byte(`{"response_format":"verbose_json","language":"de"}`), nil, raw, nil)
+	if got := gjson.Get(out, "text").String(); got != "Guten Tag." {
+		t.Fatalf("text = %q: %s", got, out)
+	}
+	if got := gjson.Get(out, "language").String(); got != "de" {
+		t.Fatalf("language = %q, want de", got)
+	}
+	if got := gjson.Get(out, "usage.total_tokens").Int(); got != 44 {
+		t.Fatalf("usage.total_tokens = %d, want 44", got)
+	}
+}
@@ -0,0 +1,26 @@
+package audio
+
+import (
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/translator"
+)
+
+func init() {
+	translator.Register(
+		OpenAITranscription,
+		Gemini,
+		ConvertOpenAITranscriptionRequestToGemini,
+		interfaces.TranslateResponse{
+			NonStream: ConvertGeminiResponseToOpenAITranscription,
+		},
+	)
+	translator.Register(
+		OpenAISpeech,
+		Gemini,
+		ConvertOpenAISpeechRequestToGemini,
+		interfaces.TranslateResponse{
+			NonStream: ConvertGeminiResponseToOpenAISpeech,
+		},
+	)
+}
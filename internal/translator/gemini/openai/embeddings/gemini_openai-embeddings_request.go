@@ -0,0 +1,42 @@
+// Package embeddings translates between the OpenAI embeddings API and the Gemini
+// batchEmbedContents API. OpenAI inputs become one embedding request each, and
+// the Gemini embeddings are returned as an OpenAI embedding list.
+package embeddings
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertOpenAIEmbeddingRequestToGemini converts an OpenAI embeddings request into a Gemini
+// batchEmbedContents request. String inputs and arrays of strings are supported; token-array
+// inputs have no Gemini equivalent and are skipped.
+func ConvertOpenAIEmbeddingRequestToGemini(modelName string, inputRawJSON []byte, _ bool) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+	dimensions := root.Get("dimensions")
+
+	out := `{"requests":[]}`
+	appendText := func(text string) {
+		item := `{"model":"","content":{"parts":[{"text":""}]}}`
+		item, _ = sjson.Set(item, "model", "models/"+modelName)
+		item, _ = sjson.Set(item, "content.parts.0.text", text)
+		if dimensions.Exists() && dimensions.Int() > 0 {
+			item, _ = sjson.Set(item, "outputDimensionality", dimensions.Int())
+		}
+		out, _ = sjson.SetRaw(out, "requests.-1", item)
+	}
+
+	input := root.Get("input")
+	switch {
+	case input.Type == gjson.String:
+		appendText(input.String())
+	case input.IsArray():
+		input.ForEach(func(_, value gjson.Result) bool {
+			if value.Type == gjson.String {
+				appendText(value.String())
+			}
+			return true
+		})
+	}
+	return []byte(out)
+}
@@ -0,0 +1,48 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiEmbeddingResponseToOpenAI converts a Gemini batchEmbedContents response into an
+// OpenAI embeddings list. When the client asked for encoding_format "base64", vectors are
+// encoded as little-endian float32 like the OpenAI API does.
+func ConvertGeminiEmbeddingResponseToOpenAI(_ context.Context, modelName string, originalRequestRawJSON, _, rawJSON []byte, _ *any) string {
+	asBase64 := gjson.GetBytes(originalRequestRawJSON, "encoding_format").String() == "base64"
+
+	out := `{"object":"list","data":[],"model":"","usage":{"prompt_tokens":0,"total_tokens":0}}`
+	out, _ = sjson.Set(out, "model", modelName)
+	gjson.GetBytes(rawJSON, "embeddings").ForEach(func(key, embedding gjson.Result) bool {
+		item := `{"object":"embedding","index":0,"embedding":[]}`
+		item, _ = sjson.Set(item, "index", key.Int())
+		values := embedding.Get("values")
+		if asBase64 {
+			item, _ = sjson.Set(item, "embedding", encodeFloat32Base64(values))
+		} else if values.IsArray() {
+			item, _ = sjson.SetRaw(item, "embedding", values.Raw)
+		}
+		out, _ = sjson.SetRaw(out, "data.-1", item)
+		return true
+	})
+
+	if tokens := gjson.GetBytes(rawJSON, "usageMetadata.promptTokenCount"); tokens.Exists() {
+		out, _ = sjson.Set(out, "usage.prompt_tokens", tokens.Int())
+		out, _ = sjson.Set(out, "usage.total_tokens", tokens.Int())
+	}
+	return out
+}
+
+func encodeFloat32Base64(values gjson.Result) string {
+	array := values.Array()
+	buf := make([]byte, 4*len(array))
+	for i, v := range array {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v.Float())))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,50 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIEmbeddingRequestToGemini(t *testing.T) {
+	out := ConvertOpenAIEmbeddingRequestToGemini("text-embedding-004", []byte(`{"model":"text-embedding-004","input":["a","b"],"dimensions":256}`), false)
+
+	requests := gjson.GetBytes(out, "requests").Array()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2: %s", len(requests), out)
+	}
+	for i, want := range []string{"a", "b"} {
+		if got := requests[i].Get("content.parts.0.text").String(); got != want {
+			t.Fatalf("requests[%d] text = %q, want %q", i, got, want)
+		}
+		if got := requests[i].Get("model").String(); got != "models/text-embedding-004" {
+			t.Fatalf("requests[%d] model = %q", i, got)
+		}
+		if got := requests[i].Get("outputDimensionality").Int(); got != 256 {
+			t.Fatalf("requests[%d] outputDimensionality = %d, want 256", i, got)
+		}
+	}
+}
+
+func TestConvertGeminiEmbeddingResponseToOpenAI(t *testing.T) {
+	raw := []byte(`{"embeddings":[{"values":[0.5,-1]},{"values":[2]}],"usageMetadata":{"promptTokenCount":7}}`)
+
+	out := ConvertGeminiEmbeddingResponseToOpenAI(context.Background(), "m", []byte(`{}`), nil, raw, nil)
+	if got := gjson.Get(out, "data.1.index").Int(); got != 1 {
+		t.Fatalf("data[1].index = %d, want 1: %s", got, out)
+	}
+	if got := gjson.Get(out, "data.0.embedding.1").Float(); got != -1 {
+		t.Fatalf("data[0].embedding[1] = %v, want -1", got)
+	}
+	if got := gjson.Get(out, "usage.prompt_tokens").Int(); got != 7 {
+		t.Fatalf("usage.prompt_tokens = %d, want 7", got)
+	}
+
+	out = ConvertGeminiEmbeddingResponseToOpenAI(context.Background(), "m", []byte(`{"encoding_format":"base64"}`), nil, raw, nil)
+	decoded, err := base64.StdEncoding.DecodeString(gjson.Get(out, "data.0.embedding").String())
+	if err != nil || len(decoded) != 8 {
+		t.Fatalf("base64 embedding = %q (%v), want 8 bytes", gjson.Get(out, "data.0.embedding").String(), err)
+	}
+}
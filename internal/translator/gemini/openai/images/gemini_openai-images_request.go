@@ -0,0 +1,89 @@
+// Package images translates OpenAI Images API requests into Gemini generateContent requests
+// for image-capable models, and inline image parts of the response back into an OpenAI
+// images response. Edit requests carry their source images in an "images" array of
+// {"data","mime_type","filename"} objects and an optional "mask" object of the same shape.
+package images
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// geminiAspectRatios lists the aspect ratios accepted by generationConfig.imageConfig.
+var geminiAspectRatios = []string{"1:1", "2:3", "3:2", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9"}
+
+// ConvertOpenAIImageRequestToGemini converts an OpenAI image generation or edit request into a
+// Gemini generateContent request that asks for image output.
+func ConvertOpenAIImageRequestToGemini(modelName string, inputRawJSON []byte, _ bool) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out := `{"contents":[{"role":"user","parts":[]}],"generationConfig":{"responseModalities":["IMAGE","TEXT"]}}`
+	out, _ = sjson.Set(out, "model", modelName)
+
+	prompt := root.Get("prompt").String()
+	if mask := root.Get("mask"); mask.IsObject() {
+		prompt += "\n\nOnly change the regions that are transparent in the provided mask image; keep everything else unchanged."
+	}
+	if prompt != "" {
+		out, _ = sjson.Set(out, "contents.0.parts.-1.text", prompt)
+	}
+	appendImage := func(image gjson.Result) {
+		data := image.Get("data").String()
+		if data == "" {
+			return
+		}
+		mimeType := image.Get("mime_type").String()
+		if mimeType == "" {
+			mimeType = "image/png"
+		}
+		part := `{"inlineData":{"mime_type":"","data":""}}`
+		part, _ = sjson.Set(part, "inlineData.mime_type", mimeType)
+		part, _ = sjson.Set(part, "inlineData.data", data)
+		out, _ = sjson.SetRaw(out, "contents.0.parts.-1", part)
+	}
+	root.Get("images").ForEach(func(_, image gjson.Result) bool {
+		appendImage(image)
+		return true
+	})
+	if mask := root.Get("mask"); mask.IsObject() {
+		appendImage(mask)
+	}
+
+	aspectRatio := root.Get("aspect_ratio").String()
+	if aspectRatio == "" {
+		aspectRatio = aspectRatioFromSize(root.Get("size").String())
+	}
+	if aspectRatio != "" {
+		out, _ = sjson.Set(out, "generationConfig.imageConfig.aspectRatio", aspectRatio)
+	}
+	return []byte(out)
+}
+
+// aspectRatioFromSize maps an OpenAI size such as "1536x1024" to the closest Gemini aspect
+// ratio. "auto" and unparsable sizes leave the choice to the model.
+func aspectRatioFromSize(size string) string {
+	width, height, ok := strings.Cut(strings.ToLower(strings.TrimSpace(size)), "x")
+	if !ok {
+		return ""
+	}
+	w, errW := strconv.ParseFloat(width, 64)
+	h, errH := strconv.ParseFloat(height, 64)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return ""
+	}
+	target := w / h
+	best, bestDiff := "", math.MaxFloat64
+	for _, ratio := range geminiAspectRatios {
+		a, b, _ := strings.Cut(ratio, ":")
+		num, _ := strconv.ParseFloat(a, 64)
+		den, _ := strconv.ParseFloat(b, 64)
+		if diff := math.Abs(num/den - target); diff < bestDiff {
+			best, bestDiff = ratio, diff
+		}
+	}
+	return best
+}
@@ -0,0 +1,63 @@
+package images
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiResponseToOpenAIImage converts the inline image parts of a Gemini response into
+// an OpenAI images response with b64_json entries. Text returned next to the images becomes
+// the revised_prompt of the first image.
+func ConvertGeminiResponseToOpenAIImage(_ context.Context, _ string, _, _, rawJSON []byte, _ *any) string {
+	out := `{"created":0,"data":[]}`
+	out, _ = sjson.Set(out, "created", time.Now().Unix())
+
+	var texts []string
+	outputFormat := ""
+	gjson.GetBytes(rawJSON, "candidates").ForEach(func(_, candidate gjson.Result) bool {
+		candidate.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			if part.Get("thought").Bool() {
+				return true
+			}
+			inline := part.Get("inlineData")
+			if !inline.Exists() {
+				inline = part.Get("inline_data")
+			}
+			if data := inline.Get("data").String(); data != "" {
+				item := `{"b64_json":""}`
+				item, _ = sjson.Set(item, "b64_json", data)
+				out, _ = sjson.SetRaw(out, "data.-1", item)
+				if outputFormat == "" {
+					mimeType := inline.Get("mimeType").String()
+					if mimeType == "" {
+						mimeType = inline.Get("mime_type").String()
+					}
+					outputFormat = strings.TrimPrefix(mimeType, "image/")
+				}
+				return true
+			}
+			if text := part.Get("text").String(); text != "" {
+				texts = append(texts, text)
+			}
+			return true
+		})
+		return true
+	})
+	if len(texts) > 0 && gjson.Get(out, "data.#").Int() > 0 {
+		out, _ = sjson.Set(out, "data.0.revised_prompt", strings.TrimSpace(strings.Join(texts, "\n")))
+	}
+	if outputFormat != "" {
+		out, _ = sjson.Set(out, "output_format", outputFormat)
+	}
+
+	if usage := gjson.GetBytes(rawJSON, "usageMetadata"); usage.Exists() {
+		out, _ = sjson.Set(out, "usage.input_tokens", usage.Get("promptTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.output_tokens", usage.Get("candidatesTokenCount").Int())
+		out, _ = sjson.Set(out, "usage.total_tokens", usage.Get("totalTokenCount").Int())
+	}
+	return out
+}
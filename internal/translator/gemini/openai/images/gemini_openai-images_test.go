@@ -0,0 +1,48 @@
+package images
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIImageRequestToGemini(t *testing.T) {
+	raw := []byte(`{"model":"gemini-2.5-flash-image","prompt":"add a hat","size":"1536x1024","images":[{"data":"aW1n","mime_type":"image/jpeg"}],"mask":{"data":"bWFzaw=="}}`)
+
+	out := ConvertOpenAIImageRequestToGemini("gemini-2.5-flash-image", raw, false)
+	parts := gjson.GetBytes(out, "contents.0.parts").Array()
+	if len(parts) != 3 {
+		t.Fatalf("parts = %d, want 3: %s", len(parts), out)
+	}
+	if got := parts[1].Get("inlineData.mime_type").String(); got != "image/jpeg" {
+		t.Fatalf("image mime_type = %q, want image/jpeg", got)
+	}
+	if got := parts[2].Get("inlineData.mime_type").String(); got != "image/png" {
+		t.Fatalf("mask mime_type = %q, want image/png", got)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.imageConfig.aspectRatio").String(); got != "3:2" {
+		t.Fatalf("aspectRatio = %q, want 3:2", got)
+	}
+	if got := gjson.GetBytes(out, "generationConfig.responseModalities.0").String(); got != "IMAGE" {
+		t.Fatalf("responseModalities = %s", gjson.GetBytes(out, "generationConfig.responseModalities").Raw)
+	}
+}
+
+func TestConvertGeminiResponseToOpenAIImage(t *testing.T) {
+	raw := []byte(`{"candidates":[{"content":{"parts":[{"text":"A cat wearing a hat."},{"inlineData":{"mimeType":"image/png","data":"cG5n"}}]}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":1290,"totalTokenCount":1295}}`)
+
+	out := ConvertGeminiResponseToOpenAIImage(context.Background(), "m", nil, nil, raw, nil)
+	if got := gjson.Get(out, "data.0.b64_json").String(); got != "cG5n" {
+		t.Fatalf("data[0].b64_json = %q: %s", got, out)
+	}
+	if got := gjson.Get(out, "data.0.revised_prompt").String(); got != "A cat wearing a hat." {
+		t.Fatalf("revised_prompt = %q", got)
+	}
+	if got := gjson.Get(out, "output_format").String(); got != "png" {
+		t.Fatalf("output_format = %q, want png", got)
+	}
+	if got := gjson.Get(out, "usage.total_tokens").Int(); got != 1295 {
+		t.Fatalf("usage.total_tokens = %d, want 1295", got)
+	}
+}
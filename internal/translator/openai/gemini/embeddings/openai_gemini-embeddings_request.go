@@ -0,0 +1,38 @@
+// Package embeddings translates between the Gemini batchEmbedContents API and the OpenAI
+// embeddings API. Each Gemini embedding request becomes one OpenAI input, and the OpenAI
+// embedding list is returned as Gemini embeddings in request order.
+package embeddings
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiEmbeddingRequestToOpenAI converts a Gemini batchEmbedContents request into an
+// OpenAI embeddings request. The text parts of each request are joined with newlines.
+func ConvertGeminiEmbeddingRequestToOpenAI(modelName string, inputRawJSON []byte, _ bool) []byte {
+	out := `{"model":"","input":[],"encoding_format":"float"}`
+	out, _ = sjson.Set(out, "model", modelName)
+
+	var dimensions int64
+	gjson.GetBytes(inputRawJSON, "requests").ForEach(func(_, request gjson.Result) bool {
+		var texts []string
+		request.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			if text := part.Get("text"); text.Exists() {
+				texts = append(texts, text.String())
+			}
+			return true
+		})
+		out, _ = sjson.Set(out, "input.-1", strings.Join(texts, "\n"))
+		if dimensions == 0 {
+			dimensions = request.Get("outputDimensionality").Int()
+		}
+		return true
+	})
+	if dimensions > 0 {
+		out, _ = sjson.Set(out, "dimensions", dimensions)
+	}
+	return []byte(out)
+}
@@ -0,0 +1,45 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertOpenAIEmbeddingResponseToGemini converts an OpenAI embeddings list into a Gemini
+// batchEmbedContents response, ordering the vectors by their index.
+func ConvertOpenAIEmbeddingResponseToGemini(_ context.Context, _ string, _, _, rawJSON []byte, _ *any) string {
+	data := gjson.GetBytes(rawJSON, "data").Array()
+	sort.SliceStable(data, func(i, j int) bool { return data[i].Get("index").Int() < data[j].Get("index").Int() })
+
+	out := `{"embeddings":[]}`
+	for _, item := range data {
+		embedding := `{"values":[]}`
+		vector := item.Get("embedding")
+		switch {
+		case vector.IsArray():
+			embedding, _ = sjson.SetRaw(embedding, "values", vector.Raw)
+		case vector.Type == gjson.String:
+			embedding, _ = sjson.Set(embedding, "values", decodeFloat32Base64(vector.String()))
+		}
+		out, _ = sjson.SetRaw(out, "embeddings.-1", embedding)
+	}
+	return out
+}
+
+func decodeFloat32Base64(encoded string) []float32 {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return []float32{}
+	}
+	values := make([]float32, len(raw)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return values
+}
@@ -6,13 +6,14 @@ package usage
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/router-for-me/CLIProxyAPI/v6/internal/quota"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 )
 
@@ -49,18 +50,6 @@ func (p *LoggerPlugin) HandleUsage(ctx context.Context, record coreusage.Record)
 		return
 	}
 	p.stats.Record(ctx, record)
-
-	// Update quota usage if API key is present
-	if record.APIKey != "" && !record.Failed {
-		detail := record.Detail
-		quota.GetManager().UpdateUsage(
-			record.APIKey,
-			record.Model,
-			detail.InputTokens,
-			detail.OutputTokens,
-			detail.CachedTokens,
-		)
-	}
 }
 
 // SetStatisticsEnabled toggles whether in-memory statistics are recorded.
@@ -84,6 +73,14 @@ type RequestStatistics struct {
 	requestsByHour map[int]int64
 	tokensByDay    map[string]int64
 	tokensByHour   map[int]int64
+
+	cache map[string]*CacheCounts
+}
+
+// CacheCounts holds response cache lookups for a single model.
+type CacheCounts struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
 }
 
 // apiStats holds aggregated metrics for a single API key.
@@ -100,13 +97,18 @@ type modelStats struct {
 	Details       []RequestDetail
 }
 
-// RequestDetail stores the timestamp and token usage for a single request.
+// RequestDetail stores the timestamp, token usage and timings for a single request.
+// Timing fields are omitted for records captured before they were tracked.
 type RequestDetail struct {
-	Timestamp time.Time  `json:"timestamp"`
-	Source    string     `json:"source"`
-	AuthIndex string     `json:"auth_index"`
-	Tokens    TokenStats `json:"tokens"`
-	Failed    bool       `json:"failed"`
+	Timestamp       time.Time  `json:"timestamp"`
+	Source          string     `json:"source"`
+	AuthIndex       string     `json:"auth_index"`
+	Provider        string     `json:"provider,omitempty"`
+	Tokens          TokenStats `json:"tokens"`
+	Failed          bool       `json:"failed"`
+	LatencyMs       int64      `json:"latency_ms,omitempty"`
+	TTFTMs          int64      `json:"ttft_ms,omitempty"`
+	TokensPerSecond float64    `json:"tokens_per_second,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -131,6 +133,40 @@ type StatisticsSnapshot struct {
 	RequestsByHour map[string]int64 `json:"requests_by_hour"`
 	TokensByDay    map[string]int64 `json:"tokens_by_day"`
 	TokensByHour   map[string]int64 `json:"tokens_by_hour"`
+
+	ResponseCache CacheSnapshot `json:"response_cache"`
+
+	Latency LatencySnapshot `json:"latency"`
+}
+
+// LatencySnapshot groups latency percentiles by provider, model and auth index.
+type LatencySnapshot struct {
+	Providers map[string]LatencyStats `json:"providers"`
+	Models    map[string]LatencyStats `json:"models"`
+	Auths     map[string]LatencyStats `json:"auths"`
+}
+
+// LatencyStats summarises the timings of the requests that reported them.
+// TTFT and throughput only cover streaming requests.
+type LatencyStats struct {
+	Requests        int64        `json:"requests"`
+	LatencyMs       Percentiles  `json:"latency_ms"`
+	TTFTMs          *Percentiles `json:"ttft_ms,omitempty"`
+	TokensPerSecond *Percentiles `json:"tokens_per_second,omitempty"`
+}
+
+// Percentiles holds nearest-rank percentiles of a sample.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+// CacheSnapshot summarises response cache lookups.
+type CacheSnapshot struct {
+	Hits   int64                  `json:"hits"`
+	Misses int64                  `json:"misses"`
+	Models map[string]CacheCounts `json:"models"`
 }
 
 // APISnapshot summarises metrics for a single API key.
@@ -160,6 +196,29 @@ func NewRequestStatistics() *RequestStatistics {
 		requestsByHour: make(map[int]int64),
 		tokensByDay:    make(map[string]int64),
 		tokensByHour:   make(map[int]int64),
+		cache:          make(map[string]*CacheCounts),
+	}
+}
+
+// RecordCacheLookup counts a response cache hit or miss for model.
+func (s *RequestStatistics) RecordCacheLookup(model string, hit bool) {
+	if s == nil || !statisticsEnabled.Load() {
+		return
+	}
+	if model == "" {
+		model = "unknown"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.cache[model]
+	if !ok {
+		counts = &CacheCounts{}
+		s.cache[model] = counts
+	}
+	if hit {
+		counts.Hits++
+	} else {
+		counts.Misses++
 	}
 }
 
@@ -210,11 +269,15 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		s.apis[statsKey] = stats
 	}
 	s.updateAPIStats(stats, modelName, RequestDetail{
-		Timestamp: timestamp,
-		Source:    record.Source,
-		AuthIndex: record.AuthIndex,
-		Tokens:    detail,
-		Failed:    failed,
+		Timestamp:       timestamp,
+		Source:          record.Source,
+		AuthIndex:       record.AuthIndex,
+		Provider:        record.Provider,
+		Tokens:          detail,
+		Failed:          failed,
+		LatencyMs:       record.Latency.Milliseconds(),
+		TTFTMs:          record.TimeToFirstToken.Milliseconds(),
+		TokensPerSecond: roundRate(record.OutputTokensPerSecond),
 	})
 
 	s.requestsByDay[dayKey]++
@@ -251,6 +314,7 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	result.FailureCount = s.failureCount
 	result.TotalTokens = s.totalTokens
 
+	latency := newLatencyAggregator()
 	result.APIs = make(map[string]APISnapshot, len(s.apis))
 	for apiName, stats := range s.apis {
 		apiSnapshot := APISnapshot{
@@ -261,6 +325,9 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 		for modelName, modelStatsValue := range stats.Models {
 			requestDetails := make([]RequestDetail, len(modelStatsValue.Details))
 			copy(requestDetails, modelStatsValue.Details)
+			for i := range requestDetails {
+				latency.add(modelName, requestDetails[i])
+			}
 			apiSnapshot.Models[modelName] = ModelSnapshot{
 				TotalRequests: modelStatsValue.TotalRequests,
 				TotalTokens:   modelStatsValue.TotalTokens,
@@ -292,6 +359,15 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 		result.TokensByHour[key] = v
 	}
 
+	result.ResponseCache.Models = make(map[string]CacheCounts, len(s.cache))
+	for model, counts := range s.cache {
+		result.ResponseCache.Hits += counts.Hits
+		result.ResponseCache.Misses += counts.Misses
+		result.ResponseCache.Models[model] = *counts
+	}
+
+	result.Latency = latency.snapshot()
+
 	return result
 }
 
@@ -345,6 +421,7 @@ func (s *RequestStatistics) MergeSnapshot(snapshot StatisticsSnapshot) MergeResu
 			}
 			for _, detail := range modelSnapshot.Details {
 				detail.Tokens = normaliseTokenStats(detail.Tokens)
+				detail = normaliseTimings(detail)
 				if detail.Timestamp.IsZero() {
 					detail.Timestamp = time.Now()
 				}
@@ -407,6 +484,113 @@ func dedupKey(apiName, modelName string, detail RequestDetail) string {
 	)
 }
 
+// latencySamples collects the timings of one provider, model or auth.
+type latencySamples struct {
+	latency []float64
+	ttft    []float64
+	rate    []float64
+}
+
+type latencyAggregator struct {
+	providers map[string]*latencySamples
+	models    map[string]*latencySamples
+	auths     map[string]*latencySamples
+}
+
+func newLatencyAggregator() *latencyAggregator {
+	return &latencyAggregator{
+		providers: make(map[string]*latencySamples),
+		models:    make(map[string]*latencySamples),
+		auths:     make(map[string]*latencySamples),
+	}
+}
+
+// add records the timings of detail. Details without a latency, such as those imported
+// from older snapshots, are skipped.
+func (a *latencyAggregator) add(modelName string, detail RequestDetail) {
+	if detail.LatencyMs <= 0 {
+		return
+	}
+	provider := detail.Provider
+	if provider == "" {
+		provider = "unknown"
+	}
+	targets := []*latencySamples{samplesFor(a.providers, provider), samplesFor(a.models, modelName)}
+	if detail.AuthIndex != "" {
+		targets = append(targets, samplesFor(a.auths, detail.AuthIndex))
+	}
+	for _, samples := range targets {
+		samples.latency = append(samples.latency, float64(detail.LatencyMs))
+		if detail.TTFTMs > 0 {
+			samples.ttft = append(samples.ttft, float64(detail.TTFTMs))
+		}
+		if detail.TokensPerSecond > 0 {
+			samples.rate = append(samples.rate, detail.TokensPerSecond)
+		}
+	}
+}
+
+func samplesFor(groups map[string]*latencySamples, key string) *latencySamples {
+	samples, ok := groups[key]
+	if !ok {
+		samples = &latencySamples{}
+		groups[key] = samples
+	}
+	return samples
+}
+
+func (a *latencyAggregator) snapshot() LatencySnapshot {
+	return LatencySnapshot{
+		Providers: summariseLatency(a.providers),
+		Models:    summariseLatency(a.models),
+		Auths:     summariseLatency(a.auths),
+	}
+}
+
+func summariseLatency(groups map[string]*latencySamples) map[string]LatencyStats {
+	out := make(map[string]LatencyStats, len(groups))
+	for key, samples := range groups {
+		stats := LatencyStats{
+			Requests:  int64(len(samples.latency)),
+			LatencyMs: percentiles(samples.latency),
+		}
+		if len(samples.ttft) > 0 {
+			ttft := percentiles(samples.ttft)
+			stats.TTFTMs = &ttft
+		}
+		if len(samples.rate) > 0 {
+			rate := percentiles(samples.rate)
+			stats.TokensPerSecond = &rate
+		}
+		out[key] = stats
+	}
+	return out
+}
+
+// percentiles sorts values in place and returns their nearest-rank percentiles.
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Float64s(values)
+	rank := func(p float64) float64 {
+		idx := int(math.Ceil(p*float64(len(values)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return values[idx]
+	}
+	return Percentiles{P50: rank(0.50), P90: rank(0.90), P99: rank(0.99)}
+}
+
+// roundRate keeps two decimals of a tokens-per-second rate.
+func roundRate(rate float64) float64 {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 0
+	}
+	return math.Round(rate*100) / 100
+}
+
 func resolveAPIIdentifier(ctx context.Context, record coreusage.Record) string {
 	if ctx != nil {
 		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
@@ -476,6 +660,19 @@ func normaliseTokenStats(tokens TokenStats) TokenStats {
 	return tokens
 }
 
+// normaliseTimings clears invalid timings from imported details. Snapshots written before
+// timings were tracked simply leave them at zero.
+func normaliseTimings(detail RequestDetail) RequestDetail {
+	if detail.LatencyMs < 0 {
+		detail.LatencyMs = 0
+	}
+	if detail.TTFTMs < 0 || detail.TTFTMs > detail.LatencyMs {
+		detail.TTFTMs = 0
+	}
+	detail.TokensPerSecond = roundRate(detail.TokensPerSecond)
+	return detail
+}
+
 func formatHour(hour int) string {
 	if hour < 0 {
 		hour = 0
@@ -0,0 +1,97 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestSnapshotLatencyPercentiles(t *testing.T) {
+	stats := NewRequestStatistics()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 1; i <= 10; i++ {
+		stats.Record(ctx, coreusage.Record{
+			Provider:              "claude",
+			Model:                 "claude-sonnet-4",
+			APIKey:                "key",
+			AuthIndex:             "1",
+			RequestedAt:           base.Add(time.Duration(i) * time.Second),
+			Latency:               time.Duration(i*100) * time.Millisecond,
+			TimeToFirstToken:      time.Duration(i*10) * time.Millisecond,
+			OutputTokensPerSecond: float64(i),
+			Detail:                coreusage.Detail{OutputTokens: 10},
+		})
+	}
+	// A non-streaming request without TTFT still counts towards latency.
+	stats.Record(ctx, coreusage.Record{
+		Provider:    "gemini",
+		Model:       "gemini-2.5-pro",
+		APIKey:      "key",
+		RequestedAt: base,
+		Latency:     250 * time.Millisecond,
+		Detail:      coreusage.Detail{OutputTokens: 1},
+	})
+
+	snapshot := stats.Snapshot()
+	claude, ok := snapshot.Latency.Providers["claude"]
+	if !ok {
+		t.Fatalf("missing claude latency stats: %+v", snapshot.Latency)
+	}
+	if claude.Requests != 10 {
+		t.Fatalf("claude requests = %d, want 10", claude.Requests)
+	}
+	if want := (Percentiles{P50: 500, P90: 900, P99: 1000}); claude.LatencyMs != want {
+		t.Fatalf("claude latency = %+v, want %+v", claude.LatencyMs, want)
+	}
+	if claude.TTFTMs == nil || claude.TTFTMs.P50 != 50 {
+		t.Fatalf("claude ttft = %+v, want p50 50", claude.TTFTMs)
+	}
+	if claude.TokensPerSecond == nil || claude.TokensPerSecond.P90 != 9 {
+		t.Fatalf("claude tokens/s = %+v, want p90 9", claude.TokensPerSecond)
+	}
+	if auth := snapshot.Latency.Auths["1"]; auth.Requests != 10 {
+		t.Fatalf("auth 1 requests = %d, want 10", auth.Requests)
+	}
+	gemini := snapshot.Latency.Models["gemini-2.5-pro"]
+	if gemini.LatencyMs.P99 != 250 || gemini.TTFTMs != nil {
+		t.Fatalf("gemini stats = %+v", gemini)
+	}
+}
+
+func TestMergeSnapshotAcceptsSnapshotsWithoutTimings(t *testing.T) {
+	legacy := `{"apis":{"key":{"models":{"gpt-5":{"details":[
+		{"timestamp":"2026-01-02T03:04:05Z","source":"a","auth_index":"2","tokens":{"input_tokens":3,"output_tokens":4},"failed":false}
+	]}}}}}`
+	var snapshot StatisticsSnapshot
+	if err := json.Unmarshal([]byte(legacy), &snapshot); err != nil {
+		t.Fatalf("unmarshal legacy snapshot: %v", err)
+	}
+
+	stats := NewRequestStatistics()
+	if result := stats.MergeSnapshot(snapshot); result.Added != 1 {
+		t.Fatalf("merge result = %+v, want 1 added", result)
+	}
+	merged := stats.Snapshot()
+	if merged.TotalTokens != 7 {
+		t.Fatalf("total tokens = %d, want 7", merged.TotalTokens)
+	}
+	if len(merged.Latency.Models) != 0 {
+		t.Fatalf("details without timings should not produce latency stats: %+v", merged.Latency)
+	}
+
+	exported, err := json.Marshal(merged)
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	var roundTrip StatisticsSnapshot
+	if err := json.Unmarshal(exported, &roundTrip); err != nil {
+		t.Fatalf("unmarshal exported snapshot: %v", err)
+	}
+	if result := stats.MergeSnapshot(roundTrip); result.Skipped != 1 || result.Added != 0 {
+		t.Fatalf("re-import result = %+v, want 1 skipped", result)
+	}
+}
@@ -143,6 +143,10 @@ func LoadFromFile() error {
 			}
 			if pAPI.Models != nil {
 				for modelName, pModel := range pAPI.Models {
+					// Files saved before timings were tracked load with zero timings.
+					for i := range pModel.Details {
+						pModel.Details[i] = normaliseTimings(pModel.Details[i])
+					}
 					api.Models[modelName] = &modelStats{
 						TotalRequests: pModel.TotalRequests,
 						TotalTokens:   pModel.TotalTokens,
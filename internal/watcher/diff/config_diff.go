@@ -39,6 +39,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.RequestLog != newCfg.RequestLog {
 		changes = append(changes, fmt.Sprintf("request-log: %t -> %t", oldCfg.RequestLog, newCfg.RequestLog))
 	}
+	if oldCfg.RequestLogSink.Format != newCfg.RequestLogSink.Format {
+		changes = append(changes, fmt.Sprintf("request-log-sink.format: %s -> %s (restart required)", oldCfg.RequestLogSink.Format, newCfg.RequestLogSink.Format))
+	}
 	if oldCfg.RequestRetry != newCfg.RequestRetry {
 		changes = append(changes, fmt.Sprintf("request-retry: %d -> %d", oldCfg.RequestRetry, newCfg.RequestRetry))
 	}
@@ -63,6 +66,31 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 		changes = append(changes, fmt.Sprintf("quota-exceeded.switch-preview-model: %t -> %t", oldCfg.QuotaExceeded.SwitchPreviewModel, newCfg.QuotaExceeded.SwitchPreviewModel))
 	}
 
+	if !strings.EqualFold(strings.TrimSpace(oldCfg.Routing.Strategy), strings.TrimSpace(newCfg.Routing.Strategy)) {
+		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", strings.TrimSpace(oldCfg.Routing.Strategy), strings.TrimSpace(newCfg.Routing.Strategy)))
+	}
+	if oldCfg.Routing.CircuitBreaker != newCfg.Routing.CircuitBreaker {
+		ob, nb := oldCfg.Routing.CircuitBreaker, newCfg.Routing.CircuitBreaker
+		changes = append(changes, fmt.Sprintf("routing.circuit-breaker: enabled=%t threshold=%d window=%ds open=%ds -> enabled=%t threshold=%d window=%ds open=%ds",
+			ob.Enabled, ob.FailureThreshold, ob.WindowSeconds, ob.OpenSeconds, nb.Enabled, nb.FailureThreshold, nb.WindowSeconds, nb.OpenSeconds))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.Hedging, newCfg.Routing.Hedging) {
+		oh, nh := oldCfg.Routing.Hedging, newCfg.Routing.Hedging
+		changes = append(changes, fmt.Sprintf("routing.hedging: enabled=%t p%g models=%d -> enabled=%t p%g models=%d",
+			oh.Enabled, oh.Percentile, len(oh.Models), nh.Enabled, nh.Percentile, len(nh.Models)))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.Concurrency, newCfg.Routing.Concurrency) {
+		oc, nc := oldCfg.Routing.Concurrency, newCfg.Routing.Concurrency
+		changes = append(changes, fmt.Sprintf("routing.concurrency: providers=%d queue=%d timeout=%ds -> providers=%d queue=%d timeout=%ds",
+			len(oc.ProviderLimits), oc.QueueSize, oc.QueueTimeoutSeconds, len(nc.ProviderLimits), nc.QueueSize, nc.QueueTimeoutSeconds))
+	}
+	if oldCfg.Routing.SessionAffinity.Enabled != newCfg.Routing.SessionAffinity.Enabled {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.enabled: %t -> %t", oldCfg.Routing.SessionAffinity.Enabled, newCfg.Routing.SessionAffinity.Enabled))
+	}
+	if oldCfg.Routing.SessionAffinity.TTLSeconds != newCfg.Routing.SessionAffinity.TTLSeconds {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.ttl-seconds: %d -> %d", oldCfg.Routing.SessionAffinity.TTLSeconds, newCfg.Routing.SessionAffinity.TTLSeconds))
+	}
+
 	// API keys (redacted) and counts
 	if len(oldCfg.APIKeys) != len(newCfg.APIKeys) {
 		changes = append(changes, fmt.Sprintf("api-keys count: %d -> %d", len(oldCfg.APIKeys), len(newCfg.APIKeys)))
@@ -90,6 +118,15 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("gemini[%d].headers: updated", i))
 			}
+			if o.Priority != n.Priority {
+				changes = append(changes, fmt.Sprintf("gemini[%d].priority: %d -> %d", i, o.Priority, n.Priority))
+			}
+			if o.Weight != n.Weight {
+				changes = append(changes, fmt.Sprintf("gemini[%d].weight: %d -> %d", i, o.Weight, n.Weight))
+			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("gemini[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			oldModels := SummarizeGeminiModels(o.Models)
 			newModels := SummarizeGeminiModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -125,6 +162,15 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("claude[%d].headers: updated", i))
 			}
+			if o.Priority != n.Priority {
+				changes = append(changes, fmt.Sprintf("claude[%d].priority: %d -> %d", i, o.Priority, n.Priority))
+			}
+			if o.Weight != n.Weight {
+				changes = append(changes, fmt.Sprintf("claude[%d].weight: %d -> %d", i, o.Weight, n.Weight))
+			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("claude[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			oldModels := SummarizeClaudeModels(o.Models)
 			newModels := SummarizeClaudeModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -160,6 +206,15 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("codex[%d].headers: updated", i))
 			}
+			if o.Priority != n.Priority {
+				changes = append(changes, fmt.Sprintf("codex[%d].priority: %d -> %d", i, o.Priority, n.Priority))
+			}
+			if o.Weight != n.Weight {
+				changes = append(changes, fmt.Sprintf("codex[%d].weight: %d -> %d", i, o.Weight, n.Weight))
+			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("codex[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			oldModels := SummarizeCodexModels(o.Models)
 			newModels := SummarizeCodexModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -212,6 +267,24 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if entries, _ := DiffOAuthModelMappingChanges(oldCfg.OAuthModelMappings, newCfg.OAuthModelMappings); len(entries) > 0 {
 		changes = append(changes, entries...)
 	}
+	if oldCfg.ResponseStore != newCfg.ResponseStore {
+		changes = append(changes, fmt.Sprintf("response-store: backend %q -> %q", oldCfg.ResponseStore.Backend, newCfg.ResponseStore.Backend))
+	}
+	if oldCfg.Batch.Dir != newCfg.Batch.Dir {
+		changes = append(changes, fmt.Sprintf("batch.dir: %q -> %q", oldCfg.Batch.Dir, newCfg.Batch.Dir))
+	}
+	if oldCfg.Batch.Concurrency != newCfg.Batch.Concurrency {
+		changes = append(changes, fmt.Sprintf("batch.concurrency: %d -> %d", oldCfg.Batch.Concurrency, newCfg.Batch.Concurrency))
+	}
+	if oldCfg.Tracing.Enabled != newCfg.Tracing.Enabled {
+		changes = append(changes, fmt.Sprintf("tracing.enabled: %t -> %t", oldCfg.Tracing.Enabled, newCfg.Tracing.Enabled))
+	}
+	if oldCfg.Tracing.Exporter != newCfg.Tracing.Exporter || oldCfg.Tracing.Endpoint != newCfg.Tracing.Endpoint {
+		changes = append(changes, fmt.Sprintf("tracing: exporter %q -> %q, endpoint %q -> %q", oldCfg.Tracing.Exporter, newCfg.Tracing.Exporter, oldCfg.Tracing.Endpoint, newCfg.Tracing.Endpoint))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		changes = append(changes, fmt.Sprintf("model-fallbacks: updated (%d -> %d chains)", len(oldCfg.ModelFallbacks), len(newCfg.ModelFallbacks)))
+	}
 
 	// Remote management (never print the key)
 	if oldCfg.RemoteManagement.AllowRemote != newCfg.RemoteManagement.AllowRemote {
@@ -75,12 +75,22 @@ func describeOpenAICompatibilityUpdate(oldEntry, newEntry config.OpenAICompatibi
 	if !equalStringMap(oldEntry.Headers, newEntry.Headers) {
 		details = append(details, "headers updated")
 	}
+	if oldEntry.Protocol != newEntry.Protocol {
+		details = append(details, fmt.Sprintf("protocol %s -> %s", compatProtocolLabel(oldEntry.Protocol), compatProtocolLabel(newEntry.Protocol)))
+	}
 	if len(details) == 0 {
 		return ""
 	}
 	return "(" + strings.Join(details, ", ") + ")"
 }
 
+func compatProtocolLabel(protocol string) string {
+	if protocol == "" {
+		return "openai"
+	}
+	return protocol
+}
+
 func countAPIKeys(entry config.OpenAICompatibility) int {
 	count := 0
 	for _, keyEntry := range entry.APIKeyEntries {
@@ -142,6 +152,9 @@ func openAICompatSignature(entry config.OpenAICompatibility) string {
 	if v := strings.TrimSpace(entry.BaseURL); v != "" {
 		parts = append(parts, "base="+v)
 	}
+	if v := strings.TrimSpace(entry.Protocol); v != "" {
+		parts = append(parts, "protocol="+strings.ToLower(v))
+	}
 
 	models := make([]string, 0, len(entry.Models))
 	for _, model := range entry.Models {
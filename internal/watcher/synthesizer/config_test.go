@@ -225,6 +225,40 @@ func TestConfigSynthesizer_ClaudeKeys_SkipsEmptyAndHeaders(t *testing.T) {
 	}
 }
 
+func TestConfigSynthesizer_RoutingAttributes(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			ClaudeKey: []config.ClaudeKey{
+				{APIKey: "paid-key", Priority: 2, Weight: 3},
+				{APIKey: "default-key"},
+			},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 2 {
+		t.Fatalf("expected 2 auths, got %d", len(auths))
+	}
+	if auths[0].Attributes["priority"] != "2" || auths[0].Priority() != 2 {
+		t.Errorf("expected priority 2, got %q", auths[0].Attributes["priority"])
+	}
+	if auths[0].Attributes["weight"] != "3" || auths[0].Weight() != 3 {
+		t.Errorf("expected weight 3, got %q", auths[0].Attributes["weight"])
+	}
+	if _, ok := auths[1].Attributes["priority"]; ok {
+		t.Error("expected no priority attribute for default key")
+	}
+	if auths[1].Weight() != 1 {
+		t.Errorf("expected default weight 1, got %d", auths[1].Weight())
+	}
+}
+
 func TestConfigSynthesizer_CodexKeys(t *testing.T) {
 	synth := NewConfigSynthesizer()
 	ctx := &SynthesisContext{
@@ -365,6 +399,34 @@ func TestConfigSynthesizer_OpenAICompat(t *testing.T) {
 	}
 }
 
+func TestConfigSynthesizer_OpenAICompatProtocol(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			OpenAICompatibility: []config.OpenAICompatibility{
+				{Name: "deepseek", BaseURL: "https://api.deepseek.com/anthropic", Protocol: "claude", APIKeyEntries: []config.OpenAICompatibilityAPIKey{{APIKey: "k"}}},
+				{Name: "plain", BaseURL: "https://plain.api.com"},
+			},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 2 {
+		t.Fatalf("expected 2 auths, got %d", len(auths))
+	}
+	if got := auths[0].Attributes["protocol"]; got != "claude" {
+		t.Fatalf("protocol = %q, want claude", got)
+	}
+	if _, ok := auths[1].Attributes["protocol"]; ok {
+		t.Fatal("protocol attribute set for openai provider")
+	}
+}
+
 func TestConfigSynthesizer_VertexCompat(t *testing.T) {
 	synth := NewConfigSynthesizer()
 	ctx := &SynthesisContext{
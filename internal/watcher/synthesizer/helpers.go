@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -108,3 +109,49 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 		attrs["header:"+key] = val
 	}
 }
+
+// addRoutingAttrs records credential routing hints (priority, weight and max concurrency)
+// in auth attributes. Zero values are omitted so selectors fall back to their defaults.
+func addRoutingAttrs(priority, weight, maxConcurrency int, attrs map[string]string) {
+	if attrs == nil {
+		return
+	}
+	if priority != 0 {
+		attrs["priority"] = strconv.Itoa(priority)
+	}
+	if weight > 0 {
+		attrs["weight"] = strconv.Itoa(weight)
+	}
+	if maxConcurrency > 0 {
+		attrs["max_concurrency"] = strconv.Itoa(maxConcurrency)
+	}
+}
+
+// routingAttrsFromMetadata copies priority, weight and max_concurrency hints from auth file metadata.
+func routingAttrsFromMetadata(metadata map[string]any, attrs map[string]string) {
+	if metadata == nil || attrs == nil {
+		return
+	}
+	addRoutingAttrs(
+		intFromMetadata(metadata["priority"]),
+		intFromMetadata(metadata["weight"]),
+		intFromMetadata(metadata["max_concurrency"]),
+		attrs,
+	)
+}
+
+func intFromMetadata(raw any) int {
+	switch v := raw.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return 0
+}
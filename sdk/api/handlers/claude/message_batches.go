@@ -0,0 +1,210 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/sjson"
+)
+
+// CreateMessageBatch handles POST /v1/messages/batches. The requests are persisted and run
+// in the background; the returned batch is polled for progress.
+func (h *ClaudeCodeAPIHandler) CreateMessageBatch(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	requests, err := batch.ParseAnthropicRequests(rawJSON)
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := manager.Submit(&batch.Job{Format: batch.FormatAnthropic, HandlerType: Claude, Owner: handlers.ClientOwner(c)}, handlers.ClientAPIKey(c), requests)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// ListMessageBatches handles GET /v1/messages/batches, newest first. It supports the
+// limit (1-1000, default 20), before_id and after_id query parameters.
+func (h *ClaudeCodeAPIHandler) ListMessageBatches(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	jobs, err := manager.List(handlers.ClientOwner(c), batch.FormatAnthropic)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		if n, errAtoi := strconv.Atoi(raw); errAtoi == nil && n >= 1 && n <= 1000 {
+			limit = n
+		}
+	}
+	start, end := 0, len(jobs)
+	if afterID := c.Query("after_id"); afterID != "" {
+		for i, job := range jobs {
+			if job.ID == afterID {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if beforeID := c.Query("before_id"); beforeID != "" {
+		for i, job := range jobs {
+			if job.ID == beforeID {
+				end = i
+				break
+			}
+		}
+		if end-start > limit {
+			start = end - limit
+		}
+	}
+	if start > end {
+		start = end
+	}
+	hasMore := end-start > limit
+	if hasMore {
+		end = start + limit
+	}
+
+	out := `{"data":[],"has_more":false,"first_id":null,"last_id":null}`
+	for _, job := range jobs[start:end] {
+		out, _ = sjson.SetRaw(out, "data.-1", string(batch.AnthropicBatch(job, resultsURL(c, job.ID))))
+	}
+	if end > start {
+		out, _ = sjson.Set(out, "first_id", jobs[start].ID)
+		out, _ = sjson.Set(out, "last_id", jobs[end-1].ID)
+	}
+	out, _ = sjson.Set(out, "has_more", hasMore)
+	c.Data(http.StatusOK, "application/json", []byte(out))
+}
+
+// GetMessageBatch handles GET /v1/messages/batches/{id}.
+func (h *ClaudeCodeAPIHandler) GetMessageBatch(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	job, ok := loadMessageBatch(c, manager)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// CancelMessageBatch handles POST /v1/messages/batches/{id}/cancel.
+func (h *ClaudeCodeAPIHandler) CancelMessageBatch(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	job, ok := loadMessageBatch(c, manager)
+	if !ok {
+		return
+	}
+	job, err := manager.Cancel(handlers.ClientOwner(c), job.ID)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// MessageBatchResults handles GET /v1/messages/batches/{id}/results and streams the results
+// as JSONL once the batch has ended.
+func (h *ClaudeCodeAPIHandler) MessageBatchResults(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	job, results, err := manager.Results(handlers.ClientOwner(c), id)
+	if err != nil || job.Format != batch.FormatAnthropic {
+		switch {
+		case errors.Is(err, batch.ErrInProgress):
+			writeBatchError(c, http.StatusBadRequest, fmt.Sprintf("Batch %s has not ended yet.", id))
+		case err == nil || errors.Is(err, batch.ErrNotFound):
+			writeBatchError(c, http.StatusNotFound, fmt.Sprintf("Batch %s not found.", id))
+		default:
+			writeBatchError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	c.Data(http.StatusOK, "application/x-jsonl", batch.AnthropicResults(results))
+}
+
+// DeleteMessageBatch handles DELETE /v1/messages/batches/{id}. Only ended batches can be deleted.
+func (h *ClaudeCodeAPIHandler) DeleteMessageBatch(c *gin.Context) {
+	manager, ok := batchManager(c)
+	if !ok {
+		return
+	}
+	job, ok := loadMessageBatch(c, manager)
+	if !ok {
+		return
+	}
+	if err := manager.Delete(handlers.ClientOwner(c), job.ID); err != nil {
+		if errors.Is(err, batch.ErrInProgress) {
+			writeBatchError(c, http.StatusBadRequest, fmt.Sprintf("Batch %s must be canceled or ended before it can be deleted.", job.ID))
+			return
+		}
+		writeBatchError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": job.ID, "type": "message_batch_deleted"})
+}
+
+func batchManager(c *gin.Context) (*batch.Manager, bool) {
+	manager := batch.Default()
+	if manager == nil {
+		writeBatchError(c, http.StatusServiceUnavailable, "Message batches are not available.")
+		return nil, false
+	}
+	return manager, true
+}
+
+func loadMessageBatch(c *gin.Context, manager *batch.Manager) (*batch.Job, bool) {
+	id := c.Param("id")
+	job, err := manager.Get(handlers.ClientOwner(c), id)
+	if err == nil && job.Format == batch.FormatAnthropic {
+		return job, true
+	}
+	if err == nil || errors.Is(err, batch.ErrNotFound) {
+		writeBatchError(c, http.StatusNotFound, fmt.Sprintf("Batch %s not found.", id))
+	} else {
+		writeBatchError(c, http.StatusInternalServerError, err.Error())
+	}
+	return nil, false
+}
+
+func writeBatchError(c *gin.Context, status int, message string) {
+	c.JSON(status, claudeErrorResponse{
+		Type: "error",
+		Error: claudeErrorDetail{
+			Type:    batch.AnthropicErrorType(status),
+			Message: message,
+		},
+	})
+}
+
+// resultsURL builds the absolute results_url clients download results from.
+func resultsURL(c *gin.Context, id string) string {
+	return fmt.Sprintf("%s/v1/messages/batches/%s/results", handlers.RequestBaseURL(c), id)
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"golang.org/x/net/context"
+)
+
+const (
+	// overflowBytesPerToken is the rough ratio used to screen requests before asking the
+	// provider for an exact count.
+	overflowBytesPerToken = 4
+
+	// defaultKeepRecentTurns is the number of latest turns the summarize strategy keeps verbatim.
+	defaultKeepRecentTurns = 4
+
+	// summaryPrompt instructs the summarizer model.
+	summaryPrompt = "Summarize the following conversation excerpt so it can replace the original turns. " +
+		"Keep facts, decisions, open questions, file names, identifiers and tool results that later turns may rely on. " +
+		"Answer with the summary only."
+)
+
+// overflowLayout describes where a request format keeps its conversation turns.
+type overflowLayout struct {
+	// turns is the JSON path of the turn array.
+	turns string
+	// isSystem reports inline system turns, which are never dropped.
+	isSystem func(turn gjson.Result) bool
+	// startsTurn reports turns a shortened history may begin with: plain user input rather
+	// than an assistant reply or a tool result whose call was dropped.
+	startsTurn func(turn gjson.Result) bool
+	// summaryTurn builds a user turn carrying text.
+	summaryTurn func(text string) []byte
+}
+
+// overflowTurn is one entry of the turn array with its estimated size.
+type overflowTurn struct {
+	raw    string
+	tokens int
+	system bool
+	start  bool
+}
+
+func openAISystemRole(turn gjson.Result) bool {
+	role := turn.Get("role").String()
+	return role == "system" || role == "developer"
+}
+
+func geminiStartsTurn(turn gjson.Result) bool {
+	if role := turn.Get("role").String(); role != "" && role != "user" {
+		return false
+	}
+	for _, part := range turn.Get("parts").Array() {
+		if part.Get("functionResponse").Exists() {
+			return false
+		}
+	}
+	return true
+}
+
+func geminiSummaryTurn(text string) []byte {
+	out, _ := sjson.SetBytes([]byte(`{"role":"user","parts":[{"text":""}]}`), "parts.0.text", text)
+	return out
+}
+
+// overflowLayoutFor returns the turn layout of an inbound request format.
+func overflowLayoutFor(handlerType string) (overflowLayout, bool) {
+	switch handlerType {
+	case "openai":
+		return overflowLayout{
+			turns:    "messages",
+			isSystem: openAISystemRole,
+			startsTurn: func(turn gjson.Result) bool {
+				return turn.Get("role").String() == "user"
+			},
+			summaryTurn: func(text string) []byte {
+				out, _ := sjson.SetBytes([]byte(`{"role":"user","content":""}`), "content", text)
+				return out
+			},
+		}, true
+	case "openai-response":
+		return overflowLayout{
+			turns:    "input",
+			isSystem: openAISystemRole,
+			startsTurn: func(turn gjson.Result) bool {
+				itemType := turn.Get("type").String()
+				return (itemType == "" || itemType == "message") && turn.Get("role").String() == "user"
+			},
+			summaryTurn: func(text string) []byte {
+				out, _ := sjson.SetBytes([]byte(`{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`), "content.0.text", text)
+				return out
+			},
+		}, true
+	case "claude":
+		return overflowLayout{
+			turns:    "messages",
+			isSystem: func(gjson.Result) bool { return false },
+			startsTurn: func(turn gjson.Result) bool {
+				if turn.Get("role").String() != "user" {
+					return false
+				}
+				for _, block := range turn.Get("content").Array() {
+					if block.Get("type").String() == "tool_result" {
+						return false
+					}
+				}
+				return true
+			},
+			summaryTurn: func(text string) []byte {
+				out, _ := sjson.SetBytes([]byte(`{"role":"user","content":[{"type":"text","text":""}]}`), "content.0.text", text)
+				return out
+			},
+		}, true
+	case "gemini":
+		return overflowLayout{
+			turns:       "contents",
+			isSystem:    func(gjson.Result) bool { return false },
+			startsTurn:  geminiStartsTurn,
+			summaryTurn: geminiSummaryTurn,
+		}, true
+	case "gemini-cli":
+		return overflowLayout{
+			turns:       "request.contents",
+			isSystem:    func(gjson.Result) bool { return false },
+			startsTurn:  geminiStartsTurn,
+			summaryTurn: geminiSummaryTurn,
+		}, true
+	default:
+		return overflowLayout{}, false
+	}
+}
+
+// contextOverflowRule returns the first overflow rule matching modelName, or nil.
+func (h *BaseAPIHandler) contextOverflowRule(modelName string) *config.ContextOverflowRule {
+	if h.Cfg == nil {
+		return nil
+	}
+	for i := range h.Cfg.ContextOverflow {
+		rule := &h.Cfg.ContextOverflow[i]
+		for _, pattern := range rule.Models {
+			if matchOverflowModel(pattern, modelName) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// matchOverflowModel reports whether model matches pattern, where "*" matches any run of characters.
+func matchOverflowModel(pattern, model string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "*") {
+		return strings.EqualFold(pattern, model)
+	}
+	expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	matched, err := regexp.MatchString(expr, model)
+	return err == nil && matched
+}
+
+// contextInputLimit returns the number of input tokens the model accepts for this request,
+// or 0 when the registry does not know the model's window.
+func contextInputLimit(modelName string, rawJSON []byte) int {
+	info := registry.GetGlobalRegistry().GetModelInfo(modelName)
+	if info == nil {
+		return 0
+	}
+	if info.InputTokenLimit > 0 {
+		return info.InputTokenLimit
+	}
+	if info.ContextLength <= 0 {
+		return 0
+	}
+	output := 0
+	for _, path := range []string{"max_tokens", "max_completion_tokens", "max_output_tokens", "generationConfig.maxOutputTokens", "request.generationConfig.maxOutputTokens"} {
+		if value := gjson.GetBytes(rawJSON, path); value.Exists() {
+			output = int(value.Int())
+			break
+		}
+	}
+	return info.ContextLength - output
+}
+
+// applyContextOverflow enforces the overflow rule configured for modelName before the
+// request is dispatched. Requests are first screened with a byte-based estimate; only those
+// near the limit are counted exactly by the provider. It returns the possibly shortened body.
+func (h *BaseAPIHandler) applyContextOverflow(ctx context.Context, handlerType, modelName, normalizedModel string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	rule := h.contextOverflowRule(modelName)
+	if rule == nil {
+		return rawJSON, nil
+	}
+	layout, ok := overflowLayoutFor(handlerType)
+	if !ok {
+		return rawJSON, nil
+	}
+	limit := contextInputLimit(normalizedModel, rawJSON) - rule.ReserveTokens
+	if limit <= 0 {
+		return rawJSON, nil
+	}
+	estimate := len(rawJSON) / overflowBytesPerToken
+	if estimate <= limit/2 {
+		return rawJSON, nil
+	}
+	total := estimate
+	if counted, okCount := h.countRequestTokens(ctx, handlerType, modelName, rawJSON, alt); okCount {
+		total = counted
+	}
+	if total <= limit {
+		return rawJSON, nil
+	}
+
+	strategy := strings.ToLower(strings.TrimSpace(rule.Strategy))
+	turnsResult := gjson.GetBytes(rawJSON, layout.turns)
+	if strategy == config.ContextOverflowReject || !turnsResult.IsArray() {
+		return nil, contextOverflowError(limit, total, "")
+	}
+
+	// Per-turn sizes are byte estimates scaled to the exact total.
+	scale := float64(total) / float64(max(estimate, 1))
+	var turns []overflowTurn
+	for _, turn := range turnsResult.Array() {
+		turns = append(turns, overflowTurn{
+			raw:    turn.Raw,
+			tokens: int(float64(len(turn.Raw)/overflowBytesPerToken) * scale),
+			system: layout.isSystem(turn),
+			start:  layout.startsTurn(turn),
+		})
+	}
+
+	action := "truncated"
+	if strategy == config.ContextOverflowSummarize {
+		if summarized, newTotal, errSummary := h.summarizeTurns(ctx, layout, rule, turns, total); errSummary != nil {
+			log.Warnf("context overflow: summarizing history for %s failed, truncating instead: %v", modelName, errSummary)
+		} else {
+			turns, total = summarized, newTotal
+			action = "summarized"
+		}
+	}
+	before := len(turns)
+	turns, total = truncateTurns(turns, total, limit)
+	if total > limit {
+		return nil, contextOverflowError(limit, total, " after removing earlier turns")
+	}
+	if len(turns) < before && action == "summarized" {
+		action = "summarized,truncated"
+	}
+
+	raws := make([]json.RawMessage, 0, len(turns))
+	for _, turn := range turns {
+		raws = append(raws, json.RawMessage(turn.raw))
+	}
+	encoded, err := json.Marshal(raws)
+	if err != nil {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: err}
+	}
+	out, err := sjson.SetRawBytes(rawJSON, layout.turns, encoded)
+	if err != nil {
+		return nil, &interfaces.ErrorMessage{StatusCode: http.StatusInternalServerError, Error: err}
+	}
+	log.Infof("context overflow: %s history for %s to about %d of %d tokens", action, modelName, total, limit)
+	if ginCtx, okGin := ctx.Value("gin").(*gin.Context); okGin && ginCtx != nil {
+		ginCtx.Header("X-Context-Overflow", action)
+	}
+	return out, nil
+}
+
+// countRequestTokens asks the selected provider for the exact input size of the request.
+func (h *BaseAPIHandler) countRequestTokens(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (int, bool) {
+	resp, errMsg := h.ExecuteCountWithAuthManager(ctx, handlerType, modelName, rawJSON, alt)
+	if errMsg != nil {
+		log.Debugf("context overflow: token count for %s unavailable: %v", modelName, errMsg.Error)
+		return 0, false
+	}
+	for _, path := range []string{"input_tokens", "totalTokens", "usage.prompt_tokens", "usage.input_tokens", "response.usage.input_tokens"} {
+		if value := gjson.GetBytes(resp, path); value.Exists() {
+			return int(value.Int()), true
+		}
+	}
+	return 0, false
+}
+
+// truncateTurns drops the oldest non-system turns until total fits limit. The last turn is
+// always kept and the remaining history starts at a plain user turn.
+func truncateTurns(turns []overflowTurn, total, limit int) ([]overflowTurn, int) {
+	last := len(turns) - 1
+	dropped := make([]bool, len(turns))
+	for i := 0; i < last && total > limit; i++ {
+		if turns[i].system {
+			continue
+		}
+		dropped[i] = true
+		total -= turns[i].tokens
+	}
+	// Skip past assistant replies and tool results whose originating turn is gone.
+	anyDropped := false
+	for i := range dropped {
+		anyDropped = anyDropped || dropped[i]
+	}
+	if anyDropped {
+		for i := 0; i < last; i++ {
+			if turns[i].system || dropped[i] {
+				continue
+			}
+			if turns[i].start {
+				break
+			}
+			dropped[i] = true
+			total -= turns[i].tokens
+		}
+	}
+	kept := make([]overflowTurn, 0, len(turns))
+	for i := range turns {
+		if !dropped[i] {
+			kept = append(kept, turns[i])
+		}
+	}
+	return kept, total
+}
+
+// summarizeTurns replaces the turns between the system prompt and the latest turns with a
+// summary written by the rule's summary model.
+func (h *BaseAPIHandler) summarizeTurns(ctx context.Context, layout overflowLayout, rule *config.ContextOverflowRule, turns []overflowTurn, total int) ([]overflowTurn, int, error) {
+	summaryModel := strings.TrimSpace(rule.SummaryModel)
+	if summaryModel == "" {
+		return nil, 0, errors.New("summary-model is not configured")
+	}
+	keepRecent := rule.KeepRecentTurns
+	if keepRecent <= 0 {
+		keepRecent = defaultKeepRecentTurns
+	}
+
+	var candidates []int
+	for i := range turns {
+		if !turns[i].system {
+			candidates = append(candidates, i)
+		}
+	}
+	cut := len(candidates) - keepRecent
+	// The verbatim tail must begin at a plain user turn.
+	for cut > 0 && !turns[candidates[cut]].start {
+		cut--
+	}
+	if cut <= 0 {
+		return nil, 0, errors.New("no earlier turns to summarize")
+	}
+	middle := candidates[:cut]
+
+	var transcript strings.Builder
+	middleTokens := 0
+	for _, idx := range middle {
+		turn := gjson.Parse(turns[idx].raw)
+		role := turn.Get("role").String()
+		if role == "" {
+			role = turn.Get("type").String()
+		}
+		var parts []string
+		collectTurnText(turn, &parts)
+		fmt.Fprintf(&transcript, "[%s]\n%s\n\n", role, strings.Join(parts, "\n"))
+		middleTokens += turns[idx].tokens
+	}
+
+	request := []byte(`{"messages":[{"role":"system","content":""},{"role":"user","content":""}]}`)
+	request, _ = sjson.SetBytes(request, "model", summaryModel)
+	request, _ = sjson.SetBytes(request, "messages.0.content", summaryPrompt)
+	request, _ = sjson.SetBytes(request, "messages.1.content", transcript.String())
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, "openai", summaryModel, request, "")
+	if errMsg != nil {
+		return nil, 0, errMsg.Error
+	}
+	summary := strings.TrimSpace(gjson.GetBytes(resp, "choices.0.message.content").String())
+	if summary == "" {
+		return nil, 0, errors.New("summary model returned no text")
+	}
+
+	summaryRaw := string(layout.summaryTurn("Summary of the earlier conversation:\n" + summary))
+	summaryTokens := len(summaryRaw) / overflowBytesPerToken
+	out := make([]overflowTurn, 0, len(turns)-len(middle)+1)
+	inMiddle := make(map[int]bool, len(middle))
+	for _, idx := range middle {
+		inMiddle[idx] = true
+	}
+	for i := range turns {
+		if i == middle[0] {
+			out = append(out, overflowTurn{raw: summaryRaw, tokens: summaryTokens, start: true})
+		}
+		if !inMiddle[i] {
+			out = append(out, turns[i])
+		}
+	}
+	return out, total - middleTokens + summaryTokens, nil
+}
+
+// collectTurnText gathers the readable text of a turn in any of the supported formats.
+func collectTurnText(value gjson.Result, parts *[]string) {
+	switch {
+	case value.IsArray():
+		for _, item := range value.Array() {
+			collectTurnText(item, parts)
+		}
+	case value.IsObject():
+		value.ForEach(func(key, item gjson.Result) bool {
+			switch key.String() {
+			case "text", "content", "output", "arguments", "result":
+				if item.Type == gjson.String {
+					if text := strings.TrimSpace(item.String()); text != "" {
+						*parts = append(*parts, text)
+					}
+					return true
+				}
+			case "input", "args", "response":
+				if item.IsObject() {
+					*parts = append(*parts, item.Raw)
+					return true
+				}
+			case "data", "image_url", "source", "inlineData", "fileData":
+				return true
+			}
+			collectTurnText(item, parts)
+			return true
+		})
+	}
+}
+
+// contextOverflowError builds the 400 returned when a request cannot fit the model's window.
+func contextOverflowError(limit, total int, detail string) *interfaces.ErrorMessage {
+	message := fmt.Sprintf("This model's maximum context length is %d tokens, but the request has about %d input tokens%s. Please shorten the conversation.", limit, total, detail)
+	body, _ := json.Marshal(ErrorResponse{Error: ErrorDetail{
+		Message: message,
+		Type:    "invalid_request_error",
+		Code:    "context_length_exceeded",
+	}})
+	return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: errors.New(string(body))}
+}
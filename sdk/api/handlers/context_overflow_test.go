@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// overflowExecutor counts one token per four payload bytes and answers every request with
+// a fixed summary.
+type overflowExecutor struct{}
+
+func (overflowExecutor) Identifier() string { return "overflow-test" }
+
+func (overflowExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(`{"choices":[{"message":{"role":"assistant","content":"the user asked about apples"}}]}`)}, nil
+}
+
+func (overflowExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "ExecuteStream not implemented"}
+}
+
+func (overflowExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (overflowExecutor) CountTokens(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(fmt.Sprintf(`{"input_tokens":%d}`, len(req.Payload)/4))}, nil
+}
+
+func newOverflowHandler(t *testing.T, rule sdkconfig.ContextOverflowRule) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(overflowExecutor{})
+	auth := &coreauth.Auth{ID: "overflow-auth", Provider: "overflow-test", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{
+		{ID: "small-model", InputTokenLimit: 200},
+		{ID: "summary-model"},
+	})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{ContextOverflow: []sdkconfig.ContextOverflowRule{rule}}, manager)
+}
+
+func longChat() []byte {
+	filler := strings.Repeat("a", 300)
+	return []byte(`{"model":"small-model","messages":[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":"` + filler + `"},
+		{"role":"assistant","content":"` + filler + `"},
+		{"role":"user","content":"` + filler + `"},
+		{"role":"assistant","content":"ok"},
+		{"role":"user","content":"latest question"}]}`)
+}
+
+func TestApplyContextOverflow_Truncate(t *testing.T) {
+	h := newOverflowHandler(t, sdkconfig.ContextOverflowRule{Models: []string{"small-*"}, Strategy: "truncate"})
+
+	out, errMsg := h.applyContextOverflow(context.Background(), "openai", "small-model", "small-model", longChat(), "")
+	if errMsg != nil {
+		t.Fatalf("applyContextOverflow error: %v", errMsg.Error)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if messages[0].Get("role").String() != "system" || messages[1].Get("role").String() != "user" {
+		t.Fatalf("messages = %s, want system prompt followed by a user turn", gjson.GetBytes(out, "messages").Raw)
+	}
+	if last := messages[len(messages)-1].Get("content").String(); last != "latest question" {
+		t.Fatalf("last message = %q", last)
+	}
+	if len(out)/4 > 200 {
+		t.Fatalf("request still has %d tokens", len(out)/4)
+	}
+}
+
+func TestApplyContextOverflow_Summarize(t *testing.T) {
+	h := newOverflowHandler(t, sdkconfig.ContextOverflowRule{Models: []string{"small-model"}, Strategy: "summarize", SummaryModel: "summary-model", KeepRecentTurns: 1})
+
+	out, errMsg := h.applyContextOverflow(context.Background(), "openai", "small-model", "small-model", longChat(), "")
+	if errMsg != nil {
+		t.Fatalf("applyContextOverflow error: %v", errMsg.Error)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %s, want system, summary and latest turn", gjson.GetBytes(out, "messages").Raw)
+	}
+	if summary := messages[1].Get("content").String(); !strings.Contains(summary, "the user asked about apples") {
+		t.Fatalf("summary turn = %q", summary)
+	}
+}
+
+func TestApplyContextOverflow_Reject(t *testing.T) {
+	h := newOverflowHandler(t, sdkconfig.ContextOverflowRule{Models: []string{"small-model"}, Strategy: "reject"})
+
+	_, errMsg := h.applyContextOverflow(context.Background(), "openai", "small-model", "small-model", longChat(), "")
+	if errMsg == nil || errMsg.StatusCode != 400 {
+		t.Fatalf("expected 400, got %+v", errMsg)
+	}
+	if code := gjson.Get(errMsg.Error.Error(), "error.code").String(); code != "context_length_exceeded" {
+		t.Fatalf("error code = %q", code)
+	}
+
+	// Requests that fit are forwarded unchanged.
+	small := []byte(`{"model":"small-model","messages":[{"role":"user","content":"hi"}]}`)
+	out, errMsg := h.applyContextOverflow(context.Background(), "openai", "small-model", "small-model", small, "")
+	if errMsg != nil || string(out) != string(small) {
+		t.Fatalf("small request changed: %s %+v", out, errMsg)
+	}
+}
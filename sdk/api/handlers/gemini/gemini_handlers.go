@@ -17,6 +17,8 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 // GeminiAPIHandler contains the handlers for Gemini API endpoints.
@@ -211,9 +213,55 @@ func (h *GeminiAPIHandler) GeminiHandler(c *gin.Context) {
 		h.handleStreamGenerateContent(c, action[0], rawJSON)
 	case "countTokens":
 		h.handleCountTokens(c, action[0], rawJSON)
+	case "embedContent":
+		h.handleEmbedContent(c, action[0], rawJSON, false)
+	case "batchEmbedContents":
+		h.handleEmbedContent(c, action[0], rawJSON, true)
 	}
 }
 
+// handleEmbedContent handles embedContent and batchEmbedContents requests for Gemini models.
+// A single embedContent request is wrapped into the batch form used internally and the
+// first embedding is unwrapped from the batch response.
+//
+// Parameters:
+//   - c: The Gin context for the request
+//   - modelName: The name of the embedding model
+//   - rawJSON: The raw JSON request body
+//   - batch: Whether the request uses the batchEmbedContents shape
+func (h *GeminiAPIHandler) handleEmbedContent(c *gin.Context, modelName string, rawJSON []byte, batch bool) {
+	if !registry.GetGlobalRegistry().GetModelInfo(modelName).SupportsEmbeddings() {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("model %q is not an embedding model", modelName),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	c.Header("Content-Type", "application/json")
+	payload := rawJSON
+	if !batch {
+		payload, _ = sjson.SetRawBytes([]byte(`{"requests":[]}`), "requests.-1", rawJSON)
+	}
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, GeminiEmbedding, modelName, payload, "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	if !batch {
+		single := `{"embedding":{"values":[]}}`
+		if embedding := gjson.GetBytes(resp, "embeddings.0"); embedding.Exists() {
+			single, _ = sjson.SetRaw(single, "embedding", embedding.Raw)
+		}
+		resp = []byte(single)
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
 // handleStreamGenerateContent handles streaming content generation requests for Gemini models.
 // This function establishes a Server-Sent Events connection and streams the generated content
 // back to the client in real-time. It supports both SSE format and direct streaming based
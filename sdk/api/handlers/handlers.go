@@ -5,6 +5,8 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,11 +17,14 @@ import (
 	"github.com/google/uuid"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsecache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
 	"golang.org/x/net/context"
 )
 
@@ -125,19 +130,47 @@ func StreamingBootstrapRetries(cfg *config.SDKConfig) int {
 	return retries
 }
 
-func requestExecutionMetadata(ctx context.Context) map[string]any {
+func requestExecutionMetadata(ctx context.Context, rawJSON []byte) map[string]any {
 	// Idempotency-Key is an optional client-supplied header used to correlate retries.
 	// It is forwarded as execution metadata; when absent we generate a UUID.
 	key := ""
+	var ginCtx *gin.Context
 	if ctx != nil {
-		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		if gc, ok := ctx.Value("gin").(*gin.Context); ok && gc != nil && gc.Request != nil {
+			ginCtx = gc
 			key = strings.TrimSpace(ginCtx.GetHeader("Idempotency-Key"))
 		}
 	}
 	if key == "" {
 		key = uuid.NewString()
 	}
-	return map[string]any{idempotencyKeyMetadataKey: key}
+	meta := map[string]any{idempotencyKeyMetadataKey: key}
+	if session := requestSessionKey(ginCtx, rawJSON); session != "" {
+		meta[coreauth.SessionKeyMetadataKey] = session
+	}
+	return meta
+}
+
+// requestSessionKey derives the client session identifier used for session-sticky routing.
+// An explicit X-Session-Id (or Codex session_id) header wins over identifiers embedded in
+// the payload: Claude metadata.user_id and the OpenAI/Codex prompt_cache_key.
+func requestSessionKey(ginCtx *gin.Context, rawJSON []byte) string {
+	if ginCtx != nil {
+		for _, header := range []string{"X-Session-Id", "Session_id"} {
+			if value := strings.TrimSpace(ginCtx.GetHeader(header)); value != "" {
+				return value
+			}
+		}
+	}
+	if len(rawJSON) == 0 {
+		return ""
+	}
+	for _, path := range []string{"metadata.user_id", "prompt_cache_key"} {
+		if value := strings.TrimSpace(gjson.GetBytes(rawJSON, path).String()); value != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 func mergeMetadata(base, overlay map[string]any) map[string]any {
@@ -211,6 +244,55 @@ func (h *BaseAPIHandler) GetAlt(c *gin.Context) string {
 	return alt
 }
 
+// ClientAPIKey returns the API key the client authenticated with, or "" when the request
+// was not authenticated with one.
+func ClientAPIKey(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	value, exists := c.Get("apiKey")
+	if !exists || value == nil {
+		return ""
+	}
+	if key, ok := value.(string); ok {
+		return key
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// ClientOwner returns an opaque identifier of the authenticated client, derived from its
+// API key, for scoping resources stored on the client's behalf. Unauthenticated requests
+// share the empty owner.
+func ClientOwner(c *gin.Context) string {
+	return APIKeyOwner(ClientAPIKey(c))
+}
+
+// APIKeyOwner returns the owner identifier ClientOwner reports for apiKey.
+func APIKeyOwner(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestBaseURL returns the scheme and host the client used to reach the server, honouring
+// X-Forwarded-Proto and X-Forwarded-Host set by reverse proxies.
+func RequestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := strings.TrimSpace(strings.Split(c.GetHeader("X-Forwarded-Proto"), ",")[0]); forwarded != "" {
+		scheme = forwarded
+	}
+	host := c.Request.Host
+	if forwardedHost := strings.TrimSpace(strings.Split(c.GetHeader("X-Forwarded-Host"), ",")[0]); forwardedHost != "" {
+		host = forwardedHost
+	}
+	return scheme + "://" + host
+}
+
 // GetContextWithCancel creates a new context with cancellation capabilities.
 // It embeds the Gin context and the API handler into the new context for later use.
 // The returned cancel function also handles logging the API response if request logging is enabled.
@@ -241,6 +323,9 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
+	if requestCtx != nil {
+		parentCtx = tracing.Inherit(parentCtx, requestCtx)
+	}
 	newCtx, cancel := context.WithCancel(parentCtx)
 	if requestCtx != nil && requestCtx != parentCtx {
 		go func() {
@@ -253,6 +338,7 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 	}
 	newCtx = context.WithValue(newCtx, "gin", c)
 	newCtx = context.WithValue(newCtx, "handler", handler)
+	newCtx = coreexecutor.WithClientAPIKey(newCtx, ClientAPIKey(c))
 	return newCtx, func(params ...interface{}) {
 		if h.Cfg.RequestLog && len(params) == 1 {
 			if existing, exists := c.Get("API_RESPONSE"); exists {
@@ -323,7 +409,15 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 	if errMsg != nil {
 		return nil, errMsg
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	rawJSON, errMsg = h.applyContextOverflow(ctx, handlerType, modelName, normalizedModel, rawJSON, alt)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	cacheSlot := responseCacheFor(ctx, handlerType, normalizedModel, metadata, rawJSON, alt, false)
+	if entry := cacheSlot.get(ctx); entry != nil {
+		return cloneBytes(entry.Body), nil
+	}
+	reqMeta := requestExecutionMetadata(ctx, rawJSON)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -354,6 +448,7 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		}
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
+	cacheSlot.put(ctx, &responsecache.Entry{Body: cloneBytes(resp.Payload)})
 	return cloneBytes(resp.Payload), nil
 }
 
@@ -364,7 +459,7 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	if errMsg != nil {
 		return nil, errMsg
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta := requestExecutionMetadata(ctx, rawJSON)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -408,7 +503,18 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		close(errChan)
 		return nil, errChan
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	rawJSON, errMsg = h.applyContextOverflow(ctx, handlerType, modelName, normalizedModel, rawJSON, alt)
+	if errMsg != nil {
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
+	cacheSlot := responseCacheFor(ctx, handlerType, normalizedModel, metadata, rawJSON, alt, true)
+	if entry := cacheSlot.get(ctx); entry != nil {
+		return replayStream(ctx, entry)
+	}
+	reqMeta := requestExecutionMetadata(ctx, rawJSON)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -448,6 +554,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		defer close(dataChan)
 		defer close(errChan)
 		sentPayload := false
+		recording := cacheSlot.recording()
 		bootstrapRetries := 0
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
 
@@ -480,6 +587,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					chunk, ok = <-chunks
 				}
 				if !ok {
+					recording.put(ctx)
 					return
 				}
 				if chunk.Err != nil {
@@ -515,6 +623,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				}
 				if len(chunk.Payload) > 0 {
 					sentPayload = true
+					recording.add(chunk.Payload)
 					dataChan <- cloneBytes(chunk.Payload)
 				}
 			}
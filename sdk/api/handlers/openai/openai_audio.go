@@ -0,0 +1,180 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// speechContentTypes maps speech response formats to the content type of raw audio bytes
+// returned by providers that implement the Audio API natively.
+var speechContentTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/opus",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+	"pcm":  "audio/pcm",
+}
+
+// AudioTranscriptions handles POST /v1/audio/transcriptions. The multipart upload is
+// converted into a JSON request whose "file" object carries the audio as base64.
+func (h *OpenAIAPIHandler) AudioTranscriptions(c *gin.Context) {
+	form, rawJSON, err := parseMultipartRequest(c, []byte(`{}`), "temperature")
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		writeOpenAIError(c, http.StatusBadRequest, "file is required", "invalid_request_error")
+		return
+	}
+	file, err := encodeUploadedFile(files[0], "audio")
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+	rawJSON, _ = sjson.SetRawBytes(rawJSON, "file", file)
+
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, OpenAITranscription, modelName, rawJSON, "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+
+	switch gjson.GetBytes(rawJSON, "response_format").String() {
+	case "text", "srt", "vtt":
+		if gjson.ValidBytes(resp) {
+			resp = []byte(gjson.GetBytes(resp, "text").String())
+		}
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", resp)
+	default:
+		if !gjson.ValidBytes(resp) {
+			resp, _ = sjson.SetBytes([]byte(`{}`), "text", string(resp))
+		}
+		c.Data(http.StatusOK, "application/json", resp)
+	}
+	cliCancel()
+}
+
+// AudioSpeech handles POST /v1/audio/speech and answers with raw audio bytes. Gemini returns
+// 16-bit PCM, so Gemini models only accept response_format "wav" (the default) and "pcm";
+// other formats are rejected rather than mislabelled.
+func (h *OpenAIAPIHandler) AudioSpeech(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if strings.TrimSpace(gjson.GetBytes(rawJSON, "input").String()) == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "input is required", "invalid_request_error")
+		return
+	}
+
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	format := gjson.GetBytes(rawJSON, "response_format").String()
+	if info := registry.GetGlobalRegistry().GetModelInfo(modelName); info != nil && info.Type == "gemini" {
+		if format == "" {
+			format = "wav"
+		}
+		if format != "wav" && format != "pcm" {
+			writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("response_format %q is not supported by model %q; use wav or pcm", format, modelName), "invalid_request_error")
+			return
+		}
+	} else if format == "" {
+		format = "mp3"
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, OpenAISpeech, modelName, rawJSON, "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+
+	audio := gjson.GetBytes(resp, "audio")
+	if !gjson.ValidBytes(resp) || !audio.Exists() {
+		contentType := speechContentTypes[format]
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Data(http.StatusOK, contentType, resp)
+		cliCancel()
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(audio.Get("data").String())
+	if err != nil || len(data) == 0 {
+		writeOpenAIError(c, http.StatusBadGateway, "the model did not return audio", "server_error")
+		cliCancel(err)
+		return
+	}
+	mimeType := audio.Get("mime_type").String()
+	sampleRate, isPCM := pcmSampleRate(mimeType)
+	switch {
+	case isPCM && format == "pcm":
+		c.Data(http.StatusOK, "audio/pcm", data)
+	case isPCM:
+		c.Data(http.StatusOK, "audio/wav", pcmToWAV(data, sampleRate))
+	default:
+		c.Data(http.StatusOK, mimeType, data)
+	}
+	cliCancel()
+}
+
+// pcmSampleRate reports whether mimeType describes raw 16-bit PCM, such as Gemini's
+// "audio/L16;codec=pcm;rate=24000", and returns its sample rate.
+func pcmSampleRate(mimeType string) (int, bool) {
+	params := strings.Split(strings.ToLower(mimeType), ";")
+	if base := strings.TrimSpace(params[0]); base != "audio/l16" && base != "audio/pcm" {
+		return 0, false
+	}
+	rate := 24000
+	for _, param := range params[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && key == "rate" {
+			if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+				rate = parsed
+			}
+		}
+	}
+	return rate, true
+}
+
+// pcmToWAV wraps mono 16-bit little-endian PCM samples in a WAV header.
+func pcmToWAV(pcm []byte, sampleRate int) []byte {
+	const channels, bitsPerSample = 1, 16
+	byteRate := sampleRate * channels * bitsPerSample / 8
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(channels*bitsPerSample/8))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
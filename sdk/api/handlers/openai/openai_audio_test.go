@@ -0,0 +1,30 @@
+package openai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+)
+
+func TestAudioSpeechRejectsFormatsGeminiCannotProduce(t *testing.T) {
+	registry.GetGlobalRegistry().RegisterClient("speech-test", "gemini", []*registry.ModelInfo{{ID: "speech-test-tts", Type: "gemini"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("speech-test") })
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIAPIHandler{}
+	router := gin.New()
+	router.POST("/v1/audio/speech", h.AudioSpeech)
+
+	for _, format := range []string{"mp3", "opus", "flac"} {
+		rec := httptest.NewRecorder()
+		body := `{"model":"speech-test-tts","input":"hi","response_format":"` + format + `"}`
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || gjson.Get(rec.Body.String(), "error.type").String() != "invalid_request_error" {
+			t.Fatalf("%s: %d %s, want a 400 invalid_request_error", format, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// UploadFile handles POST /v1/files. Only multipart uploads with purpose "batch" are
+// accepted, since files exist to feed the Batch API.
+func (h *OpenAIAPIHandler) UploadFile(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	purpose := strings.TrimSpace(c.PostForm("purpose"))
+	if purpose != "batch" {
+		writeOpenAIError(c, http.StatusBadRequest, "purpose must be \"batch\".", "invalid_request_error")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("file is required: %v", err), "invalid_request_error")
+		return
+	}
+	src, err := header.Open()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("read file: %v", err), "invalid_request_error")
+		return
+	}
+	data, err := io.ReadAll(src)
+	_ = src.Close()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("read file: %v", err), "invalid_request_error")
+		return
+	}
+	file, err := manager.CreateFile(handlers.ClientOwner(c), header.Filename, purpose, data)
+	if err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.OpenAIFile(file))
+}
+
+// ListFiles handles GET /v1/files. It supports the purpose, limit (1-10000, default 10000)
+// and after query parameters.
+func (h *OpenAIAPIHandler) ListFiles(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	files, err := manager.Files(handlers.ClientOwner(c), c.Query("purpose"))
+	if err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	items := make([][]byte, 0, len(files))
+	for _, file := range files {
+		items = append(items, batch.OpenAIFile(file))
+	}
+	page, hasMore := paginate(items, c.Query("after"), queryLimit(c, 10000, 10000))
+	c.Data(http.StatusOK, "application/json", batch.OpenAIList(page, hasMore))
+}
+
+// GetFile handles GET /v1/files/{id}.
+func (h *OpenAIAPIHandler) GetFile(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	file, err := manager.File(handlers.ClientOwner(c), c.Param("id"))
+	if err != nil {
+		writeFileLookupError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.OpenAIFile(file))
+}
+
+// GetFileContent handles GET /v1/files/{id}/content.
+func (h *OpenAIAPIHandler) GetFileContent(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	_, data, err := manager.FileContent(handlers.ClientOwner(c), c.Param("id"))
+	if err != nil {
+		writeFileLookupError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// DeleteFile handles DELETE /v1/files/{id}.
+func (h *OpenAIAPIHandler) DeleteFile(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	if err := manager.DeleteFile(handlers.ClientOwner(c), id); err != nil {
+		writeFileLookupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "file", "deleted": true})
+}
+
+// CreateBatch handles POST /v1/batches. The input file is validated up front and its
+// requests run in the background against the batch endpoint.
+func (h *OpenAIAPIHandler) CreateBatch(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	root := gjson.ParseBytes(rawJSON)
+	endpoint := root.Get("endpoint").String()
+	handlerType, supported := batch.OpenAIHandlerType(endpoint)
+	if !supported {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Unsupported endpoint %q.", endpoint), "invalid_request_error")
+		return
+	}
+	if window := root.Get("completion_window").String(); window != "" && window != "24h" {
+		writeOpenAIError(c, http.StatusBadRequest, "completion_window must be \"24h\".", "invalid_request_error")
+		return
+	}
+	inputFileID := root.Get("input_file_id").String()
+	_, data, err := manager.FileContent(handlers.ClientOwner(c), inputFileID)
+	if err != nil {
+		writeFileLookupError(c, err)
+		return
+	}
+	requests, err := batch.ParseOpenAIInput(data, endpoint)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid input file: %v", err), "invalid_request_error")
+		return
+	}
+	job := &batch.Job{
+		Format:      batch.FormatOpenAI,
+		HandlerType: handlerType,
+		Endpoint:    endpoint,
+		InputFileID: inputFileID,
+		Owner:       handlers.ClientOwner(c),
+	}
+	if metadata := root.Get("metadata"); metadata.IsObject() {
+		job.Metadata = make(map[string]string)
+		metadata.ForEach(func(key, value gjson.Result) bool {
+			job.Metadata[key.String()] = value.String()
+			return true
+		})
+	}
+	job, err = manager.Submit(job, handlers.ClientAPIKey(c), requests)
+	if err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.OpenAIBatch(job))
+}
+
+// ListBatches handles GET /v1/batches, newest first. It supports the limit (1-100,
+// default 20) and after query parameters.
+func (h *OpenAIAPIHandler) ListBatches(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	jobs, err := manager.List(handlers.ClientOwner(c), batch.FormatOpenAI)
+	if err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	items := make([][]byte, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, batch.OpenAIBatch(job))
+	}
+	page, hasMore := paginate(items, c.Query("after"), queryLimit(c, 20, 100))
+	c.Data(http.StatusOK, "application/json", batch.OpenAIList(page, hasMore))
+}
+
+// GetBatch handles GET /v1/batches/{id}.
+func (h *OpenAIAPIHandler) GetBatch(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	job, ok := loadOpenAIBatch(c, manager)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.OpenAIBatch(job))
+}
+
+// CancelBatch handles POST /v1/batches/{id}/cancel.
+func (h *OpenAIAPIHandler) CancelBatch(c *gin.Context) {
+	manager, ok := openAIBatchManager(c)
+	if !ok {
+		return
+	}
+	job, ok := loadOpenAIBatch(c, manager)
+	if !ok {
+		return
+	}
+	job, err := manager.Cancel(handlers.ClientOwner(c), job.ID)
+	if err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", batch.OpenAIBatch(job))
+}
+
+func openAIBatchManager(c *gin.Context) (*batch.Manager, bool) {
+	manager := batch.Default()
+	if manager == nil {
+		writeOpenAIError(c, http.StatusServiceUnavailable, "Batches are not available.", "server_error")
+		return nil, false
+	}
+	return manager, true
+}
+
+func loadOpenAIBatch(c *gin.Context, manager *batch.Manager) (*batch.Job, bool) {
+	id := c.Param("id")
+	job, err := manager.Get(handlers.ClientOwner(c), id)
+	if err == nil && job.Format == batch.FormatOpenAI {
+		return job, true
+	}
+	if err == nil || errors.Is(err, batch.ErrNotFound) {
+		writeOpenAIError(c, http.StatusNotFound, fmt.Sprintf("No batch found with id '%s'.", id), "invalid_request_error")
+	} else {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+	}
+	return nil, false
+}
+
+func writeFileLookupError(c *gin.Context, err error) {
+	if errors.Is(err, batch.ErrNotFound) {
+		writeOpenAIError(c, http.StatusNotFound, "No such file.", "invalid_request_error")
+		return
+	}
+	writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+}
+
+func writeOpenAIError(c *gin.Context, status int, message, errType string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: message,
+			Type:    errType,
+		},
+	})
+}
+
+func queryLimit(c *gin.Context, fallback, maxLimit int) int {
+	if raw := c.Query("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 1 && n <= maxLimit {
+			return n
+		}
+	}
+	return fallback
+}
+
+// paginate returns up to limit items following the item whose id is after.
+func paginate(items [][]byte, after string, limit int) ([][]byte, bool) {
+	start := 0
+	if after != "" {
+		for i, item := range items {
+			if gjson.GetBytes(item, "id").String() == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	items = items[start:]
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
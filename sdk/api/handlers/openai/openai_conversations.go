@@ -0,0 +1,571 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Conversations are kept in the response store as records whose ID starts with
+// conversationIDPrefix. Input holds the chat messages in Chat Completions format,
+// Instructions the system prompt, and Response the conversation object itself. CreatedAt
+// tracks the last activity, so the store TTL expires idle conversations.
+const conversationIDPrefix = "conv_"
+
+// bytesPerToken is the rough ratio used to estimate the token size of stored messages.
+const bytesPerToken = 4
+
+// conversationLock is the mutex of one conversation and the number of turns holding or
+// waiting for it.
+type conversationLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// conversationLocks serialises turns of the same conversation so appended messages and
+// replies cannot interleave. A lock exists only while a turn holds or waits for it, so
+// unrelated conversations never block each other and expired ones leave nothing behind.
+var (
+	conversationLocksMu sync.Mutex
+	conversationLocks   = make(map[string]*conversationLock)
+)
+
+func lockConversation(id string) func() {
+	conversationLocksMu.Lock()
+	lock := conversationLocks[id]
+	if lock == nil {
+		lock = &conversationLock{}
+		conversationLocks[id] = lock
+	}
+	lock.refs++
+	conversationLocksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		conversationLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(conversationLocks, id)
+		}
+		conversationLocksMu.Unlock()
+	}
+}
+
+// CreateConversation handles POST /v1/conversations. The body may set a default model,
+// system instructions, initial messages and string metadata.
+func (h *OpenAIAPIHandler) CreateConversation(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if len(rawJSON) == 0 {
+		rawJSON = []byte(`{}`)
+	}
+	root := gjson.ParseBytes(rawJSON)
+	messages, errMsg := conversationMessages(root)
+	if errMsg != "" {
+		writeOpenAIError(c, http.StatusBadRequest, errMsg, "invalid_request_error")
+		return
+	}
+
+	now := time.Now().UTC()
+	object := []byte(`{"id":"","object":"conversation","created_at":0,"metadata":{}}`)
+	object, _ = sjson.SetBytes(object, "id", conversationIDPrefix+strings.ReplaceAll(uuid.NewString(), "-", ""))
+	object, _ = sjson.SetBytes(object, "created_at", now.Unix())
+	if metadata := root.Get("metadata"); metadata.IsObject() {
+		object, _ = sjson.SetRawBytes(object, "metadata", []byte(metadata.Raw))
+	}
+	record := &responsestore.Record{
+		ID:           gjson.GetBytes(object, "id").String(),
+		Model:        root.Get("model").String(),
+		Instructions: root.Get("instructions").String(),
+		Input:        joinMessages(messages),
+		Response:     object,
+		CreatedAt:    now,
+		Owner:        handlers.ClientOwner(c),
+	}
+	if err = store.Put(c.Request.Context(), record); err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", conversationObject(record))
+}
+
+// GetConversation handles GET /v1/conversations/{id}.
+func (h *OpenAIAPIHandler) GetConversation(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	record, ok := loadConversation(c, store)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", conversationObject(record))
+}
+
+// DeleteConversation handles DELETE /v1/conversations/{id}.
+func (h *OpenAIAPIHandler) DeleteConversation(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	if !strings.HasPrefix(id, conversationIDPrefix) {
+		writeConversationNotFound(c, id)
+		return
+	}
+	unlock := lockConversation(id)
+	defer unlock()
+	_, err := responsestore.GetOwned(c.Request.Context(), store, id, handlers.ClientOwner(c))
+	if err == nil {
+		err = store.Delete(c.Request.Context(), id)
+	}
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeConversationNotFound(c, id)
+			return
+		}
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "conversation.deleted", "deleted": true})
+}
+
+// ListConversationMessages handles GET /v1/conversations/{id}/messages, oldest first.
+func (h *OpenAIAPIHandler) ListConversationMessages(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	record, ok := loadConversation(c, store)
+	if !ok {
+		return
+	}
+	out := []byte(`{"object":"list","data":[]}`)
+	out, _ = sjson.SetRawBytes(out, "data", record.Input)
+	c.Data(http.StatusOK, "application/json", out)
+}
+
+// AppendConversationMessages handles POST /v1/conversations/{id}/messages. The body is
+// either {"messages":[...]} or a single message object.
+func (h *OpenAIAPIHandler) AppendConversationMessages(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	root := gjson.ParseBytes(rawJSON)
+	var messages []gjson.Result
+	if root.Get("role").Exists() {
+		messages = []gjson.Result{root}
+	} else {
+		var errMsg string
+		if messages, errMsg = conversationMessages(root); errMsg != "" {
+			writeOpenAIError(c, http.StatusBadRequest, errMsg, "invalid_request_error")
+			return
+		}
+	}
+	if len(messages) == 0 {
+		writeOpenAIError(c, http.StatusBadRequest, "messages is required", "invalid_request_error")
+		return
+	}
+
+	unlock := lockConversation(c.Param("id"))
+	defer unlock()
+	record, ok := loadConversation(c, store)
+	if !ok {
+		return
+	}
+	record.Input = appendMessages(record.Input, messages...)
+	record.CreatedAt = time.Now().UTC()
+	if err = store.Put(c.Request.Context(), record); err != nil {
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return
+	}
+	c.Data(http.StatusOK, "application/json", conversationObject(record))
+}
+
+// ConversationCompletions handles POST /v1/conversations/{id}/completions. The body is a
+// Chat Completions request whose messages are appended to the conversation; the stored
+// history, trimmed to the model's context window, is sent in front of them. The request
+// runs through the regular chat completions pipeline, so any provider can back it, and
+// the assistant reply is appended to the conversation once it completes.
+func (h *OpenAIAPIHandler) ConversationCompletions(c *gin.Context) {
+	store, ok := conversationStore(c)
+	if !ok {
+		return
+	}
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	root := gjson.ParseBytes(rawJSON)
+	newMessages, errMsg := conversationMessages(root)
+	if errMsg != "" {
+		writeOpenAIError(c, http.StatusBadRequest, errMsg, "invalid_request_error")
+		return
+	}
+
+	id := c.Param("id")
+	unlock := lockConversation(id)
+	defer unlock()
+	record, ok := loadConversation(c, store)
+	if !ok {
+		return
+	}
+	modelName := root.Get("model").String()
+	if modelName == "" {
+		modelName = record.Model
+	}
+	if modelName == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "model is required", "invalid_request_error")
+		return
+	}
+	history := appendMessages(record.Input, newMessages...)
+	if len(gjson.ParseBytes(history).Array()) == 0 {
+		writeOpenAIError(c, http.StatusBadRequest, "the conversation has no messages", "invalid_request_error")
+		return
+	}
+
+	window, reserve := conversationBudget(modelName, root)
+	messages, dropped := fitConversationToContext(history, record.Instructions, window, reserve)
+	if dropped > 0 {
+		log.Debugf("conversation %s: dropped %d oldest messages to fit %s", id, dropped, modelName)
+		c.Header("X-Conversation-Dropped-Messages", strconv.Itoa(dropped))
+	}
+	request, _ := sjson.SetBytes(rawJSON, "model", modelName)
+	request, _ = sjson.SetRawBytes(request, "messages", messages)
+
+	finish := func(reply []byte) {
+		if len(reply) == 0 {
+			return
+		}
+		record.Input = appendMessages(history, gjson.ParseBytes(reply))
+		record.CreatedAt = time.Now().UTC()
+		if errPut := store.Put(context.Background(), record); errPut != nil {
+			log.Warnf("conversation %s: failed to store reply: %v", id, errPut)
+		}
+	}
+
+	if root.Get("stream").Bool() {
+		h.streamConversationCompletion(c, request, id, finish)
+		return
+	}
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errExec := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), modelName, request, "")
+	if errExec != nil {
+		h.WriteErrorResponse(c, errExec)
+		cliCancel(errExec.Error)
+		return
+	}
+	if message := gjson.GetBytes(resp, "choices.0.message"); message.IsObject() {
+		finish(assistantReply(message))
+	}
+	resp, _ = sjson.SetBytes(resp, "conversation_id", id)
+	c.Data(http.StatusOK, "application/json", resp)
+	cliCancel()
+}
+
+// streamConversationCompletion streams the completion to the client while collecting the
+// assistant reply from the chunk deltas. The reply is stored only when the stream ends
+// without an error.
+func (h *OpenAIAPIHandler) streamConversationCompletion(c *gin.Context, request []byte, id string, finish func([]byte)) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		writeOpenAIError(c, http.StatusInternalServerError, "Streaming not supported", "server_error")
+		return
+	}
+	modelName := gjson.GetBytes(request, "model").String()
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	dataChan, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, request, "")
+
+	var reply streamedReply
+	completed := false
+	done := make(chan struct{})
+	tee := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage, 1)
+	go func() {
+		defer close(done)
+		defer close(tee)
+		for dataChan != nil || errChan != nil {
+			select {
+			case chunk, okChunk := <-dataChan:
+				if !okChunk {
+					dataChan = nil
+					continue
+				}
+				reply.add(chunk)
+				chunk, _ = sjson.SetBytes(chunk, "conversation_id", id)
+				select {
+				case tee <- chunk:
+				case <-cliCtx.Done():
+					return
+				}
+			case errMsg, okErr := <-errChan:
+				if !okErr {
+					errChan = nil
+					continue
+				}
+				if errMsg != nil {
+					errs <- errMsg
+					return
+				}
+			}
+		}
+		completed = true
+	}()
+
+	// Peek at the first chunk so an immediate upstream failure keeps its HTTP status.
+	select {
+	case <-c.Request.Context().Done():
+		cliCancel(c.Request.Context().Err())
+		<-done
+		return
+	case errMsg := <-errs:
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		<-done
+		return
+	case chunk, okChunk := <-tee:
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("Access-Control-Allow-Origin", "*")
+		if okChunk {
+			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
+			flusher.Flush()
+		}
+		h.handleStreamResult(c, flusher, func(err error) { cliCancel(err) }, tee, errs)
+	}
+	<-done
+	if completed {
+		finish(reply.message())
+	}
+}
+
+// streamedReply accumulates the assistant message from Chat Completions stream chunks.
+type streamedReply struct {
+	content   strings.Builder
+	toolCalls []byte
+}
+
+func (r *streamedReply) add(chunk []byte) {
+	delta := gjson.GetBytes(chunk, "choices.0.delta")
+	r.content.WriteString(delta.Get("content").String())
+	delta.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+		if r.toolCalls == nil {
+			r.toolCalls = []byte(`[]`)
+		}
+		path := strconv.FormatInt(call.Get("index").Int(), 10)
+		if !gjson.GetBytes(r.toolCalls, path).Exists() {
+			r.toolCalls, _ = sjson.SetRawBytes(r.toolCalls, path, []byte(`{"id":"","type":"function","function":{"name":"","arguments":""}}`))
+		}
+		if v := call.Get("id").String(); v != "" {
+			r.toolCalls, _ = sjson.SetBytes(r.toolCalls, path+".id", v)
+		}
+		if v := call.Get("function.name").String(); v != "" {
+			r.toolCalls, _ = sjson.SetBytes(r.toolCalls, path+".function.name", v)
+		}
+		if v := call.Get("function.arguments").String(); v != "" {
+			args := gjson.GetBytes(r.toolCalls, path+".function.arguments").String() + v
+			r.toolCalls, _ = sjson.SetBytes(r.toolCalls, path+".function.arguments", args)
+		}
+		return true
+	})
+}
+
+func (r *streamedReply) message() []byte {
+	if r.content.Len() == 0 && r.toolCalls == nil {
+		return nil
+	}
+	out := []byte(`{"role":"assistant","content":""}`)
+	out, _ = sjson.SetBytes(out, "content", r.content.String())
+	if r.toolCalls != nil {
+		out, _ = sjson.SetRawBytes(out, "tool_calls", r.toolCalls)
+	}
+	return out
+}
+
+// assistantReply keeps the replayable fields of a completion message.
+func assistantReply(message gjson.Result) []byte {
+	out := []byte(`{"role":"assistant","content":""}`)
+	out, _ = sjson.SetBytes(out, "content", message.Get("content").String())
+	if calls := message.Get("tool_calls"); calls.IsArray() && len(calls.Array()) > 0 {
+		out, _ = sjson.SetRawBytes(out, "tool_calls", []byte(calls.Raw))
+	}
+	return out
+}
+
+// conversationBudget returns the context window of the model and the number of tokens to
+// keep free for the reply. A zero window disables truncation.
+func conversationBudget(modelName string, request gjson.Result) (int, int) {
+	info := registry.GetGlobalRegistry().GetModelInfo(modelName)
+	if info == nil {
+		return 0, 0
+	}
+	window := info.ContextLength
+	reserve := 0
+	if window <= 0 {
+		// InputTokenLimit already excludes the output budget.
+		return info.InputTokenLimit, 0
+	}
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		if v := request.Get(key).Int(); v > 0 {
+			reserve = int(v)
+			break
+		}
+	}
+	if reserve == 0 {
+		reserve = info.MaxCompletionTokens
+		if reserve == 0 {
+			reserve = info.OutputTokenLimit
+		}
+	}
+	return window, min(reserve, window/2)
+}
+
+// fitConversationToContext prepends the instructions as a system message and drops the
+// oldest messages until the estimated size fits window minus reserve. The newest message
+// is always kept, and the history never starts with an orphaned assistant or tool message.
+func fitConversationToContext(history json.RawMessage, instructions string, window, reserve int) (json.RawMessage, int) {
+	messages := gjson.ParseBytes(history).Array()
+	budget := window - reserve
+	used := 0
+	if instructions != "" {
+		used = len(instructions)/bytesPerToken + 4
+	}
+	start := 0
+	if window > 0 {
+		sizes := make([]int, len(messages))
+		for i, message := range messages {
+			sizes[i] = len(message.Raw)/bytesPerToken + 4
+			used += sizes[i]
+		}
+		for start < len(messages)-1 && used > budget {
+			used -= sizes[start]
+			start++
+		}
+		if start > 0 {
+			for start < len(messages)-1 && messages[start].Get("role").String() != "user" {
+				start++
+			}
+		}
+	}
+
+	out := []byte(`[]`)
+	if instructions != "" {
+		system := []byte(`{"role":"system","content":""}`)
+		system, _ = sjson.SetBytes(system, "content", instructions)
+		out, _ = sjson.SetRawBytes(out, "-1", system)
+	}
+	for _, message := range messages[start:] {
+		out, _ = sjson.SetRawBytes(out, "-1", []byte(message.Raw))
+	}
+	return out, start
+}
+
+// conversationMessages validates the optional messages array of a request body.
+func conversationMessages(root gjson.Result) ([]gjson.Result, string) {
+	messages := root.Get("messages")
+	if !messages.Exists() {
+		return nil, ""
+	}
+	if !messages.IsArray() {
+		return nil, "messages must be an array"
+	}
+	out := messages.Array()
+	for i, message := range out {
+		if message.Get("role").String() == "" {
+			return nil, fmt.Sprintf("messages[%d].role is required", i)
+		}
+	}
+	return out, ""
+}
+
+func joinMessages(messages []gjson.Result) json.RawMessage {
+	return appendMessages(json.RawMessage(`[]`), messages...)
+}
+
+func appendMessages(list json.RawMessage, messages ...gjson.Result) json.RawMessage {
+	out := []byte(list)
+	if len(out) == 0 {
+		out = []byte(`[]`)
+	}
+	for _, message := range messages {
+		out, _ = sjson.SetRawBytes(out, "-1", []byte(message.Raw))
+	}
+	return out
+}
+
+// conversationObject renders the stored conversation with its current model, instructions
+// and message count.
+func conversationObject(record *responsestore.Record) []byte {
+	out := []byte(record.Response)
+	if record.Model != "" {
+		out, _ = sjson.SetBytes(out, "model", record.Model)
+	}
+	if record.Instructions != "" {
+		out, _ = sjson.SetBytes(out, "instructions", record.Instructions)
+	}
+	out, _ = sjson.SetBytes(out, "message_count", len(gjson.ParseBytes(record.Input).Array()))
+	return out
+}
+
+func conversationStore(c *gin.Context) (responsestore.Store, bool) {
+	store := responsestore.Default()
+	if store == nil {
+		writeOpenAIError(c, http.StatusServiceUnavailable, "Conversations require a response store; set response-store.backend in the configuration.", "server_error")
+		return nil, false
+	}
+	return store, true
+}
+
+func loadConversation(c *gin.Context, store responsestore.Store) (*responsestore.Record, bool) {
+	id := c.Param("id")
+	if !strings.HasPrefix(id, conversationIDPrefix) {
+		writeConversationNotFound(c, id)
+		return nil, false
+	}
+	record, err := responsestore.GetOwned(c.Request.Context(), store, id, handlers.ClientOwner(c))
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeConversationNotFound(c, id)
+			return nil, false
+		}
+		writeOpenAIError(c, http.StatusInternalServerError, err.Error(), "server_error")
+		return nil, false
+	}
+	return record, true
+}
+
+func writeConversationNotFound(c *gin.Context, id string) {
+	writeOpenAIError(c, http.StatusNotFound, fmt.Sprintf("Conversation with id '%s' not found.", id), "invalid_request_error")
+}
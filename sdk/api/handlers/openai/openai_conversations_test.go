@@ -0,0 +1,161 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestFitConversationToContext(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	history := json.RawMessage(`[
+		{"role":"user","content":"` + long + `"},
+		{"role":"assistant","content":"` + long + `"},
+		{"role":"user","content":"` + long + `"},
+		{"role":"assistant","content":"short"},
+		{"role":"user","content":"latest"}]`)
+
+	out, dropped := fitConversationToContext(history, "be brief", 0, 0)
+	if dropped != 0 || len(gjson.ParseBytes(out).Array()) != 6 {
+		t.Fatalf("without a window: dropped = %d, messages = %s", dropped, out)
+	}
+
+	out, dropped = fitConversationToContext(history, "be brief", 260, 20)
+	messages := gjson.ParseBytes(out).Array()
+	if dropped != 2 {
+		t.Fatalf("dropped = %d, want 2: %s", dropped, out)
+	}
+	if messages[0].Get("role").String() != "system" || messages[1].Get("role").String() != "user" {
+		t.Fatalf("messages = %s, want system prompt followed by a user turn", out)
+	}
+	if last := messages[len(messages)-1].Get("content").String(); last != "latest" {
+		t.Fatalf("last message = %q, want latest", last)
+	}
+
+	out, dropped = fitConversationToContext(history, "", 10, 0)
+	if dropped != 4 || len(gjson.ParseBytes(out).Array()) != 1 {
+		t.Fatalf("tiny window: dropped = %d, messages = %s; want only the newest message", dropped, out)
+	}
+}
+
+func TestConversationEndpoints(t *testing.T) {
+	useMemoryResponseStore(t)
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIAPIHandler{}
+	router := gin.New()
+	router.POST("/v1/conversations", h.CreateConversation)
+	router.GET("/v1/conversations/:id", h.GetConversation)
+	router.DELETE("/v1/conversations/:id", h.DeleteConversation)
+	router.GET("/v1/conversations/:id/messages", h.ListConversationMessages)
+	router.POST("/v1/conversations/:id/messages", h.AppendConversationMessages)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/v1/conversations", `{"model":"m","instructions":"be brief","metadata":{"team":"notes"},"messages":[{"role":"user","content":"hi"}]}`)
+	id := gjson.Get(rec.Body.String(), "id").String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(id, conversationIDPrefix) || gjson.Get(rec.Body.String(), "metadata.team").String() != "notes" {
+		t.Fatalf("POST conversation = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodPost, "/v1/conversations/"+id+"/messages", `{"role":"assistant","content":"hello"}`); rec.Code != http.StatusOK || gjson.Get(rec.Body.String(), "message_count").Int() != 2 {
+		t.Fatalf("POST messages = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodGet, "/v1/conversations/"+id+"/messages", ""); rec.Code != http.StatusOK || gjson.Get(rec.Body.String(), "data.1.content").String() != "hello" {
+		t.Fatalf("GET messages = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodPost, "/v1/conversations/"+id+"/messages", `{"messages":[{"content":"no role"}]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("POST invalid messages = %d, want 400", rec.Code)
+	}
+	if rec = do(http.MethodDelete, "/v1/conversations/"+id, ""); rec.Code != http.StatusOK {
+		t.Fatalf("DELETE conversation = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodGet, "/v1/conversations/"+id, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET deleted conversation = %d, want 404", rec.Code)
+	}
+	if rec = do(http.MethodGet, "/v1/conversations/resp_1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET non-conversation record = %d, want 404", rec.Code)
+	}
+}
+
+func TestConversationsScopedToOwner(t *testing.T) {
+	useMemoryResponseStore(t)
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIAPIHandler{}
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("apiKey", c.GetHeader("X-Test-Key")) })
+	router.POST("/v1/conversations", h.CreateConversation)
+	router.GET("/v1/conversations/:id", h.GetConversation)
+	router.DELETE("/v1/conversations/:id", h.DeleteConversation)
+	router.GET("/v1/conversations/:id/messages", h.ListConversationMessages)
+	router.POST("/v1/conversations/:id/messages", h.AppendConversationMessages)
+	router.POST("/v1/conversations/:id/completions", h.ConversationCompletions)
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Test-Key", key)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := do(http.MethodPost, "/v1/conversations", "key-a", `{"model":"m","messages":[{"role":"user","content":"secret"}]}`)
+	id := gjson.Get(rec.Body.String(), "id").String()
+	if rec.Code != http.StatusOK || id == "" {
+		t.Fatalf("POST conversation = %d %s", rec.Code, rec.Body.String())
+	}
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodGet, "/v1/conversations/" + id, ""},
+		{http.MethodGet, "/v1/conversations/" + id + "/messages", ""},
+		{http.MethodPost, "/v1/conversations/" + id + "/messages", `{"role":"user","content":"x"}`},
+		{http.MethodPost, "/v1/conversations/" + id + "/completions", `{"messages":[{"role":"user","content":"x"}]}`},
+		{http.MethodDelete, "/v1/conversations/" + id, ""},
+	} {
+		if rec = do(tc.method, tc.path, "key-b", tc.body); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s %s by another key = %d, want 404", tc.method, tc.path, rec.Code)
+		}
+	}
+	if rec = do(http.MethodGet, "/v1/conversations/"+id+"/messages", "key-a", ""); rec.Code != http.StatusOK || gjson.Get(rec.Body.String(), "data.#").Int() != 1 {
+		t.Fatalf("GET messages by owner = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestConversationLocksArePerConversation(t *testing.T) {
+	unlockA := lockConversation("conv_lock_a")
+	acquired := make(chan struct{})
+	go func() {
+		lockConversation("conv_lock_b")()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("a held conversation blocked an unrelated one")
+	}
+
+	waiting := make(chan struct{})
+	go func() {
+		lockConversation("conv_lock_a")()
+		close(waiting)
+	}()
+	select {
+	case <-waiting:
+		t.Fatal("a second turn acquired a held conversation")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	<-waiting
+
+	conversationLocksMu.Lock()
+	defer conversationLocksMu.Unlock()
+	if len(conversationLocks) != 0 {
+		t.Fatalf("%d conversation locks left after release", len(conversationLocks))
+	}
+}
@@ -0,0 +1,47 @@
+package openai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+)
+
+func TestEmbeddingsRejectsNonEmbeddingModels(t *testing.T) {
+	registry.GetGlobalRegistry().RegisterClient("embeddings-test", "claude", []*registry.ModelInfo{{ID: "embeddings-test-chat"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("embeddings-test") })
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIAPIHandler{}
+	router := gin.New()
+	router.POST("/v1/embeddings", h.Embeddings)
+
+	for _, model := range []string{"embeddings-test-chat", "embeddings-test-unknown"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"`+model+`","input":"hi"}`)))
+		if rec.Code != http.StatusBadRequest || gjson.Get(rec.Body.String(), "error.type").String() != "invalid_request_error" {
+			t.Fatalf("%s: %d %s, want a 400 invalid_request_error", model, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestEmbeddingModelsKeptOutOfChatLists(t *testing.T) {
+	for name, models := range map[string][]*registry.ModelInfo{
+		"gemini": registry.GetGeminiModels(),
+		"vertex": registry.GetGeminiVertexModels(),
+	} {
+		for _, model := range models {
+			if model.SupportsEmbeddings() {
+				t.Errorf("%s chat models include embedding model %s", name, model.ID)
+			}
+		}
+	}
+	for _, model := range append(registry.GetGeminiEmbeddingModels(), registry.GetGeminiVertexEmbeddingModels()...) {
+		if !model.SupportsEmbeddings() {
+			t.Errorf("embedding model %s is not marked as one", model.ID)
+		}
+	}
+}
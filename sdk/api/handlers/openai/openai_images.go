@@ -0,0 +1,287 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/imagestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxImagesPerRequest caps n, matching the limit of the OpenAI Images API.
+const maxImagesPerRequest = 10
+
+// maxUploadMemory bounds the multipart form kept in memory for image and audio uploads.
+const maxUploadMemory = 32 << 20
+
+// ImageGenerations handles POST /v1/images/generations.
+func (h *OpenAIAPIHandler) ImageGenerations(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if strings.TrimSpace(gjson.GetBytes(rawJSON, "prompt").String()) == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "prompt is required", "invalid_request_error")
+		return
+	}
+	h.handleImages(c, rawJSON)
+}
+
+// ImageEdits handles POST /v1/images/edits. The multipart upload is converted into a JSON
+// request whose "images" and "mask" entries carry the files as base64, which is the form
+// the translators and executors understand. JSON bodies already in that form are accepted too.
+func (h *OpenAIAPIHandler) ImageEdits(c *gin.Context) {
+	var rawJSON []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		rawJSON, err = imageEditFormToJSON(c)
+	} else {
+		rawJSON, err = c.GetRawData()
+	}
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if strings.TrimSpace(gjson.GetBytes(rawJSON, "prompt").String()) == "" {
+		writeOpenAIError(c, http.StatusBadRequest, "prompt is required", "invalid_request_error")
+		return
+	}
+	if len(gjson.GetBytes(rawJSON, "images").Array()) == 0 {
+		writeOpenAIError(c, http.StatusBadRequest, "image is required", "invalid_request_error")
+		return
+	}
+	h.handleImages(c, rawJSON)
+}
+
+// GeneratedImage serves an image stored for a response_format "url" request. The route is
+// public because image URLs are fetched without API keys; names are unguessable and expire.
+func (h *OpenAIAPIHandler) GeneratedImage(c *gin.Context) {
+	path, err := imagestore.Path(c.Param("name"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.File(path)
+}
+
+// handleImages runs one upstream request per requested image, since Gemini image models
+// return a single image per call, and merges the results into one response.
+func (h *OpenAIAPIHandler) handleImages(c *gin.Context, rawJSON []byte) {
+	n := int(gjson.GetBytes(rawJSON, "n").Int())
+	if n < 1 {
+		n = 1
+	}
+	if n > maxImagesPerRequest {
+		writeOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("n must be at most %d", maxImagesPerRequest), "invalid_request_error")
+		return
+	}
+	wantURL := gjson.GetBytes(rawJSON, "response_format").String() == "url"
+	if wantURL {
+		// URLs are produced locally from the returned image data.
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, "response_format")
+	}
+	rawJSON, _ = sjson.SetBytes(rawJSON, "n", 1)
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	responses := make([][]byte, n)
+	errs := make([]*interfaces.ErrorMessage, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = h.ExecuteWithAuthManager(cliCtx, OpenAIImage, modelName, rawJSON, "")
+		}(i)
+	}
+	wg.Wait()
+
+	out, errMsg := mergeImageResponses(responses, errs)
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	if wantURL {
+		var errStore error
+		out, errStore = storeImagesAsURLs(out, handlers.RequestBaseURL(c))
+		if errStore != nil {
+			writeOpenAIError(c, http.StatusInternalServerError, errStore.Error(), "server_error")
+			cliCancel(errStore)
+			return
+		}
+	}
+	c.Data(http.StatusOK, "application/json", out)
+	cliCancel()
+}
+
+// mergeImageResponses concatenates the data entries of successful responses and sums their
+// usage. Partial failures are tolerated; the first error is returned only when no image
+// was produced at all.
+func mergeImageResponses(responses [][]byte, errs []*interfaces.ErrorMessage) ([]byte, *interfaces.ErrorMessage) {
+	var out []byte
+	var firstErr *interfaces.ErrorMessage
+	var inputTokens, outputTokens, totalTokens int64
+	hasUsage := false
+	for i, resp := range responses {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		root := gjson.ParseBytes(resp)
+		if usage := root.Get("usage"); usage.Exists() {
+			hasUsage = true
+			inputTokens += usage.Get("input_tokens").Int()
+			outputTokens += usage.Get("output_tokens").Int()
+			totalTokens += usage.Get("total_tokens").Int()
+		}
+		if out == nil {
+			out = resp
+			continue
+		}
+		root.Get("data").ForEach(func(_, item gjson.Result) bool {
+			out, _ = sjson.SetRawBytes(out, "data.-1", []byte(item.Raw))
+			return true
+		})
+	}
+	if len(gjson.GetBytes(out, "data").Array()) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadGateway,
+			Error:      errors.New("the model did not return an image; try rephrasing the prompt or using an image-capable model"),
+		}
+	}
+	if hasUsage {
+		out, _ = sjson.SetBytes(out, "usage.input_tokens", inputTokens)
+		out, _ = sjson.SetBytes(out, "usage.output_tokens", outputTokens)
+		out, _ = sjson.SetBytes(out, "usage.total_tokens", totalTokens)
+	}
+	return out, nil
+}
+
+// storeImagesAsURLs saves every b64_json entry to the image store and replaces it with a
+// URL served by GeneratedImage. Entries that already carry a URL are left untouched.
+func storeImagesAsURLs(out []byte, baseURL string) ([]byte, error) {
+	mimeType := "image/" + gjson.GetBytes(out, "output_format").String()
+	if mimeType == "image/" {
+		mimeType = "image/png"
+	}
+	for i, item := range gjson.GetBytes(out, "data").Array() {
+		encoded := item.Get("b64_json").String()
+		if encoded == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode image: %w", err)
+		}
+		name, err := imagestore.Save(data, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		out, _ = sjson.DeleteBytes(out, fmt.Sprintf("data.%d.b64_json", i))
+		out, _ = sjson.SetBytes(out, fmt.Sprintf("data.%d.url", i), baseURL+"/generated-images/"+name)
+	}
+	return out, nil
+}
+
+// imageEditFormToJSON converts a multipart image edit request into its JSON form.
+func imageEditFormToJSON(c *gin.Context) ([]byte, error) {
+	form, out, err := parseMultipartRequest(c, []byte(`{"images":[]}`), "n")
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"image", "image[]"} {
+		for _, header := range form.File[field] {
+			image, errEncode := encodeUploadedFile(header, "image")
+			if errEncode != nil {
+				return nil, errEncode
+			}
+			out, _ = sjson.SetRawBytes(out, "images.-1", image)
+		}
+	}
+	if masks := form.File["mask"]; len(masks) > 0 {
+		mask, errEncode := encodeUploadedFile(masks[0], "image")
+		if errEncode != nil {
+			return nil, errEncode
+		}
+		out, _ = sjson.SetRawBytes(out, "mask", mask)
+	}
+	return out, nil
+}
+
+// parseMultipartRequest parses a multipart upload and copies its text fields into the JSON
+// template. Fields listed in numeric are stored as numbers.
+func parseMultipartRequest(c *gin.Context, template []byte, numeric ...string) (*multipart.Form, []byte, error) {
+	if err := c.Request.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, nil, err
+	}
+	form := c.Request.MultipartForm
+	out := template
+	for key, values := range form.Value {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		if slices.Contains(numeric, key) {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			out, _ = sjson.SetBytes(out, key, number)
+			continue
+		}
+		out, _ = sjson.SetBytes(out, key, value)
+	}
+	return form, out, nil
+}
+
+// encodeUploadedFile reads an uploaded file into a {"data","mime_type","filename"} object.
+// When the client sent no usable content type for the media type ("image", "audio"), it is
+// derived from the file extension or content.
+func encodeUploadedFile(header *multipart.FileHeader, mediaType string) ([]byte, error) {
+	src, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", header.Filename, err)
+	}
+	data, err := io.ReadAll(src)
+	_ = src.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", header.Filename, err)
+	}
+	mimeType := header.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, mediaType+"/") {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+		if byExt := misc.MimeTypes[ext]; byExt != "" {
+			mimeType = byExt
+		} else {
+			mimeType = http.DetectContentType(data)
+		}
+	}
+	file := []byte(`{}`)
+	file, _ = sjson.SetBytes(file, "data", base64.StdEncoding.EncodeToString(data))
+	file, _ = sjson.SetBytes(file, "mime_type", mimeType)
+	file, _ = sjson.SetBytes(file, "filename", header.Filename)
+	return file, nil
+}
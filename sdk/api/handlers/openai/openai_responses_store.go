@@ -0,0 +1,322 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// storedTurn carries what is needed to persist the response of a Responses API request.
+type storedTurn struct {
+	owner              string
+	store              bool
+	model              string
+	previousResponseID string
+	instructions       string
+	input              json.RawMessage
+}
+
+// prepareStoredTurn resolves previous_response_id by prepending the stored conversation to the
+// request input, so that backends without server-side state see the full history. It returns the
+// rewritten request and the turn to persist once the response is known. Only responses stored
+// by the same owner can be chained.
+func prepareStoredTurn(ctx context.Context, owner string, rawJSON []byte) ([]byte, *storedTurn, *handlers.ErrorDetail, int) {
+	turn := &storedTurn{
+		owner:              owner,
+		store:              gjson.GetBytes(rawJSON, "store").Type != gjson.False,
+		model:              gjson.GetBytes(rawJSON, "model").String(),
+		previousResponseID: strings.TrimSpace(gjson.GetBytes(rawJSON, "previous_response_id").String()),
+		instructions:       gjson.GetBytes(rawJSON, "instructions").String(),
+		input:              normalizeResponseInput(gjson.GetBytes(rawJSON, "input")),
+	}
+	if turn.previousResponseID == "" {
+		return rawJSON, turn, nil, 0
+	}
+
+	store := responsestore.Default()
+	if store == nil {
+		return nil, nil, &handlers.ErrorDetail{
+			Message: "previous_response_id requires a response store; set response-store.backend in the configuration",
+			Type:    "invalid_request_error",
+		}, http.StatusBadRequest
+	}
+	history, err := loadResponseHistory(ctx, store, owner, turn.previousResponseID)
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			return nil, nil, &handlers.ErrorDetail{
+				Message: fmt.Sprintf("Previous response with id '%s' not found.", turn.previousResponseID),
+				Type:    "invalid_request_error",
+				Code:    "previous_response_not_found",
+			}, http.StatusBadRequest
+		}
+		return nil, nil, &handlers.ErrorDetail{Message: err.Error(), Type: "server_error"}, http.StatusInternalServerError
+	}
+
+	combined := []byte("[]")
+	for _, item := range history {
+		combined, _ = sjson.SetRawBytes(combined, "-1", item)
+	}
+	gjson.ParseBytes(turn.input).ForEach(func(_, item gjson.Result) bool {
+		combined, _ = sjson.SetRawBytes(combined, "-1", []byte(item.Raw))
+		return true
+	})
+	out, errSet := sjson.SetRawBytes(rawJSON, "input", combined)
+	if errSet != nil {
+		return nil, nil, &handlers.ErrorDetail{Message: errSet.Error(), Type: "server_error"}, http.StatusInternalServerError
+	}
+	out, _ = sjson.DeleteBytes(out, "previous_response_id")
+	return out, turn, nil, 0
+}
+
+// loadResponseHistory walks the previous_response_id chain and returns the input and output
+// items of every stored turn, oldest first. A missing older turn ends the chain; a missing
+// direct predecessor is reported as responsestore.ErrNotFound. Turns stored by another owner
+// count as missing.
+func loadResponseHistory(ctx context.Context, store responsestore.Store, owner, previousID string) ([][]byte, error) {
+	var turns [][][]byte
+	seen := make(map[string]struct{})
+	for id := previousID; id != ""; {
+		if _, loop := seen[id]; loop {
+			break
+		}
+		seen[id] = struct{}{}
+		record, err := responsestore.GetOwned(ctx, store, id, owner)
+		if err != nil {
+			if errors.Is(err, responsestore.ErrNotFound) && len(turns) > 0 {
+				break
+			}
+			return nil, err
+		}
+		var items [][]byte
+		gjson.ParseBytes(record.Input).ForEach(func(_, item gjson.Result) bool {
+			items = append(items, historyItem(item))
+			return true
+		})
+		gjson.GetBytes(record.Response, "output").ForEach(func(_, item gjson.Result) bool {
+			// Reasoning without encrypted content cannot be replayed to a stateless backend.
+			if item.Get("type").String() == "reasoning" && item.Get("encrypted_content").String() == "" {
+				return true
+			}
+			items = append(items, historyItem(item))
+			return true
+		})
+		turns = append(turns, items)
+		id = record.PreviousResponseID
+	}
+	var history [][]byte
+	for i := len(turns) - 1; i >= 0; i-- {
+		history = append(history, turns[i]...)
+	}
+	return history, nil
+}
+
+// historyItem drops item IDs, which refer to upstream state the backend may not have.
+func historyItem(item gjson.Result) []byte {
+	raw := []byte(item.Raw)
+	if item.Get("id").Exists() {
+		raw, _ = sjson.DeleteBytes(raw, "id")
+	}
+	return raw
+}
+
+// normalizeResponseInput converts the request input into an item list.
+func normalizeResponseInput(input gjson.Result) json.RawMessage {
+	switch {
+	case input.IsArray():
+		return json.RawMessage(input.Raw)
+	case input.Type == gjson.String:
+		item := []byte(`{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`)
+		item, _ = sjson.SetBytes(item, "content.0.text", input.String())
+		return json.RawMessage("[" + string(item) + "]")
+	default:
+		return json.RawMessage("[]")
+	}
+}
+
+// decorate restores the request-level fields the backend never saw on a response object.
+func (t *storedTurn) decorate(response []byte) []byte {
+	if t == nil || len(response) == 0 {
+		return response
+	}
+	if t.previousResponseID != "" {
+		response, _ = sjson.SetBytes(response, "previous_response_id", t.previousResponseID)
+	}
+	if gjson.GetBytes(response, "store").Exists() {
+		response, _ = sjson.SetBytes(response, "store", t.store && responsestore.Default() != nil)
+	}
+	return response
+}
+
+// save persists the completed response. Failures are logged and never affect the client.
+func (t *storedTurn) save(ctx context.Context, response []byte) {
+	if t == nil || !t.store {
+		return
+	}
+	store := responsestore.Default()
+	if store == nil {
+		return
+	}
+	id := gjson.GetBytes(response, "id").String()
+	if id == "" {
+		return
+	}
+	record := &responsestore.Record{
+		ID:                 id,
+		Model:              t.model,
+		PreviousResponseID: t.previousResponseID,
+		Instructions:       t.instructions,
+		Input:              t.input,
+		Response:           json.RawMessage(bytes.Clone(response)),
+		CreatedAt:          time.Now().UTC(),
+		Owner:              t.owner,
+	}
+	if err := store.Put(ctx, record); err != nil {
+		log.Warnf("failed to store response %s: %v", id, err)
+	}
+}
+
+// decorateStreamChunk rewrites the response object carried by stream events and stores the
+// final response when the response.completed event passes through.
+func (t *storedTurn) decorateStreamChunk(ctx context.Context, chunk []byte) []byte {
+	if t == nil || !bytes.Contains(chunk, []byte(`"response"`)) {
+		return chunk
+	}
+	lines := bytes.Split(chunk, []byte("\n"))
+	for i, line := range lines {
+		payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			continue
+		}
+		payload = bytes.TrimSpace(payload)
+		response := gjson.GetBytes(payload, "response")
+		if !response.IsObject() {
+			continue
+		}
+		decorated := t.decorate([]byte(response.Raw))
+		if gjson.GetBytes(payload, "type").String() == "response.completed" {
+			t.save(ctx, decorated)
+		}
+		updated, err := sjson.SetRawBytes(payload, "response", decorated)
+		if err != nil {
+			continue
+		}
+		lines[i] = append([]byte("data: "), updated...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// GetResponse handles GET /v1/responses/{id}.
+func (h *OpenAIResponsesAPIHandler) GetResponse(c *gin.Context) {
+	record, ok := h.loadStoredResponse(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", record.Response)
+}
+
+// DeleteResponse handles DELETE /v1/responses/{id}.
+func (h *OpenAIResponsesAPIHandler) DeleteResponse(c *gin.Context) {
+	store := responsestore.Default()
+	id := strings.TrimSpace(c.Param("id"))
+	if store == nil {
+		writeResponseNotFound(c, id)
+		return
+	}
+	_, err := responsestore.GetOwned(c.Request.Context(), store, id, handlers.ClientOwner(c))
+	if err == nil {
+		err = store.Delete(c.Request.Context(), id)
+	}
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeResponseNotFound(c, id)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "response", "deleted": true})
+}
+
+// ListResponseInputItems handles GET /v1/responses/{id}/input_items. It supports the
+// limit (1-100, default 20), order (asc or desc, default desc) and after query parameters.
+func (h *OpenAIResponsesAPIHandler) ListResponseInputItems(c *gin.Context) {
+	record, ok := h.loadStoredResponse(c)
+	if !ok {
+		return
+	}
+	items := gjson.ParseBytes(record.Input).Array()
+	if !strings.EqualFold(c.DefaultQuery("order", "desc"), "asc") {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if after := strings.TrimSpace(c.Query("after")); after != "" {
+		for i, item := range items {
+			if item.Get("id").String() == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	limit := 20
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	data := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data = append(data, json.RawMessage(item.Raw))
+	}
+	body := gin.H{"object": "list", "data": data, "has_more": hasMore, "first_id": nil, "last_id": nil}
+	if len(items) > 0 {
+		body["first_id"] = items[0].Get("id").String()
+		body["last_id"] = items[len(items)-1].Get("id").String()
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+func (h *OpenAIResponsesAPIHandler) loadStoredResponse(c *gin.Context) (*responsestore.Record, bool) {
+	id := strings.TrimSpace(c.Param("id"))
+	store := responsestore.Default()
+	if store == nil {
+		writeResponseNotFound(c, id)
+		return nil, false
+	}
+	record, err := responsestore.GetOwned(c.Request.Context(), store, id, handlers.ClientOwner(c))
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeResponseNotFound(c, id)
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"}})
+		return nil, false
+	}
+	return record, true
+}
+
+func writeResponseNotFound(c *gin.Context, id string) {
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: fmt.Sprintf("Response with id '%s' not found.", id),
+			Type:    "invalid_request_error",
+		},
+	})
+}
@@ -0,0 +1,150 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsestore"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+func useMemoryResponseStore(t *testing.T) {
+	t.Helper()
+	responsestore.SetDefault(responsestore.NewMemoryStore(0, 0))
+	t.Cleanup(func() { responsestore.SetDefault(nil) })
+}
+
+func TestPrepareStoredTurn_RehydratesPreviousResponses(t *testing.T) {
+	useMemoryResponseStore(t)
+	ctx := context.Background()
+
+	first := []byte(`{"model":"m","input":"hello"}`)
+	_, turn, errDetail, _ := prepareStoredTurn(ctx, "", first)
+	if errDetail != nil {
+		t.Fatalf("prepareStoredTurn() error = %+v", errDetail)
+	}
+	turn.save(ctx, []byte(`{"id":"resp_1","object":"response","output":[
+		{"id":"rs_1","type":"reasoning","summary":[]},
+		{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"output_text","text":"hi"}]}]}`))
+
+	second := []byte(`{"model":"m","previous_response_id":"resp_1","input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"again"}]}]}`)
+	rewritten, turn, errDetail, _ := prepareStoredTurn(ctx, "", second)
+	if errDetail != nil {
+		t.Fatalf("prepareStoredTurn() error = %+v", errDetail)
+	}
+	if gjson.GetBytes(rewritten, "previous_response_id").Exists() {
+		t.Fatal("previous_response_id was forwarded to the backend")
+	}
+	input := gjson.GetBytes(rewritten, "input").Array()
+	if len(input) != 3 {
+		t.Fatalf("input items = %d, want 3 (user, assistant, user): %s", len(input), rewritten)
+	}
+	if got := input[0].Get("content.0.text").String(); got != "hello" {
+		t.Fatalf("input[0] text = %q, want hello", got)
+	}
+	if input[1].Get("id").Exists() || input[1].Get("content.0.text").String() != "hi" {
+		t.Fatalf("input[1] = %s, want the assistant message without its id", input[1].Raw)
+	}
+	if got := input[2].Get("content.0.text").String(); got != "again" {
+		t.Fatalf("input[2] text = %q, want again", got)
+	}
+
+	resp := turn.decorate([]byte(`{"id":"resp_2","object":"response","store":false,"output":[]}`))
+	if gjson.GetBytes(resp, "previous_response_id").String() != "resp_1" || !gjson.GetBytes(resp, "store").Bool() {
+		t.Fatalf("decorate() = %s, want previous_response_id and store restored", resp)
+	}
+
+	_, _, errDetail, status := prepareStoredTurn(ctx, "", []byte(`{"model":"m","previous_response_id":"resp_missing","input":"x"}`))
+	if errDetail == nil || status != http.StatusBadRequest {
+		t.Fatalf("prepareStoredTurn() with unknown id = %+v, %d; want 400", errDetail, status)
+	}
+}
+
+func TestResponsesStoreEndpoints(t *testing.T) {
+	useMemoryResponseStore(t)
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIResponsesAPIHandler{}
+	router := gin.New()
+	router.GET("/v1/responses/:id", h.GetResponse)
+	router.DELETE("/v1/responses/:id", h.DeleteResponse)
+	router.GET("/v1/responses/:id/input_items", h.ListResponseInputItems)
+
+	_, turn, _, _ := prepareStoredTurn(context.Background(), "", []byte(`{"model":"m","input":[
+		{"id":"in_1","type":"message","role":"user","content":"a"},
+		{"id":"in_2","type":"message","role":"user","content":"b"}]}`))
+	turn.save(context.Background(), []byte(`{"id":"resp_1","object":"response","output":[]}`))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/v1/responses/resp_1"); rec.Code != http.StatusOK || gjson.Get(rec.Body.String(), "id").String() != "resp_1" {
+		t.Fatalf("GET response = %d %s", rec.Code, rec.Body.String())
+	}
+	rec := do(http.MethodGet, "/v1/responses/resp_1/input_items?order=asc&limit=1")
+	if rec.Code != http.StatusOK || gjson.Get(rec.Body.String(), "data.0.id").String() != "in_1" || !gjson.Get(rec.Body.String(), "has_more").Bool() {
+		t.Fatalf("GET input_items = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodDelete, "/v1/responses/resp_1"); rec.Code != http.StatusOK || !gjson.Get(rec.Body.String(), "deleted").Bool() {
+		t.Fatalf("DELETE response = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = do(http.MethodGet, "/v1/responses/resp_1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET deleted response = %d, want 404", rec.Code)
+	}
+}
+
+func TestResponsesStoreScopedToOwner(t *testing.T) {
+	useMemoryResponseStore(t)
+	gin.SetMode(gin.TestMode)
+	h := &OpenAIResponsesAPIHandler{}
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("apiKey", c.GetHeader("X-Test-Key")) })
+	router.GET("/v1/responses/:id", h.GetResponse)
+	router.DELETE("/v1/responses/:id", h.DeleteResponse)
+	router.GET("/v1/responses/:id/input_items", h.ListResponseInputItems)
+
+	owner := handlers.ClientOwner(func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("apiKey", "key-a")
+		return c
+	}())
+	_, turn, _, _ := prepareStoredTurn(context.Background(), owner, []byte(`{"model":"m","input":"secret"}`))
+	turn.save(context.Background(), []byte(`{"id":"resp_a","object":"response","output":[]}`))
+
+	do := func(method, path, key string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Test-Key", key)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/v1/responses/resp_a"},
+		{http.MethodGet, "/v1/responses/resp_a/input_items"},
+		{http.MethodDelete, "/v1/responses/resp_a"},
+	} {
+		if rec := do(tc.method, tc.path, "key-b"); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s %s by another key = %d, want 404", tc.method, tc.path, rec.Code)
+		}
+	}
+	if _, _, errDetail, status := prepareStoredTurn(context.Background(), "other", []byte(`{"model":"m","previous_response_id":"resp_a","input":"x"}`)); errDetail == nil || status != http.StatusBadRequest {
+		t.Fatalf("chaining onto another owner's response = %+v, %d; want 400", errDetail, status)
+	}
+	if rec := do(http.MethodGet, "/v1/responses/resp_a", "key-a"); rec.Code != http.StatusOK {
+		t.Fatalf("GET by owner = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNewResponseStoreDefaultsToNone(t *testing.T) {
+	store, err := responsestore.New(context.Background(), config.ResponseStoreConfig{})
+	if err != nil || store != nil {
+		t.Fatalf("New(empty backend) = %v, %v; want no store", store, err)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsecache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheHeader reports whether a response was served from the response cache.
+const cacheHeader = "X-Cache"
+
+// responseCacheSlot is the cache decision for a single request.
+type responseCacheSlot struct {
+	cache *responsecache.Cache
+	key   string
+	model string
+	// lookup is false when the client sent Cache-Control: no-cache.
+	lookup bool
+	// store is false when the client sent Cache-Control: no-store.
+	store bool
+}
+
+// responseCacheFor returns the cache slot of a request, or nil when the request bypasses the
+// cache. Unless the cache is configured with global scope, entries are keyed by the client
+// API key, so a client is never served, or told about, another client's response.
+func responseCacheFor(ctx context.Context, handlerType, model string, metadata map[string]any, rawJSON []byte, alt string, stream bool) *responseCacheSlot {
+	cache := responsecache.Default()
+	if cache == nil || !cache.Cacheable(rawJSON) {
+		return nil
+	}
+	slot := &responseCacheSlot{cache: cache, model: model, lookup: true, store: true}
+	owner := APIKeyOwner(coreexecutor.ClientAPIKey(ctx))
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		owner = ClientOwner(ginCtx)
+		directives := strings.ToLower(ginCtx.GetHeader("Cache-Control") + "," + ginCtx.GetHeader("Pragma"))
+		for _, directive := range strings.Split(directives, ",") {
+			switch strings.TrimSpace(directive) {
+			case "no-cache":
+				slot.lookup = false
+			case "no-store":
+				slot.lookup = false
+				slot.store = false
+			}
+		}
+	}
+	if !slot.lookup && !slot.store {
+		return nil
+	}
+	key, err := cache.Key(responsecache.Request{
+		Owner:    owner,
+		Format:   handlerType,
+		Model:    model,
+		Metadata: metadata,
+		Alt:      alt,
+		Stream:   stream,
+		Payload:  rawJSON,
+	})
+	if err != nil {
+		return nil
+	}
+	slot.key = key
+	return slot
+}
+
+// get returns the cached entry, recording the lookup in the usage statistics and the
+// X-Cache response header.
+func (s *responseCacheSlot) get(ctx context.Context) *responsecache.Entry {
+	if s == nil || !s.lookup {
+		return nil
+	}
+	entry, err := s.cache.Get(ctx, s.key)
+	if err != nil && !errors.Is(err, responsecache.ErrNotFound) {
+		log.Warnf("response cache: lookup failed: %v", err)
+	}
+	hit := err == nil && entry != nil
+	usage.GetRequestStatistics().RecordCacheLookup(s.model, hit)
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		if hit {
+			ginCtx.Header(cacheHeader, "HIT")
+		} else {
+			ginCtx.Header(cacheHeader, "MISS")
+		}
+	}
+	if !hit {
+		return nil
+	}
+	return entry
+}
+
+// put stores a successful response.
+func (s *responseCacheSlot) put(ctx context.Context, entry *responsecache.Entry) {
+	if s == nil || !s.store {
+		return
+	}
+	if err := s.cache.Put(context.WithoutCancel(ctx), s.key, entry); err != nil {
+		log.Warnf("response cache: store failed: %v", err)
+	}
+}
+
+// streamRecording collects the chunks of a streamed response for the cache. Once the
+// chunks outgrow the cache's entry limit they are released, since Put would reject them.
+type streamRecording struct {
+	slot   *responseCacheSlot
+	chunks [][]byte
+	size   int
+}
+
+// recording returns the recorder of a streamed response, or nil when it is not stored.
+func (s *responseCacheSlot) recording() *streamRecording {
+	if s == nil || !s.store {
+		return nil
+	}
+	return &streamRecording{slot: s}
+}
+
+// add records a chunk unless the response has grown past the entry limit.
+func (r *streamRecording) add(payload []byte) {
+	if r == nil {
+		return
+	}
+	r.size += len(payload)
+	if r.size > r.slot.cache.MaxEntryBytes() {
+		r.chunks = nil
+		return
+	}
+	r.chunks = append(r.chunks, cloneBytes(payload))
+}
+
+// put stores the recorded stream once it completed successfully.
+func (r *streamRecording) put(ctx context.Context) {
+	if r == nil || len(r.chunks) == 0 {
+		return
+	}
+	r.slot.put(ctx, &responsecache.Entry{Chunks: r.chunks})
+}
+
+// replayStream emits cached stream chunks through the channels returned by
+// ExecuteStreamWithAuthManager.
+func replayStream(ctx context.Context, entry *responsecache.Entry) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	dataChan := make(chan []byte)
+	errChan := make(chan *interfaces.ErrorMessage)
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+		for _, chunk := range entry.Chunks {
+			select {
+			case <-ctx.Done():
+				return
+			case dataChan <- cloneBytes(chunk):
+			}
+		}
+	}()
+	return dataChan, errChan
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responsecache"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+type countingExecutor struct {
+	calls atomic.Int32
+}
+
+func (e *countingExecutor) Identifier() string { return "cache-test" }
+
+func (e *countingExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	e.calls.Add(1)
+	return coreexecutor.Response{Payload: []byte(`{"answer":42}`)}, nil
+}
+
+func (e *countingExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	e.calls.Add(1)
+	ch := make(chan coreexecutor.StreamChunk, 2)
+	ch <- coreexecutor.StreamChunk{Payload: []byte("part-1")}
+	ch <- coreexecutor.StreamChunk{Payload: []byte("part-2")}
+	close(ch)
+	return ch, nil
+}
+
+func (e *countingExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *countingExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func TestResponseCacheReplaysResponses(t *testing.T) {
+	executor := &countingExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "cache-auth", Provider: "cache-test", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "cached-model"}})
+	responsecache.SetDefault(responsecache.NewCache(responsecache.NewMemoryStore(0), 0, 0, false))
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+		responsecache.SetDefault(nil)
+	})
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	ctx := context.Background()
+	payload := []byte(`{"model":"cached-model","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)
+
+	for i := 0; i < 2; i++ {
+		resp, errMsg := handler.ExecuteWithAuthManager(ctx, "openai", "cached-model", payload, "")
+		if errMsg != nil || string(resp) != `{"answer":42}` {
+			t.Fatalf("ExecuteWithAuthManager = %s, %+v", resp, errMsg)
+		}
+	}
+	if calls := executor.calls.Load(); calls != 1 {
+		t.Fatalf("non-streaming upstream calls = %d, want 1", calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		dataChan, errChan := handler.ExecuteStreamWithAuthManager(ctx, "openai", "cached-model", payload, "")
+		var got string
+		for chunk := range dataChan {
+			got += string(chunk) + "|"
+		}
+		for errMsg := range errChan {
+			if errMsg != nil {
+				t.Fatalf("stream error: %+v", errMsg)
+			}
+		}
+		if got != "part-1|part-2|" {
+			t.Fatalf("stream %d = %q", i, got)
+		}
+	}
+	if calls := executor.calls.Load(); calls != 2 {
+		t.Fatalf("total upstream calls = %d, want 2", calls)
+	}
+
+	// Non-deterministic requests always reach the upstream.
+	_, _ = handler.ExecuteWithAuthManager(ctx, "openai", "cached-model", []byte(`{"model":"cached-model","messages":[]}`), "")
+	_, _ = handler.ExecuteWithAuthManager(ctx, "openai", "cached-model", []byte(`{"model":"cached-model","messages":[]}`), "")
+	if calls := executor.calls.Load(); calls != 4 {
+		t.Fatalf("total upstream calls = %d, want 4", calls)
+	}
+}
+
+func TestResponseCacheScopedToClientKey(t *testing.T) {
+	executor := &countingExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "cache-scope-auth", Provider: "cache-test", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "scoped-model"}})
+	responsecache.SetDefault(responsecache.NewCache(responsecache.NewMemoryStore(0), 0, 0, false))
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+		responsecache.SetDefault(nil)
+	})
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	payload := []byte(`{"model":"scoped-model","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)
+
+	gin.SetMode(gin.TestMode)
+	call := func(apiKey string) string {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		c.Set("apiKey", apiKey)
+		ctx := context.WithValue(context.Background(), "gin", c)
+		if _, errMsg := handler.ExecuteWithAuthManager(ctx, "openai", "scoped-model", payload, ""); errMsg != nil {
+			t.Fatalf("ExecuteWithAuthManager: %+v", errMsg)
+		}
+		return rec.Header().Get(cacheHeader)
+	}
+	for _, tc := range []struct{ key, want string }{
+		{"key-a", "MISS"},
+		{"key-b", "MISS"},
+		{"key-a", "HIT"},
+		{"key-b", "HIT"},
+	} {
+		if got := call(tc.key); got != tc.want {
+			t.Fatalf("%s: X-Cache = %q, want %q", tc.key, got, tc.want)
+		}
+	}
+	if calls := executor.calls.Load(); calls != 2 {
+		t.Fatalf("upstream calls = %d, want one per client key", calls)
+	}
+}
+
+func TestStreamRecordingStopsAtEntryLimit(t *testing.T) {
+	slot := &responseCacheSlot{cache: responsecache.NewCache(responsecache.NewMemoryStore(0), 0, 10, false), store: true}
+	recording := slot.recording()
+	recording.add([]byte("part-1"))
+	if len(recording.chunks) != 1 {
+		t.Fatalf("recorded %d chunks below the limit, want 1", len(recording.chunks))
+	}
+	recording.add([]byte("part-2"))
+	recording.add([]byte("3"))
+	if recording.chunks != nil {
+		t.Fatalf("still holding %d chunks past the entry limit", len(recording.chunks))
+	}
+	if (&responseCacheSlot{store: false}).recording() != nil {
+		t.Fatal("no-store slot returned a recording")
+	}
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states reported by CircuitBreakerStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// errCircuitProbeRace is returned by pickNextOnce when another request took the half-open
+// probe of the selected endpoint between filtering and acquisition; pickNext retries the pick.
+var errCircuitProbeRace = &Error{Code: "circuit_probe_race", Message: "upstream circuit breaker probe already in flight", Retryable: true, HTTPStatus: http.StatusServiceUnavailable}
+
+// CircuitBreakerSettings configures the per-endpoint circuit breaker.
+type CircuitBreakerSettings struct {
+	// Enabled toggles the breaker; when false every endpoint is treated as closed.
+	Enabled bool
+	// FailureThreshold is the number of consecutive network errors or 5xx responses that open the breaker.
+	FailureThreshold int
+	// Window bounds how far apart those consecutive failures may be.
+	Window time.Duration
+	// OpenDuration is how long the breaker stays open before a half-open probe is allowed.
+	OpenDuration time.Duration
+}
+
+// CircuitBreakerStatus describes the breaker guarding one upstream endpoint.
+type CircuitBreakerStatus struct {
+	Key                 string    `json:"key"`
+	Provider            string    `json:"provider"`
+	BaseURL             string    `json:"base_url,omitempty"`
+	Proxy               string    `json:"proxy,omitempty"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+	RetryAt             time.Time `json:"retry_at,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+type circuitBreakers struct {
+	mu       sync.Mutex
+	settings CircuitBreakerSettings
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	provider     string
+	baseURL      string
+	proxy        string
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	openUntil    time.Time
+	probing      bool
+	probeStarted time.Time
+	lastError    string
+}
+
+// SetCircuitBreaker updates the circuit breaker settings. Disabling it clears all state.
+func (m *Manager) SetCircuitBreaker(settings CircuitBreakerSettings) {
+	if m == nil {
+		return
+	}
+	m.breakers.configure(settings)
+}
+
+// CircuitBreakers returns the state of every tracked upstream endpoint, ordered by key.
+func (m *Manager) CircuitBreakers() []CircuitBreakerStatus {
+	if m == nil {
+		return nil
+	}
+	return m.breakers.snapshot(time.Now())
+}
+
+// CircuitBreakerFor returns the state of the breaker guarding the auth's endpoint.
+// It returns false when the breaker is disabled or the endpoint has no recorded failures.
+func (m *Manager) CircuitBreakerFor(auth *Auth) (CircuitBreakerStatus, bool) {
+	if m == nil || auth == nil {
+		return CircuitBreakerStatus{}, false
+	}
+	return m.breakers.status(circuitKey(auth), time.Now())
+}
+
+func (b *circuitBreakers) configure(settings CircuitBreakerSettings) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.Window <= 0 {
+		settings.Window = time.Minute
+	}
+	if settings.OpenDuration <= 0 {
+		settings.OpenDuration = 30 * time.Second
+	}
+	b.settings = settings
+	if !settings.Enabled {
+		b.circuits = nil
+	}
+}
+
+// allow reports whether requests may be routed to the endpoint. A half-open circuit
+// admits traffic only while no probe is in flight. It only filters candidates; the
+// selected endpoint is admitted by tryAcquire.
+func (b *circuitBreakers) allow(key string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.settings.Enabled {
+		return true
+	}
+	c := b.circuits[key]
+	if c == nil || c.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(c.openUntil) {
+		return false
+	}
+	// A probe that never reported back must not wedge the circuit half-open.
+	return !c.probing || now.Sub(c.probeStarted) >= b.settings.OpenDuration
+}
+
+// tryAcquire admits a request routed to the endpoint. For a half-open circuit the check
+// and the probe reservation happen under one lock, so exactly one concurrent request
+// becomes the probe and the others are turned away.
+func (b *circuitBreakers) tryAcquire(key string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.settings.Enabled {
+		return true
+	}
+	c := b.circuits[key]
+	if c == nil || c.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(c.openUntil) {
+		return false
+	}
+	if c.probing && now.Sub(c.probeStarted) < b.settings.OpenDuration {
+		return false
+	}
+	c.probing = true
+	c.probeStarted = now
+	return true
+}
+
+// record updates the endpoint's circuit with an execution result.
+func (b *circuitBreakers) record(auth *Auth, result Result, now time.Time) {
+	if auth == nil {
+		return
+	}
+	key := circuitKey(auth)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.settings.Enabled {
+		return
+	}
+	c := b.circuits[key]
+	if result.Success || !isCircuitFailure(result.Error) {
+		if c == nil {
+			return
+		}
+		// A response that is not an endpoint failure proves the endpoint is reachable;
+		// stragglers finishing while the circuit is open do not close it.
+		if result.Success || c.probing || c.openUntil.IsZero() {
+			delete(b.circuits, key)
+		}
+		return
+	}
+	if c == nil {
+		if b.circuits == nil {
+			b.circuits = make(map[string]*circuit)
+		}
+		baseURL, proxy := circuitEndpoint(auth)
+		c = &circuit{provider: strings.ToLower(strings.TrimSpace(auth.Provider)), baseURL: baseURL, proxy: proxy}
+		b.circuits[key] = c
+	}
+	if result.Error != nil {
+		c.lastError = result.Error.Message
+	}
+	if c.probing {
+		// The half-open probe failed: reopen for another full period.
+		c.probing = false
+		c.openedAt = now
+		c.openUntil = now.Add(b.settings.OpenDuration)
+		return
+	}
+	if !c.openUntil.IsZero() {
+		return
+	}
+	if c.failures == 0 || now.Sub(c.firstFailure) > b.settings.Window {
+		c.failures = 0
+		c.firstFailure = now
+	}
+	c.failures++
+	if c.failures >= b.settings.FailureThreshold {
+		c.openedAt = now
+		c.openUntil = now.Add(b.settings.OpenDuration)
+	}
+}
+
+func (b *circuitBreakers) status(key string, now time.Time) (CircuitBreakerStatus, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.settings.Enabled {
+		return CircuitBreakerStatus{}, false
+	}
+	c := b.circuits[key]
+	if c == nil {
+		return CircuitBreakerStatus{}, false
+	}
+	return c.status(key, now), true
+}
+
+func (b *circuitBreakers) snapshot(now time.Time) []CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]CircuitBreakerStatus, 0, len(b.circuits))
+	for key, c := range b.circuits {
+		out = append(out, c.status(key, now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (c *circuit) status(key string, now time.Time) CircuitBreakerStatus {
+	st := CircuitBreakerStatus{
+		Key:                 key,
+		Provider:            c.provider,
+		BaseURL:             c.baseURL,
+		Proxy:               c.proxy,
+		State:               CircuitClosed,
+		ConsecutiveFailures: c.failures,
+		LastError:           c.lastError,
+	}
+	if !c.openUntil.IsZero() {
+		st.OpenedAt = c.openedAt
+		st.RetryAt = c.openUntil
+		if now.Before(c.openUntil) {
+			st.State = CircuitOpen
+		} else {
+			st.State = CircuitHalfOpen
+		}
+	}
+	return st
+}
+
+// isCircuitFailure reports whether an error indicates the endpoint itself is unhealthy:
+// transport errors (no HTTP status) or 5xx responses.
+func isCircuitFailure(err *Error) bool {
+	status := statusCodeFromResult(err)
+	return status == 0 || status >= http.StatusInternalServerError
+}
+
+// circuitKey identifies the upstream endpoint an auth talks to: its provider,
+// base URL and proxy. Auths sharing all three share a breaker.
+func circuitKey(auth *Auth) string {
+	baseURL, proxy := circuitEndpoint(auth)
+	return strings.ToLower(strings.TrimSpace(auth.Provider)) + "|" + baseURL + "|" + proxy
+}
+
+func circuitEndpoint(auth *Auth) (baseURL, proxy string) {
+	if auth == nil {
+		return "", ""
+	}
+	if auth.Attributes != nil {
+		baseURL = strings.TrimRight(strings.TrimSpace(auth.Attributes["base_url"]), "/")
+	}
+	proxy = redactURLUserinfo(strings.TrimSpace(auth.ProxyURL))
+	return baseURL, proxy
+}
+
+// redactURLUserinfo strips credentials from proxy URLs so they never surface in reports.
+func redactURLUserinfo(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw
+	}
+	parsed.User = nil
+	return parsed.String()
+}
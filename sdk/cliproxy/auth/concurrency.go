@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// defaultConcurrencyQueueSize bounds the number of requests waiting for a free credential.
+	defaultConcurrencyQueueSize = 64
+	// defaultConcurrencyQueueTimeout is how long a queued request waits for a free credential.
+	defaultConcurrencyQueueTimeout = 30 * time.Second
+)
+
+// errConcurrencySaturated is returned by pickNextOnce when every eligible credential is at
+// its concurrency limit; pickNext turns it into a queued wait.
+var errConcurrencySaturated = &Error{Code: "concurrency_saturated", Message: "all credentials are at their concurrency limit", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+
+// errConcurrencyRace is returned by pickNextOnce when the selected credential filled up
+// between filtering and acquisition; pickNext retries the pick immediately.
+var errConcurrencyRace = &Error{Code: "concurrency_race", Message: "selected credential reached its concurrency limit", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+
+// ConcurrencySettings configures per-credential in-flight limits and the wait queue used
+// when every credential is saturated.
+type ConcurrencySettings struct {
+	// ProviderLimits maps a provider key to the default limit for its credentials.
+	// A credential's own max_concurrency takes precedence; zero means unlimited.
+	ProviderLimits map[string]int
+	// QueueSize is the maximum number of waiting requests. Defaults to 64.
+	QueueSize int
+	// QueueTimeout is how long a request waits for a free credential. Defaults to 30s.
+	QueueTimeout time.Duration
+}
+
+// concurrencyLimiter tracks in-flight requests per auth and wakes queued requests when
+// a slot is released.
+type concurrencyLimiter struct {
+	mu             sync.Mutex
+	providerLimits map[string]int
+	queueSize      int
+	queueTimeout   time.Duration
+	inflight       map[string]int
+	waiting        int
+	// released is closed and replaced on every release to broadcast to waiters.
+	released chan struct{}
+}
+
+// SetConcurrency updates the concurrency limits. In-flight counts are kept across updates.
+func (m *Manager) SetConcurrency(settings ConcurrencySettings) {
+	if m == nil {
+		return
+	}
+	m.concurrency.configure(settings)
+}
+
+// InFlight returns the number of requests currently executing on the auth.
+func (m *Manager) InFlight(authID string) int {
+	if m == nil {
+		return 0
+	}
+	l := &m.concurrency
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight[authID]
+}
+
+// ConcurrencyLimit returns the effective in-flight limit of the auth, or zero when unlimited.
+func (m *Manager) ConcurrencyLimit(auth *Auth) int {
+	if m == nil || auth == nil {
+		return 0
+	}
+	l := &m.concurrency
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limitLocked(auth)
+}
+
+func (l *concurrencyLimiter) configure(settings ConcurrencySettings) {
+	if settings.QueueSize <= 0 {
+		settings.QueueSize = defaultConcurrencyQueueSize
+	}
+	if settings.QueueTimeout <= 0 {
+		settings.QueueTimeout = defaultConcurrencyQueueTimeout
+	}
+	var limits map[string]int
+	for provider, limit := range settings.ProviderLimits {
+		key := strings.ToLower(strings.TrimSpace(provider))
+		if key == "" || limit <= 0 {
+			continue
+		}
+		if limits == nil {
+			limits = make(map[string]int, len(settings.ProviderLimits))
+		}
+		limits[key] = limit
+	}
+	l.mu.Lock()
+	l.providerLimits = limits
+	l.queueSize = settings.QueueSize
+	l.queueTimeout = settings.QueueTimeout
+	l.mu.Unlock()
+	// Raised limits may admit requests that are already queued.
+	l.broadcast()
+}
+
+func (l *concurrencyLimiter) limitLocked(auth *Auth) int {
+	if limit := auth.MaxConcurrency(); limit > 0 {
+		return limit
+	}
+	return l.providerLimits[strings.ToLower(strings.TrimSpace(auth.Provider))]
+}
+
+// saturated reports whether the auth has reached its in-flight limit.
+func (l *concurrencyLimiter) saturated(auth *Auth) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit := l.limitLocked(auth)
+	return limit > 0 && l.inflight[auth.ID] >= limit
+}
+
+// tryAcquire takes an in-flight slot on the auth. Unlimited auths always succeed so
+// their in-flight count is still reported.
+func (l *concurrencyLimiter) tryAcquire(auth *Auth) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit := l.limitLocked(auth); limit > 0 && l.inflight[auth.ID] >= limit {
+		return false
+	}
+	if l.inflight == nil {
+		l.inflight = make(map[string]int)
+	}
+	l.inflight[auth.ID]++
+	return true
+}
+
+// release returns an in-flight slot taken by tryAcquire and wakes queued requests.
+func (l *concurrencyLimiter) release(authID string) {
+	l.mu.Lock()
+	if n := l.inflight[authID]; n > 1 {
+		l.inflight[authID] = n - 1
+	} else {
+		delete(l.inflight, authID)
+	}
+	l.mu.Unlock()
+	l.broadcast()
+}
+
+func (l *concurrencyLimiter) broadcast() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released != nil {
+		close(l.released)
+		l.released = nil
+	}
+}
+
+// releaseSignal returns a channel closed on the next release. It must be obtained before
+// checking for a free slot so that a release in between is not missed.
+func (l *concurrencyLimiter) releaseSignal() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released == nil {
+		l.released = make(chan struct{})
+	}
+	return l.released
+}
+
+// enqueue reserves a place in the wait queue and returns the queue timeout.
+func (l *concurrencyLimiter) enqueue() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queueSize, timeout := l.queueSize, l.queueTimeout
+	if queueSize <= 0 {
+		queueSize = defaultConcurrencyQueueSize
+	}
+	if timeout <= 0 {
+		timeout = defaultConcurrencyQueueTimeout
+	}
+	if l.waiting >= queueSize {
+		return 0, false
+	}
+	l.waiting++
+	return timeout, true
+}
+
+func (l *concurrencyLimiter) dequeue() {
+	l.mu.Lock()
+	l.waiting--
+	l.mu.Unlock()
+}
+
+// pickNext selects a credential and takes an in-flight slot on it. When every eligible
+// credential is saturated the request waits in the bounded queue until a slot is released,
+// the queue timeout elapses, or the context is cancelled.
+func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (picked *Auth, pickedExecutor ProviderExecutor, errPick error) {
+	ctx, span := startSpan(ctx, "cliproxy.pick_auth", tracing.AttrProvider.String(provider), tracing.AttrModel.String(model))
+	defer func() {
+		if picked != nil {
+			span.SetAttributes(tracing.AttrAuthIndex.String(picked.Index))
+		}
+		tracing.End(span, errPick)
+	}()
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var timer *time.Timer
+	for {
+		signal := m.concurrency.releaseSignal()
+		auth, executor, err := m.pickNextOnce(ctx, provider, model, opts, tried)
+		if err != errConcurrencySaturated {
+			if err == errConcurrencyRace || err == errCircuitProbeRace {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+				m.concurrency.dequeue()
+			}
+			return auth, executor, err
+		}
+		if timer == nil {
+			timeout, ok := m.concurrency.enqueue()
+			if !ok {
+				return nil, nil, &Error{Code: "concurrency_queue_full", Message: "all credentials are busy and the request queue is full", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+			}
+			timer = time.NewTimer(timeout)
+		}
+		select {
+		case <-signal:
+		case <-timer.C:
+			m.concurrency.dequeue()
+			return nil, nil, &Error{Code: "concurrency_timeout", Message: "timed out waiting for a free credential", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+		case <-done:
+			timer.Stop()
+			m.concurrency.dequeue()
+			return nil, nil, ctx.Err()
+		}
+	}
+}
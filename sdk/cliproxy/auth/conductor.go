@@ -14,9 +14,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ProviderExecutor defines the contract required by Manager to execute provider calls.
@@ -65,8 +68,17 @@ type Result struct {
 	Success bool
 	// RetryAfter carries a provider supplied retry hint (e.g. 429 retryDelay).
 	RetryAfter *time.Duration
+	// Latency is the time until the upstream produced its first byte; for
+	// non-streaming calls this is the full response time. Zero when unknown.
+	Latency time.Duration
 	// Error describes the failure when Success is false.
 	Error *Error
+	// Cooldown is the time the credential (or its model) is blocked after this failure.
+	// MarkResult fills it before notifying hooks.
+	Cooldown time.Duration
+	// CooldownReason names the cause of Cooldown (unauthorized, payment_required,
+	// not_found, quota or transient).
+	CooldownReason string
 }
 
 // Selector chooses an auth candidate for execution.
@@ -114,6 +126,21 @@ type Manager struct {
 	// modelNameMappings stores global model name alias mappings (alias -> upstream name) keyed by channel.
 	modelNameMappings atomic.Value
 
+	// modelFallbacks stores the compiled model fallback chains.
+	modelFallbacks atomic.Value
+
+	// sessions pins client sessions to credentials for prompt-cache reuse.
+	sessions sessionAffinity
+
+	// breakers guards upstream endpoints (provider, base URL, proxy) that keep failing.
+	breakers circuitBreakers
+
+	// hedging fires a second attempt for slow non-streaming requests.
+	hedging hedger
+
+	// concurrency bounds in-flight requests per credential and queues the overflow.
+	concurrency concurrencyLimiter
+
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
@@ -214,7 +241,7 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 	m.auths[auth.ID] = auth.Clone()
 	m.mu.Unlock()
 	_ = m.persist(ctx, auth)
-	m.hook.OnAuthRegistered(ctx, auth.Clone())
+	m.currentHook().OnAuthRegistered(ctx, auth.Clone())
 	return auth.Clone(), nil
 }
 
@@ -230,9 +257,13 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	}
 	auth.EnsureIndex()
 	m.auths[auth.ID] = auth.Clone()
+	selector := m.selector
 	m.mu.Unlock()
+	if forgetter, ok := selector.(AuthForgetter); ok && forgetter != nil && auth.Disabled {
+		forgetter.ForgetAuth(auth.ID)
+	}
 	_ = m.persist(ctx, auth)
-	m.hook.OnAuthUpdated(ctx, auth.Clone())
+	m.currentHook().OnAuthUpdated(ctx, auth.Clone())
 	return auth.Clone(), nil
 }
 
@@ -260,7 +291,37 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When every credential of the model fails, the configured model fallback chain is tried in order.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	ctx, span := startSpan(ctx, "cliproxy.execute", tracing.AttrModel.String(req.Model))
+	out, err := m.execute(ctx, providers, req, opts)
+	tracing.End(span, err)
+	return out, err
+}
+
+func (m *Manager) execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	resp, errExec := m.executeModel(ctx, providers, req, opts)
+	if errExec == nil {
+		return resp, nil
+	}
+	for _, fallback := range m.fallbackChain(req.Model) {
+		if !shouldFallback(ctx, errExec) {
+			break
+		}
+		fbProviders, fbReq, fbOpts, ok := fallbackRequest(fallback, req, opts)
+		if !ok {
+			continue
+		}
+		logEntryWithRequestID(ctx).Infof("model %s unavailable (%v), falling back to %s", req.Model, errExec, fbReq.Model)
+		resp, errExec = m.executeModel(ctx, fbProviders, fbReq, fbOpts)
+		if errExec == nil {
+			return resp, nil
+		}
+	}
+	return cliproxyexecutor.Response{}, errExec
+}
+
+func (m *Manager) executeModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -275,9 +336,11 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
-		resp, errExec := m.executeProvidersOnce(ctx, rotated, func(execCtx context.Context, provider string) (cliproxyexecutor.Response, error) {
+		attemptCtx, attemptSpan := startSpan(ctx, "cliproxy.attempt", tracing.AttrModel.String(req.Model), attribute.Int("cliproxy.attempt", attempt+1))
+		resp, errExec := m.executeProvidersOnce(attemptCtx, rotated, func(execCtx context.Context, provider string) (cliproxyexecutor.Response, error) {
 			return m.executeWithProvider(execCtx, provider, req, opts)
 		})
+		tracing.End(attemptSpan, errExec)
 		if errExec == nil {
 			return resp, nil
 		}
@@ -286,6 +349,7 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
@@ -299,6 +363,13 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 // ExecuteCount performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
 func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	ctx, span := startSpan(ctx, "cliproxy.count_tokens", tracing.AttrModel.String(req.Model))
+	out, err := m.executeCount(ctx, providers, req, opts)
+	tracing.End(span, err)
+	return out, err
+}
+
+func (m *Manager) executeCount(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -313,9 +384,11 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
-		resp, errExec := m.executeProvidersOnce(ctx, rotated, func(execCtx context.Context, provider string) (cliproxyexecutor.Response, error) {
+		attemptCtx, attemptSpan := startSpan(ctx, "cliproxy.attempt", tracing.AttrModel.String(req.Model), attribute.Int("cliproxy.attempt", attempt+1))
+		resp, errExec := m.executeProvidersOnce(attemptCtx, rotated, func(execCtx context.Context, provider string) (cliproxyexecutor.Response, error) {
 			return m.executeCountWithProvider(execCtx, provider, req, opts)
 		})
+		tracing.End(attemptSpan, errExec)
 		if errExec == nil {
 			return resp, nil
 		}
@@ -324,6 +397,7 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
@@ -336,7 +410,38 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When every credential of the model fails before the stream starts, the configured model
+// fallback chain is tried in order.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	ctx, span := startSpan(ctx, "cliproxy.execute_stream", tracing.AttrModel.String(req.Model))
+	out, err := m.executeStream(ctx, providers, req, opts)
+	tracing.End(span, err)
+	return out, err
+}
+
+func (m *Manager) executeStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	chunks, errStream := m.executeStreamModel(ctx, providers, req, opts)
+	if errStream == nil {
+		return chunks, nil
+	}
+	for _, fallback := range m.fallbackChain(req.Model) {
+		if !shouldFallback(ctx, errStream) {
+			break
+		}
+		fbProviders, fbReq, fbOpts, ok := fallbackRequest(fallback, req, opts)
+		if !ok {
+			continue
+		}
+		logEntryWithRequestID(ctx).Infof("model %s unavailable (%v), falling back to %s", req.Model, errStream, fbReq.Model)
+		chunks, errStream = m.executeStreamModel(ctx, fbProviders, fbReq, fbOpts)
+		if errStream == nil {
+			return chunks, nil
+		}
+	}
+	return nil, errStream
+}
+
+func (m *Manager) executeStreamModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -351,9 +456,11 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
-		chunks, errStream := m.executeStreamProvidersOnce(ctx, rotated, func(execCtx context.Context, provider string) (<-chan cliproxyexecutor.StreamChunk, error) {
+		attemptCtx, attemptSpan := startSpan(ctx, "cliproxy.attempt", tracing.AttrModel.String(req.Model), attribute.Int("cliproxy.attempt", attempt+1))
+		chunks, errStream := m.executeStreamProvidersOnce(attemptCtx, rotated, func(execCtx context.Context, provider string) (<-chan cliproxyexecutor.StreamChunk, error) {
 			return m.executeStreamWithProvider(execCtx, provider, req, opts)
 		})
+		tracing.End(attemptSpan, errStream)
 		if errStream == nil {
 			return chunks, nil
 		}
@@ -362,6 +469,7 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 		if !shouldRetry {
 			break
 		}
+		m.notifyRetry(ctx, req.Model, attempt+1, wait)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return nil, errWait
 		}
@@ -387,53 +495,74 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			}
 			return cliproxyexecutor.Response{}, errPick
 		}
+		tried[auth.ID] = struct{}{}
 
-		accountType, accountInfo := auth.AccountInfo()
-		proxyInfo := auth.ProxyInfo()
-		entry := logEntryWithRequestID(ctx)
-		if accountType == "api_key" {
-			if proxyInfo != "" {
-				entry.Debugf("Use API key %s for model %s %s", util.HideAPIKey(accountInfo), req.Model, proxyInfo)
-			} else {
-				entry.Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
-			}
-		} else if accountType == "oauth" {
-			if proxyInfo != "" {
-				entry.Debugf("Use OAuth %s for model %s %s", accountInfo, req.Model, proxyInfo)
-			} else {
-				entry.Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
+		if delay, ok := m.hedgeDelay(routeModel, opts); ok {
+			resp, errExec := m.executeHedged(ctx, provider, routeModel, auth, executor, req, opts, tried, delay)
+			if errExec == nil {
+				return resp, nil
 			}
+			lastErr = errExec
+			continue
 		}
 
-		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-		execReq := req
-		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
-		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
-		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
-		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
+		resp, result, errExec := m.executeAttempt(ctx, provider, routeModel, auth, executor, req, opts)
+		m.concurrency.release(auth.ID)
+		m.MarkResult(ctx, result)
 		if errExec != nil {
-			result.Error = &Error{Message: errExec.Error()}
-			var se cliproxyexecutor.StatusError
-			if errors.As(errExec, &se) && se != nil {
-				result.Error.HTTPStatus = se.StatusCode()
-			}
-			if ra := retryAfterFromError(errExec); ra != nil {
-				result.RetryAfter = ra
-			}
-			m.MarkResult(execCtx, result)
 			lastErr = errExec
 			continue
 		}
-		m.MarkResult(execCtx, result)
 		return resp, nil
 	}
 }
 
+// executeAttempt runs one non-streaming call against the selected auth and returns the
+// outcome without recording it, so callers decide whether it counts.
+func (m *Manager) executeAttempt(ctx context.Context, provider, routeModel string, auth *Auth, executor ProviderExecutor, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, Result, error) {
+	accountType, accountInfo := auth.AccountInfo()
+	proxyInfo := auth.ProxyInfo()
+	entry := logEntryWithRequestID(ctx)
+	if accountType == "api_key" {
+		if proxyInfo != "" {
+			entry.Debugf("Use API key %s for model %s %s", util.HideAPIKey(accountInfo), req.Model, proxyInfo)
+		} else {
+			entry.Debugf("Use API key %s for model %s", util.HideAPIKey(accountInfo), req.Model)
+		}
+	} else if accountType == "oauth" {
+		if proxyInfo != "" {
+			entry.Debugf("Use OAuth %s for model %s %s", accountInfo, req.Model, proxyInfo)
+		} else {
+			entry.Debugf("Use OAuth %s for model %s", accountInfo, req.Model)
+		}
+	}
+
+	execCtx := ctx
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	execReq := req
+	execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
+	execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
+	execCtx, span := startSpan(execCtx, "cliproxy.executor.execute", executorSpanAttributes(provider, execReq.Model, auth)...)
+	started := time.Now()
+	resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+	tracing.End(span, errExec)
+	result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil, Latency: time.Since(started)}
+	if errExec != nil {
+		result.Error = &Error{Message: errExec.Error()}
+		var se cliproxyexecutor.StatusError
+		if errors.As(errExec, &se) && se != nil {
+			result.Error.HTTPStatus = se.StatusCode()
+		}
+		if ra := retryAfterFromError(errExec); ra != nil {
+			result.RetryAfter = ra
+		}
+	}
+	return resp, result, errExec
+}
+
 func (m *Manager) executeCountWithProvider(ctx context.Context, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	if provider == "" {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
@@ -476,7 +605,10 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 		execReq := req
 		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
 		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
+		execCtx, span := startSpan(execCtx, "cliproxy.executor.count_tokens", executorSpanAttributes(provider, execReq.Model, auth)...)
 		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
+		tracing.End(span, errExec)
+		m.concurrency.release(auth.ID)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -538,39 +670,66 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 		execReq := req
 		execReq.Model, execReq.Metadata = rewriteModelForAuth(routeModel, req.Metadata, auth)
 		execReq.Model, execReq.Metadata = m.applyOAuthModelMapping(auth, execReq.Model, execReq.Metadata)
+		execCtx, span := startSpan(tracing.WithTranslationStats(execCtx), "cliproxy.executor.stream", executorSpanAttributes(provider, execReq.Model, auth)...)
+		started := time.Now()
 		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
 		if errStream != nil {
+			tracing.End(span, errStream)
+			m.concurrency.release(auth.ID)
 			rerr := &Error{Message: errStream.Error()}
 			var se cliproxyexecutor.StatusError
 			if errors.As(errStream, &se) && se != nil {
 				rerr.HTTPStatus = se.StatusCode()
 			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr}
+			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr, Latency: time.Since(started)}
 			result.RetryAfter = retryAfterFromError(errStream)
 			m.MarkResult(execCtx, result)
 			lastErr = errStream
 			continue
 		}
 		out := make(chan cliproxyexecutor.StreamChunk)
-		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
+		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk, streamSpan trace.Span) {
 			defer close(out)
+			defer m.concurrency.release(streamAuth.ID)
 			var failed bool
+			var streamErr error
+			var ttfb time.Duration
+			var count int64
+			defer func() {
+				streamSpan.SetAttributes(attribute.Int64("cliproxy.stream.chunks", count))
+				tracing.AnnotateTranslation(streamCtx, streamSpan)
+				tracing.End(streamSpan, streamErr)
+			}()
 			for chunk := range streamChunks {
+				count++
+				if ttfb == 0 {
+					ttfb = time.Since(started)
+					streamSpan.AddEvent("first chunk")
+				}
 				if chunk.Err != nil && !failed {
 					failed = true
+					streamErr = chunk.Err
 					rerr := &Error{Message: chunk.Err.Error()}
 					var se cliproxyexecutor.StatusError
 					if errors.As(chunk.Err, &se) && se != nil {
 						rerr.HTTPStatus = se.StatusCode()
 					}
-					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr})
+					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr, Latency: ttfb})
+				}
+				select {
+				case out <- chunk:
+				case <-streamCtx.Done():
+					// The consumer is gone; drain the upstream so the executor can finish
+					// and the credential's in-flight slot is returned.
+					for range streamChunks {
+					}
+					return
 				}
-				out <- chunk
 			}
 			if !failed {
-				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true})
+				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true, Latency: ttfb})
 			}
-		}(execCtx, auth.Clone(), provider, chunks)
+		}(execCtx, auth.Clone(), provider, chunks, span)
 		return out, nil
 	}
 }
@@ -744,6 +903,8 @@ func waitForCooldown(ctx context.Context, wait time.Duration) error {
 	if wait <= 0 {
 		return nil
 	}
+	_, span := startSpan(ctx, "cliproxy.cooldown_wait", attribute.Float64("cliproxy.wait_ms", float64(wait)/float64(time.Millisecond)))
+	defer span.End()
 	timer := time.NewTimer(wait)
 	defer timer.Stop()
 	select {
@@ -880,19 +1041,37 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 				default:
 					state.NextRetryAfter = time.Time{}
 				}
+				if state.NextRetryAfter.After(now) {
+					result.Cooldown = state.NextRetryAfter.Sub(now)
+					result.CooldownReason = cooldownReason(statusCode)
+				}
 
 				auth.Status = StatusError
 				auth.UpdatedAt = now
 				updateAggregatedAvailability(auth, now)
 			} else {
 				applyAuthFailureState(auth, result.Error, result.RetryAfter, now)
+				if auth.NextRetryAfter.After(now) {
+					result.Cooldown = auth.NextRetryAfter.Sub(now)
+					result.CooldownReason = cooldownReason(statusCodeFromResult(result.Error))
+				}
 			}
 		}
+		if ctx == nil || ctx.Err() == nil {
+			// Client cancellations say nothing about the endpoint's health.
+			m.breakers.record(auth, result, now)
+		}
 
 		_ = m.persist(ctx, auth)
 	}
+	selector := m.selector
+	hook := m.hook
 	m.mu.Unlock()
 
+	if observer, ok := selector.(ResultObserver); ok && observer != nil {
+		observer.ObserveResult(result)
+	}
+
 	if clearModelQuota && result.Model != "" {
 		registry.GetGlobalRegistry().ClearModelQuotaExceeded(result.AuthID, result.Model)
 	}
@@ -905,7 +1084,9 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
 
-	m.hook.OnResult(ctx, result)
+	if hook != nil {
+		hook.OnResult(ctx, result)
+	}
 }
 
 func ensureModelState(auth *Auth, model string) *ModelState {
@@ -1164,6 +1345,22 @@ func (m *Manager) List() []*Auth {
 	return list
 }
 
+// SelectorScores returns the ranking statistics the active selector keeps for an auth.
+// It returns nil when the selector does not report scores.
+func (m *Manager) SelectorScores(authID string) []AdaptiveScore {
+	if m == nil || authID == "" {
+		return nil
+	}
+	m.mu.RLock()
+	selector := m.selector
+	m.mu.RUnlock()
+	reporter, ok := selector.(ScoreReporter)
+	if !ok || reporter == nil {
+		return nil
+	}
+	return reporter.Scores(authID)
+}
+
 // GetByID retrieves an auth entry by its ID.
 
 func (m *Manager) GetByID(id string) (*Auth, bool) {
@@ -1179,7 +1376,10 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
-func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+// pickNextOnce selects a credential without waiting and takes an in-flight slot on it.
+// Saturated credentials are skipped; errConcurrencySaturated reports that they were the
+// only ones left, or that the credential the session is pinned to is busy.
+func (m *Manager) pickNextOnce(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
 	if !okExecutor {
@@ -1189,6 +1389,12 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	candidates := make([]*Auth, 0, len(m.auths))
 	modelKey := strings.TrimSpace(model)
 	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+	sessionKey := sessionAffinityKey(ctx, provider, model, opts)
+	pinnedID, hasPin := m.sessions.lookup(sessionKey, now)
+	pinHeld := hasPin && sessionPinHolds(m.auths[pinnedID], provider, modelKey, now)
+	var pinned *Auth
+	circuitOpen, saturated := 0, 0
 	for _, candidate := range m.auths {
 		if candidate.Provider != provider || candidate.Disabled {
 			continue
@@ -1199,20 +1405,61 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if !m.breakers.allow(circuitKey(candidate), now) {
+			circuitOpen++
+			continue
+		}
+		if m.concurrency.saturated(candidate) {
+			if pinHeld && candidate.ID == pinnedID {
+				// A busy pinned credential is waited for rather than leaving its session.
+				m.mu.RUnlock()
+				return nil, nil, errConcurrencySaturated
+			}
+			saturated++
+			continue
+		}
+		if pinHeld && candidate.ID == pinnedID {
+			pinned = candidate
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
+		if saturated > 0 {
+			return nil, nil, errConcurrencySaturated
+		}
+		if circuitOpen > 0 {
+			return nil, nil, &Error{Code: "circuit_open", Message: "upstream circuit breaker is open", Retryable: true, HTTPStatus: http.StatusServiceUnavailable}
+		}
 		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
-	if errPick != nil {
+	selected := pinned
+	if selected == nil {
+		var errPick error
+		selected, errPick = m.selector.Pick(ctx, provider, model, opts, candidates)
+		if errPick != nil {
+			m.mu.RUnlock()
+			return nil, nil, errPick
+		}
+		if selected == nil {
+			m.mu.RUnlock()
+			return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		}
+	}
+	if pinHeld {
+		// Requests spilling off a held pin are served elsewhere without moving the session.
+		m.sessions.pin(sessionKey, pinnedID, now)
+	} else {
+		m.sessions.pin(sessionKey, selected.ID, now)
+	}
+	if !m.concurrency.tryAcquire(selected) {
 		m.mu.RUnlock()
-		return nil, nil, errPick
+		return nil, nil, errConcurrencyRace
 	}
-	if selected == nil {
+	if !m.breakers.tryAcquire(circuitKey(selected), now) {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		m.concurrency.release(selected.ID)
+		return nil, nil, errCircuitProbeRace
 	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
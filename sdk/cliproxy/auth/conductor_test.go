@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// scriptedExecutor answers every call with the configured status; zero status succeeds
+// and echoes the requested model as the payload.
+type scriptedExecutor struct {
+	provider string
+	status   int
+
+	mu     sync.Mutex
+	models []string
+	opts   []cliproxyexecutor.Options
+}
+
+func (e *scriptedExecutor) Identifier() string { return e.provider }
+
+func (e *scriptedExecutor) record(req cliproxyexecutor.Request, opts cliproxyexecutor.Options) {
+	e.mu.Lock()
+	e.models = append(e.models, req.Model)
+	e.opts = append(e.opts, opts)
+	e.mu.Unlock()
+}
+
+func (e *scriptedExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.record(req, opts)
+	if e.status != 0 {
+		return cliproxyexecutor.Response{}, &Error{Code: "upstream", Message: http.StatusText(e.status), HTTPStatus: e.status}
+	}
+	return cliproxyexecutor.Response{Payload: []byte(req.Model)}, nil
+}
+
+func (e *scriptedExecutor) ExecuteStream(_ context.Context, _ *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.record(req, opts)
+	if e.status != 0 {
+		return nil, &Error{Code: "upstream", Message: http.StatusText(e.status), HTTPStatus: e.status}
+	}
+	ch := make(chan cliproxyexecutor.StreamChunk, 1)
+	ch <- cliproxyexecutor.StreamChunk{Payload: []byte(req.Model)}
+	close(ch)
+	return ch, nil
+}
+
+func (e *scriptedExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *scriptedExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func registerTestAuth(t *testing.T, m *Manager, id, provider string, models ...string) {
+	t.Helper()
+	if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: provider, Status: StatusActive}); err != nil {
+		t.Fatalf("Register(%s) error = %v", id, err)
+	}
+	infos := make([]*registry.ModelInfo, 0, len(models))
+	for _, model := range models {
+		infos = append(infos, &registry.ModelInfo{ID: model})
+	}
+	registry.GetGlobalRegistry().RegisterClient(id, provider, infos)
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+}
+
+func TestManagerExecute_ModelFallback(t *testing.T) {
+	primary := &scriptedExecutor{provider: "fallback-test-primary", status: http.StatusTooManyRequests}
+	backup := &scriptedExecutor{provider: "fallback-test-backup"}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(primary)
+	m.RegisterExecutor(backup)
+	registerTestAuth(t, m, "fallback-primary-1", primary.provider, "fallback-primary-model")
+	registerTestAuth(t, m, "fallback-primary-2", primary.provider, "fallback-primary-model")
+	registerTestAuth(t, m, "fallback-backup-1", backup.provider, "fallback-backup-model")
+	m.SetModelFallbacks(map[string][]string{
+		"Fallback-Primary-Model": {"fallback-missing-model", "fallback-backup-model"},
+	})
+
+	req := cliproxyexecutor.Request{Model: "fallback-primary-model"}
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{SessionKeyMetadataKey: "conv"}}
+	resp, err := m.Execute(context.Background(), []string{primary.provider}, req, opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "fallback-backup-model" {
+		t.Fatalf("Execute() payload = %q, want fallback model", resp.Payload)
+	}
+	if len(primary.models) != 2 {
+		t.Fatalf("primary attempts = %d, want every credential tried once", len(primary.models))
+	}
+	got := backup.opts[0].Metadata
+	if got[FallbackFromMetadataKey] != "fallback-primary-model" || got[SessionKeyMetadataKey] != "conv" {
+		t.Fatalf("fallback metadata = %v, want fallback_from and preserved session key", got)
+	}
+}
+
+func TestManagerExecute_ModelFallbackSkipsClientErrors(t *testing.T) {
+	primary := &scriptedExecutor{provider: "fallback-client-primary", status: http.StatusBadRequest}
+	backup := &scriptedExecutor{provider: "fallback-client-backup"}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(primary)
+	m.RegisterExecutor(backup)
+	registerTestAuth(t, m, "fallback-client-primary-1", primary.provider, "fallback-client-primary-model")
+	registerTestAuth(t, m, "fallback-client-backup-1", backup.provider, "fallback-client-backup-model")
+	m.SetModelFallbacks(map[string][]string{"fallback-client-primary-model": {"fallback-client-backup-model"}})
+
+	_, err := m.Execute(context.Background(), []string{primary.provider}, cliproxyexecutor.Request{Model: "fallback-client-primary-model"}, cliproxyexecutor.Options{})
+	if err == nil {
+		t.Fatal("Execute() expected client error to be returned")
+	}
+	if len(backup.models) != 0 {
+		t.Fatalf("backup attempts = %d, want none for a client error", len(backup.models))
+	}
+}
+
+func TestManagerExecuteStream_ModelFallback(t *testing.T) {
+	primary := &scriptedExecutor{provider: "fallback-stream-primary", status: http.StatusServiceUnavailable}
+	backup := &scriptedExecutor{provider: "fallback-stream-backup"}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(primary)
+	m.RegisterExecutor(backup)
+	registerTestAuth(t, m, "fallback-stream-primary-1", primary.provider, "fallback-stream-primary-model")
+	registerTestAuth(t, m, "fallback-stream-backup-1", backup.provider, "fallback-stream-backup-model")
+	m.SetModelFallbacks(map[string][]string{"fallback-stream-primary-model": {"fallback-stream-backup-model"}})
+
+	chunks, err := m.ExecuteStream(context.Background(), []string{primary.provider}, cliproxyexecutor.Request{Model: "fallback-stream-primary-model"}, cliproxyexecutor.Options{Stream: true})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var payload []byte
+	for chunk := range chunks {
+		payload = append(payload, chunk.Payload...)
+	}
+	if string(payload) != "fallback-stream-backup-model" {
+		t.Fatalf("ExecuteStream() payload = %q, want fallback model", payload)
+	}
+}
+
+func TestManagerCircuitBreaker_OpensAndProbes(t *testing.T) {
+	upstream := &scriptedExecutor{provider: "breaker-test", status: http.StatusBadGateway}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(upstream)
+	m.SetCircuitBreaker(CircuitBreakerSettings{Enabled: true, FailureThreshold: 2, Window: time.Minute, OpenDuration: 50 * time.Millisecond})
+	for _, id := range []string{"breaker-1", "breaker-2", "breaker-3"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: upstream.provider, Attributes: map[string]string{"base_url": "https://relay.example.com/v1/"}}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+
+	// Two auths fail in a row; the shared endpoint trips before the third is ever tried.
+	if _, err := m.Execute(context.Background(), []string{upstream.provider}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); statusCodeFromError(err) != http.StatusBadGateway {
+		t.Fatalf("Execute() error = %v, want the upstream 502", err)
+	}
+	if len(upstream.models) != 2 {
+		t.Fatalf("upstream attempts = %d, want 2", len(upstream.models))
+	}
+	// Further requests fail fast without reaching the upstream.
+	_, err := m.Execute(context.Background(), []string{upstream.provider}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "circuit_open" || authErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("Execute() error = %v, want circuit_open", err)
+	}
+	if len(upstream.models) != 2 {
+		t.Fatalf("upstream attempts = %d, want no new attempts while open", len(upstream.models))
+	}
+	auth, _ := m.GetByID("breaker-3")
+	status, ok := m.CircuitBreakerFor(auth)
+	if !ok || status.State != CircuitOpen || status.BaseURL != "https://relay.example.com/v1" {
+		t.Fatalf("CircuitBreakerFor() = %+v, %v; want open breaker for the relay", status, ok)
+	}
+
+	// After the open period a single probe is admitted; its success closes the circuit.
+	time.Sleep(60 * time.Millisecond)
+	upstream.status = 0
+	if _, err = m.Execute(context.Background(), []string{upstream.provider}, cliproxyexecutor.Request{}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() probe error = %v", err)
+	}
+	if _, ok = m.CircuitBreakerFor(auth); ok {
+		t.Fatal("CircuitBreakerFor() still reports a breaker after a successful probe")
+	}
+	if list := m.CircuitBreakers(); len(list) != 0 {
+		t.Fatalf("CircuitBreakers() = %+v, want none", list)
+	}
+}
+
+func TestCircuitBreakers_HalfOpenAdmitsSingleProbe(t *testing.T) {
+	var breakers circuitBreakers
+	breakers.configure(CircuitBreakerSettings{Enabled: true, FailureThreshold: 1, OpenDuration: time.Minute})
+	auth := &Auth{ID: "probe-1", Provider: "breaker-probe"}
+	opened := time.Now()
+	breakers.record(auth, Result{Error: &Error{HTTPStatus: http.StatusBadGateway}}, opened)
+
+	halfOpen := opened.Add(2 * time.Minute)
+	key := circuitKey(auth)
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if breakers.allow(key, halfOpen) && breakers.tryAcquire(key, halfOpen) {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := admitted.Load(); got != 1 {
+		t.Fatalf("admitted probes = %d, want exactly 1", got)
+	}
+}
+
+// delayedExecutor answers after a per-auth delay, or fails with the context error when cancelled first.
+type delayedExecutor struct {
+	provider string
+	delays   map[string]time.Duration
+
+	mu        sync.Mutex
+	cancelled []string
+}
+
+func (e *delayedExecutor) Identifier() string { return e.provider }
+
+func (e *delayedExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	select {
+	case <-time.After(e.delays[auth.ID]):
+		return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+	case <-ctx.Done():
+		e.mu.Lock()
+		e.cancelled = append(e.cancelled, auth.ID)
+		e.mu.Unlock()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+}
+
+func (e *delayedExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, &Error{Code: "not_implemented", Message: "streaming not supported"}
+}
+
+func (e *delayedExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *delayedExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func TestManagerExecute_HedgesSlowAttempt(t *testing.T) {
+	upstream := &delayedExecutor{provider: "hedge-test", delays: map[string]time.Duration{
+		"hedge-a-slow": 2 * time.Second,
+		"hedge-b-fast": 0,
+	}}
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.RegisterExecutor(upstream)
+	for id := range upstream.delays {
+		registerTestAuth(t, m, id, upstream.provider, "hedge-model")
+	}
+	m.SetHedging(HedgingSettings{Enabled: true, InitialDelay: 20 * time.Millisecond})
+
+	started := time.Now()
+	resp, err := m.Execute(context.Background(), []string{upstream.provider}, cliproxyexecutor.Request{Model: "hedge-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "hedge-b-fast" {
+		t.Fatalf("Execute() payload = %q, want the hedged attempt to win", resp.Payload)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("Execute() took %s, want the slow attempt to be abandoned", elapsed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		upstream.mu.Lock()
+		cancelled := len(upstream.cancelled)
+		upstream.mu.Unlock()
+		if cancelled == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("slow attempt was not cancelled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	loser, _ := m.GetByID("hedge-a-slow")
+	if loser.LastError != nil || loser.Status == StatusError {
+		t.Fatalf("cancelled loser was recorded: status=%s err=%v", loser.Status, loser.LastError)
+	}
+
+	// Streaming requests are never hedged.
+	if _, ok := m.hedgeDelay("hedge-model", cliproxyexecutor.Options{Stream: true}); ok {
+		t.Fatal("hedgeDelay() reported a streaming request as eligible")
+	}
+}
+
+func TestManagerExecute_ConcurrencyQueue(t *testing.T) {
+	upstream := &delayedExecutor{provider: "concurrency-test", delays: map[string]time.Duration{"concurrency-1": 100 * time.Millisecond}}
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(upstream)
+	m.SetConcurrency(ConcurrencySettings{ProviderLimits: map[string]int{upstream.provider: 1}, QueueSize: 1, QueueTimeout: time.Second})
+	registerTestAuth(t, m, "concurrency-1", upstream.provider, "concurrency-model")
+	req := cliproxyexecutor.Request{Model: "concurrency-model"}
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	errs := make(chan error, 2)
+	run := func() {
+		_, err := m.Execute(context.Background(), []string{upstream.provider}, req, cliproxyexecutor.Options{})
+		errs <- err
+	}
+
+	go run()
+	waitFor("first request in flight", func() bool { return m.InFlight("concurrency-1") == 1 })
+	go run()
+	waitFor("second request queued", func() bool {
+		m.concurrency.mu.Lock()
+		defer m.concurrency.mu.Unlock()
+		return m.concurrency.waiting == 1
+	})
+
+	// The credential is busy and the only queue slot is taken.
+	_, err := m.Execute(context.Background(), []string{upstream.provider}, req, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "concurrency_queue_full" || authErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Execute() error = %v, want concurrency_queue_full", err)
+	}
+
+	// The queued request runs once the first one releases the credential.
+	for i := 0; i < 2; i++ {
+		if err = <-errs; err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if got := m.InFlight("concurrency-1"); got != 0 {
+		t.Fatalf("InFlight() = %d after completion, want 0", got)
+	}
+}
+
+type recordingHook struct {
+	NoopHook
+	mu      sync.Mutex
+	results []Result
+}
+
+func (h *recordingHook) OnResult(_ context.Context, result Result) {
+	h.mu.Lock()
+	h.results = append(h.results, result)
+	h.mu.Unlock()
+}
+
+func TestManagerAddHook_ReportsCooldown(t *testing.T) {
+	exec := &scriptedExecutor{provider: "hook-test", status: http.StatusTooManyRequests}
+	first, second := &recordingHook{}, &recordingHook{}
+	m := NewManager(nil, nil, first)
+	m.AddHook(second)
+	m.RegisterExecutor(exec)
+	registerTestAuth(t, m, "hook-auth", exec.provider, "hook-model")
+
+	if _, err := m.Execute(context.Background(), []string{exec.provider}, cliproxyexecutor.Request{Model: "hook-model"}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() error = nil, want upstream failure")
+	}
+	for _, hook := range []*recordingHook{first, second} {
+		if len(hook.results) != 1 {
+			t.Fatalf("hook results = %d, want 1", len(hook.results))
+		}
+		result := hook.results[0]
+		if result.CooldownReason != "quota" || result.Cooldown <= 0 {
+			t.Fatalf("result cooldown = %v (%q), want quota cooldown", result.Cooldown, result.CooldownReason)
+		}
+	}
+}
+
+// billingExecutor publishes a usage record as soon as an attempt starts.
+type billingExecutor struct{ *delayedExecutor }
+
+func (e billingExecutor) Execute(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	usage.PublishRecord(ctx, usage.Record{Provider: e.provider, Model: req.Model, AuthID: auth.ID})
+	return e.delayedExecutor.Execute(ctx, auth, req, opts)
+}
+
+type usageRecorder struct {
+	model   string
+	records chan usage.Record
+}
+
+func (r *usageRecorder) HandleUsage(_ context.Context, record usage.Record) {
+	if record.Model == r.model {
+		r.records <- record
+	}
+}
+
+func TestManagerExecute_HedgingRecordsOnlyWinnerUsage(t *testing.T) {
+	upstream := billingExecutor{&delayedExecutor{provider: "hedge-usage-test", delays: map[string]time.Duration{
+		"hedge-usage-a-slow": 2 * time.Second,
+		"hedge-usage-b-fast": 0,
+	}}}
+	recorder := &usageRecorder{model: "hedge-usage-model", records: make(chan usage.Record, 4)}
+	usage.RegisterPlugin(recorder)
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.RegisterExecutor(upstream)
+	for id := range upstream.delays {
+		registerTestAuth(t, m, id, upstream.provider, recorder.model)
+	}
+	m.SetHedging(HedgingSettings{Enabled: true, InitialDelay: 20 * time.Millisecond})
+
+	// The slow attempt publishes its usage before the fast one wins.
+	if _, err := m.Execute(context.Background(), []string{upstream.provider}, cliproxyexecutor.Request{Model: recorder.model}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	select {
+	case record := <-recorder.records:
+		if record.AuthID != "hedge-usage-b-fast" {
+			t.Fatalf("usage recorded for %q, want only the winner", record.AuthID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("winner usage was not recorded")
+	}
+	select {
+	case record := <-recorder.records:
+		t.Fatalf("loser usage recorded for %q", record.AuthID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+const (
+	// hedgeLatencyWindow is the number of recent latencies kept per model.
+	hedgeLatencyWindow = 128
+	// hedgeMinSamples is the number of samples required before the percentile is trusted.
+	hedgeMinSamples = 20
+)
+
+// HedgingSettings configures hedged execution of non-streaming requests.
+type HedgingSettings struct {
+	// Enabled toggles hedging.
+	Enabled bool
+	// Percentile of recent response latencies after which a second attempt is fired. Defaults to 95.
+	Percentile float64
+	// InitialDelay is used until enough latencies have been observed for the model. Defaults to 2s.
+	InitialDelay time.Duration
+	// MinDelay bounds the hedge delay from below so fast models are not hedged on every request.
+	MinDelay time.Duration
+	// Models restricts hedging to the listed models; empty hedges every non-streaming request.
+	Models []string
+}
+
+type hedger struct {
+	mu        sync.Mutex
+	settings  HedgingSettings
+	models    map[string]struct{}
+	latencies map[string]*latencyRing
+}
+
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+}
+
+type hedgeAttempt struct {
+	cancel context.CancelFunc
+	usage  *usage.Buffer
+}
+
+type hedgeOutcome struct {
+	index  int
+	resp   cliproxyexecutor.Response
+	result Result
+	err    error
+}
+
+// SetHedging updates the hedging settings. Observed latencies are kept across updates.
+func (m *Manager) SetHedging(settings HedgingSettings) {
+	if m == nil {
+		return
+	}
+	m.hedging.configure(settings)
+}
+
+func (h *hedger) configure(settings HedgingSettings) {
+	if settings.Percentile <= 0 || settings.Percentile >= 100 {
+		settings.Percentile = 95
+	}
+	if settings.InitialDelay <= 0 {
+		settings.InitialDelay = 2 * time.Second
+	}
+	var models map[string]struct{}
+	for _, model := range settings.Models {
+		if key := strings.ToLower(strings.TrimSpace(model)); key != "" {
+			if models == nil {
+				models = make(map[string]struct{}, len(settings.Models))
+			}
+			models[key] = struct{}{}
+		}
+	}
+	h.mu.Lock()
+	h.settings = settings
+	h.models = models
+	h.mu.Unlock()
+}
+
+// hedgeDelay returns how long to wait for the first attempt before hedging, and
+// whether the request is eligible for hedging at all.
+func (m *Manager) hedgeDelay(model string, opts cliproxyexecutor.Options) (time.Duration, bool) {
+	if m == nil || opts.Stream {
+		return 0, false
+	}
+	h := &m.hedging
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.settings.Enabled {
+		return 0, false
+	}
+	key := strings.ToLower(strings.TrimSpace(model))
+	if h.models != nil {
+		if _, ok := h.models[key]; !ok {
+			return 0, false
+		}
+	}
+	delay := h.settings.InitialDelay
+	if ring := h.latencies[key]; ring != nil && len(ring.samples) >= hedgeMinSamples {
+		delay = ring.percentile(h.settings.Percentile)
+	}
+	if delay < h.settings.MinDelay {
+		delay = h.settings.MinDelay
+	}
+	return delay, true
+}
+
+// observe records the latency of a successful hedge-eligible request.
+func (h *hedger) observe(model string, latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(model))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.latencies == nil {
+		h.latencies = make(map[string]*latencyRing)
+	}
+	ring := h.latencies[key]
+	if ring == nil {
+		ring = &latencyRing{samples: make([]time.Duration, 0, hedgeLatencyWindow)}
+		h.latencies[key] = ring
+	}
+	if len(ring.samples) < hedgeLatencyWindow {
+		ring.samples = append(ring.samples, latency)
+		return
+	}
+	ring.samples[ring.next] = latency
+	ring.next = (ring.next + 1) % hedgeLatencyWindow
+}
+
+func (r *latencyRing) percentile(p float64) time.Duration {
+	sorted := make([]time.Duration, len(r.samples))
+	copy(sorted, r.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// executeHedged runs the request against auth and, if it has not completed within delay,
+// fires the same request at a second credential of the provider. The first successful
+// response wins; the other attempt is cancelled and neither its result nor its usage is
+// recorded. Attempts that fail on their own are still recorded so cooldowns apply.
+func (m *Manager) executeHedged(ctx context.Context, provider, routeModel string, auth *Auth, executor ProviderExecutor, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, tried map[string]struct{}, delay time.Duration) (cliproxyexecutor.Response, error) {
+	outcomes := make(chan hedgeOutcome, 2)
+	var attempts []hedgeAttempt
+	launch := func(a *Auth, e ProviderExecutor) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		attemptCtx, buffer := usage.WithBuffer(attemptCtx)
+		index := len(attempts)
+		attempts = append(attempts, hedgeAttempt{cancel: cancel, usage: buffer})
+		go func() {
+			resp, result, err := m.executeAttempt(attemptCtx, provider, routeModel, a, e, req, opts)
+			m.concurrency.release(a.ID)
+			outcomes <- hedgeOutcome{index: index, resp: resp, result: result, err: err}
+		}()
+	}
+
+	launch(auth, executor)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var early *hedgeOutcome
+	select {
+	case out := <-outcomes:
+		early = &out
+	case <-timer.C:
+		if hedgeAuth, hedgeExecutor, errPick := m.pickNextOnce(ctx, provider, routeModel, opts, tried); errPick == nil {
+			tried[hedgeAuth.ID] = struct{}{}
+			logEntryWithRequestID(ctx).Debugf("hedging model %s after %s with auth %s", routeModel, delay, hedgeAuth.ID)
+			launch(hedgeAuth, hedgeExecutor)
+		}
+	}
+
+	var lastErr error
+	for remaining := len(attempts); remaining > 0; remaining-- {
+		var out hedgeOutcome
+		if early != nil {
+			out, early = *early, nil
+		} else {
+			out = <-outcomes
+		}
+		if out.err == nil {
+			// Usage is held per attempt until here, so only the winner's is recorded even
+			// when the loser completed too.
+			for i, attempt := range attempts {
+				if i == out.index {
+					attempt.usage.Flush()
+				} else {
+					attempt.usage.Drop()
+				}
+				attempt.cancel()
+			}
+			m.hedging.observe(routeModel, out.result.Latency)
+			m.MarkResult(ctx, out.result)
+			return out.resp, nil
+		}
+		attempts[out.index].usage.Flush()
+		attempts[out.index].cancel()
+		m.MarkResult(ctx, out.result)
+		lastErr = out.err
+	}
+	return cliproxyexecutor.Response{}, lastErr
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// RetryObserver is implemented by hooks that want to know when a request is retried
+// after every candidate credential failed. Attempt is the 1-based number of the retry
+// about to run and wait is the cooldown slept before it.
+type RetryObserver interface {
+	OnRetry(ctx context.Context, model string, attempt int, wait time.Duration)
+}
+
+// multiHook fans lifecycle callbacks out to several hooks in registration order.
+type multiHook []Hook
+
+// OnAuthRegistered implements Hook.
+func (h multiHook) OnAuthRegistered(ctx context.Context, auth *Auth) {
+	for _, hook := range h {
+		hook.OnAuthRegistered(ctx, auth)
+	}
+}
+
+// OnAuthUpdated implements Hook.
+func (h multiHook) OnAuthUpdated(ctx context.Context, auth *Auth) {
+	for _, hook := range h {
+		hook.OnAuthUpdated(ctx, auth)
+	}
+}
+
+// OnResult implements Hook.
+func (h multiHook) OnResult(ctx context.Context, result Result) {
+	for _, hook := range h {
+		hook.OnResult(ctx, result)
+	}
+}
+
+// OnRetry implements RetryObserver.
+func (h multiHook) OnRetry(ctx context.Context, model string, attempt int, wait time.Duration) {
+	for _, hook := range h {
+		if observer, ok := hook.(RetryObserver); ok {
+			observer.OnRetry(ctx, model, attempt, wait)
+		}
+	}
+}
+
+// AddHook registers an additional hook next to the one passed to NewManager.
+// Hooks are invoked in registration order; adding the same hook twice is a no-op.
+func (m *Manager) AddHook(hook Hook) {
+	if m == nil || hook == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch existing := m.hook.(type) {
+	case nil, NoopHook:
+		m.hook = hook
+	case multiHook:
+		for _, registered := range existing {
+			if sameHook(registered, hook) {
+				return
+			}
+		}
+		m.hook = append(append(multiHook{}, existing...), hook)
+	default:
+		if sameHook(existing, hook) {
+			return
+		}
+		m.hook = multiHook{existing, hook}
+	}
+}
+
+// sameHook compares hooks without panicking on non-comparable hook types.
+func sameHook(a, b Hook) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// currentHook returns the hook under the manager lock.
+func (m *Manager) currentHook() Hook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.hook == nil {
+		return NoopHook{}
+	}
+	return m.hook
+}
+
+// notifyRetry reports a retry to the hook when it implements RetryObserver.
+func (m *Manager) notifyRetry(ctx context.Context, model string, attempt int, wait time.Duration) {
+	if observer, ok := m.currentHook().(RetryObserver); ok {
+		observer.OnRetry(ctx, model, attempt, wait)
+	}
+}
+
+// cooldownReason names the cooldown applied for an upstream status code.
+func cooldownReason(statusCode int) string {
+	switch statusCode {
+	case 401:
+		return "unauthorized"
+	case 402, 403:
+		return "payment_required"
+	case 404:
+		return "not_found"
+	case 429:
+		return "quota"
+	default:
+		return "transient"
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// FallbackFromMetadataKey records the originally requested model in execution
+// metadata when a request is served by a fallback model.
+const FallbackFromMetadataKey = "fallback_from"
+
+type modelFallbackTable struct {
+	// chains maps the lower-cased model name to its ordered fallback models.
+	chains map[string][]string
+}
+
+func compileModelFallbackTable(fallbacks map[string][]string) *modelFallbackTable {
+	out := &modelFallbackTable{}
+	for model, chain := range internalconfig.NormalizeModelFallbacks(fallbacks) {
+		if out.chains == nil {
+			out.chains = make(map[string][]string)
+		}
+		out.chains[strings.ToLower(model)] = chain
+	}
+	return out
+}
+
+// SetModelFallbacks updates the model fallback chains used by Execute and ExecuteStream.
+// Each key is a requested model and its value lists the models to try, in order, once
+// every credential of the previous model is exhausted or fails with 429/5xx.
+func (m *Manager) SetModelFallbacks(fallbacks map[string][]string) {
+	if m == nil {
+		return
+	}
+	m.modelFallbacks.Store(compileModelFallbackTable(fallbacks))
+}
+
+// fallbackChain returns the configured fallback models for the requested model.
+func (m *Manager) fallbackChain(model string) []string {
+	if m == nil {
+		return nil
+	}
+	table, _ := m.modelFallbacks.Load().(*modelFallbackTable)
+	if table == nil || len(table.chains) == 0 {
+		return nil
+	}
+	return table.chains[strings.ToLower(strings.TrimSpace(model))]
+}
+
+// shouldFallback reports whether a failed model attempt may move on to the next model.
+// Only exhausted credentials and upstream capacity failures qualify; client errors
+// would fail the same way on any model.
+func shouldFallback(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	status := statusCodeFromError(err)
+	return status == 0 || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// fallbackRequest rewrites the request for a fallback model and resolves its providers.
+// It returns false when no provider currently serves the fallback model.
+func fallbackRequest(model string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) ([]string, cliproxyexecutor.Request, cliproxyexecutor.Options, bool) {
+	normalized, modelMeta := util.NormalizeThinkingModel(model)
+	providers := util.GetProviderName(normalized)
+	if len(providers) == 0 {
+		return nil, req, opts, false
+	}
+
+	original := req.Model
+	if raw, ok := opts.Metadata[FallbackFromMetadataKey].(string); ok && raw != "" {
+		original = raw
+	}
+
+	// Model-derived metadata (e.g. thinking suffixes) belongs to the previous model
+	// and is replaced; request-scoped entries such as idempotency and session keys are kept.
+	meta := make(map[string]any, len(opts.Metadata)+len(modelMeta)+1)
+	for k, v := range opts.Metadata {
+		if _, modelScoped := req.Metadata[k]; modelScoped {
+			continue
+		}
+		meta[k] = v
+	}
+	for k, v := range modelMeta {
+		meta[k] = v
+	}
+	meta[FallbackFromMetadataKey] = original
+
+	req.Model = normalized
+	req.Metadata = modelMeta
+	opts.Metadata = meta
+	return providers, req, opts, true
+}
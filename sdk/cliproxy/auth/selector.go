@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,64 @@ type RoundRobinSelector struct {
 // rolling-window subscription caps (e.g. chat message limits).
 type FillFirstSelector struct{}
 
+// PrioritySelector drains credentials tier by tier. It round-robins across the
+// available credentials with the lowest Priority value and only spills over to
+// the next tier when every credential in the preferred tier is unavailable.
+type PrioritySelector struct {
+	mu      sync.Mutex
+	cursors map[string]int
+}
+
+// WeightedSelector distributes requests proportionally to each credential's Weight
+// using smooth weighted round-robin, so heavier credentials are interleaved rather
+// than picked in bursts.
+type WeightedSelector struct {
+	mu      sync.Mutex
+	current map[string]map[string]int
+}
+
+// Routing strategy names accepted by NewSelector.
+const (
+	StrategyRoundRobin = "round-robin"
+	StrategyFillFirst  = "fill-first"
+	StrategyPriority   = "priority"
+	StrategyWeighted   = "weighted"
+	StrategyAdaptive   = "adaptive"
+)
+
+// NormalizeStrategy maps a configured routing strategy (including aliases) to its canonical name.
+// Unknown or empty values resolve to round-robin.
+func NormalizeStrategy(strategy string) string {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "fill-first", "fillfirst", "ff":
+		return StrategyFillFirst
+	case "priority", "tiered":
+		return StrategyPriority
+	case "weighted", "weight", "wrr":
+		return StrategyWeighted
+	case "adaptive", "latency", "fastest":
+		return StrategyAdaptive
+	default:
+		return StrategyRoundRobin
+	}
+}
+
+// NewSelector constructs the selector for the given routing strategy.
+func NewSelector(strategy string) Selector {
+	switch NormalizeStrategy(strategy) {
+	case StrategyFillFirst:
+		return &FillFirstSelector{}
+	case StrategyPriority:
+		return &PrioritySelector{}
+	case StrategyWeighted:
+		return &WeightedSelector{}
+	case StrategyAdaptive:
+		return &AdaptiveSelector{}
+	default:
+		return &RoundRobinSelector{}
+	}
+}
+
 type blockReason int
 
 const (
@@ -183,6 +242,75 @@ func (s *FillFirstSelector) Pick(ctx context.Context, provider, model string, op
 	return available[0], nil
 }
 
+// Pick selects a round-robin auth from the most preferred available priority tier.
+func (s *PrioritySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	best := available[0].Priority()
+	for _, candidate := range available[1:] {
+		if p := candidate.Priority(); p < best {
+			best = p
+		}
+	}
+	tier := make([]*Auth, 0, len(available))
+	for _, candidate := range available {
+		if candidate.Priority() == best {
+			tier = append(tier, candidate)
+		}
+	}
+	key := provider + ":" + model + ":" + strconv.Itoa(best)
+	s.mu.Lock()
+	if s.cursors == nil {
+		s.cursors = make(map[string]int)
+	}
+	index := s.cursors[key]
+	if index >= 2_147_483_640 {
+		index = 0
+	}
+	s.cursors[key] = index + 1
+	s.mu.Unlock()
+	return tier[index%len(tier)], nil
+}
+
+// Pick selects the next available auth according to smooth weighted round-robin.
+func (s *WeightedSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	key := provider + ":" + model
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		s.current = make(map[string]map[string]int)
+	}
+	previous := s.current[key]
+	// Rebuild the state from the currently available auths so removed or
+	// cooling-down credentials do not retain stale credit.
+	state := make(map[string]int, len(available))
+	total := 0
+	var selected *Auth
+	for _, candidate := range available {
+		weight := candidate.Weight()
+		total += weight
+		state[candidate.ID] = previous[candidate.ID] + weight
+		if selected == nil || state[candidate.ID] > state[selected.ID] {
+			selected = candidate
+		}
+	}
+	state[selected.ID] -= total
+	s.current[key] = state
+	return selected, nil
+}
+
 func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, blockReason, time.Time) {
 	if auth == nil {
 		return true, blockReasonOther, time.Time{}
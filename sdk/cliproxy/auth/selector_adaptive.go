@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	adaptiveDefaultAlpha       = 0.2
+	adaptiveDefaultExploreRate = 0.05
+	// adaptiveErrorPenalty scales how strongly the error rate inflates a score.
+	// A credential failing half of its requests scores like one three times slower.
+	adaptiveErrorPenalty = 4.0
+	// adaptiveFallbackLatency is assumed for credentials without latency samples
+	// when no other candidate has any either.
+	adaptiveFallbackLatency = time.Second
+	// adaptiveStatsTTL drops statistics that have not been updated for this long, so
+	// models that are no longer requested do not accumulate entries.
+	adaptiveStatsTTL = 6 * time.Hour
+	// adaptiveSweepInterval bounds how often stale statistics are swept.
+	adaptiveSweepInterval = 10 * time.Minute
+)
+
+// ResultObserver is implemented by selectors that learn from execution outcomes.
+// Manager.MarkResult forwards every recorded result to the active selector when it
+// implements this interface.
+type ResultObserver interface {
+	ObserveResult(result Result)
+}
+
+// AuthForgetter is implemented by selectors that keep per-auth state. Manager calls
+// ForgetAuth when an auth is disabled or removed so that state does not outlive it.
+type AuthForgetter interface {
+	ForgetAuth(authID string)
+}
+
+// ScoreReporter is implemented by selectors that can describe how they rank credentials.
+type ScoreReporter interface {
+	Scores(authID string) []AdaptiveScore
+}
+
+// AdaptiveScore summarises the observed performance of one credential for one model.
+type AdaptiveScore struct {
+	Model     string    `json:"model"`
+	LatencyMs float64   `json:"latency_ms"`
+	ErrorRate float64   `json:"error_rate"`
+	Samples   int64     `json:"samples"`
+	Score     float64   `json:"score"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AdaptiveSelector prefers the credentials with the lowest exponentially weighted
+// time-to-first-byte and error rate for the requested model. A small fraction of
+// requests (ExploreRate) probes other credentials: unmeasured ones first, so new
+// and re-enabled credentials are measured without receiving a burst of traffic,
+// then random non-best ones so that recovered credentials are rediscovered.
+type AdaptiveSelector struct {
+	// Alpha is the EWMA smoothing factor in (0, 1]. Zero uses the default.
+	Alpha float64
+	// ExploreRate is the probability of probing a non-best credential.
+	// Zero uses the default; a negative value disables probing.
+	ExploreRate float64
+
+	mu        sync.Mutex
+	stats     map[adaptiveKey]*adaptiveStats
+	rng       *rand.Rand
+	lastSweep time.Time
+}
+
+type adaptiveKey struct {
+	authID string
+	model  string
+}
+
+type adaptiveStats struct {
+	latencyMs float64
+	errorRate float64
+	samples   int64
+	latencies int64
+	updatedAt time.Time
+}
+
+func (s *AdaptiveSelector) alpha() float64 {
+	if s.Alpha <= 0 || s.Alpha > 1 {
+		return adaptiveDefaultAlpha
+	}
+	return s.Alpha
+}
+
+func (s *AdaptiveSelector) exploreRate() float64 {
+	if s.ExploreRate < 0 {
+		return 0
+	}
+	if s.ExploreRate == 0 {
+		return adaptiveDefaultExploreRate
+	}
+	return s.ExploreRate
+}
+
+// Pick selects the available auth with the best adaptive score for the model.
+func (s *AdaptiveSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(available) == 1 {
+		return available[0], nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fallback := adaptiveFallbackLatency.Seconds() * 1000
+	observed := false
+	measured := make([]*Auth, 0, len(available))
+	var unmeasured []*Auth
+	for _, candidate := range available {
+		st := s.stats[adaptiveKey{authID: candidate.ID, model: model}]
+		if st == nil || st.samples == 0 {
+			unmeasured = append(unmeasured, candidate)
+			continue
+		}
+		measured = append(measured, candidate)
+		if st.latencies > 0 && (!observed || st.latencyMs > fallback) {
+			fallback = st.latencyMs
+			observed = true
+		}
+	}
+	if len(measured) == 0 {
+		// Nothing is known yet; start with the first credential and measure the rest
+		// through exploration.
+		return available[0], nil
+	}
+
+	ranked := measured
+	scores := make(map[string]float64, len(ranked))
+	for _, candidate := range ranked {
+		scores[candidate.ID] = s.stats[adaptiveKey{authID: candidate.ID, model: model}].score(fallback)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i].ID] < scores[ranked[j].ID] })
+
+	if rate := s.exploreRate(); rate > 0 && (len(unmeasured) > 0 || len(ranked) > 1) {
+		if s.rng == nil {
+			s.rng = rand.New(rand.NewSource(now.UnixNano()))
+		}
+		if s.rng.Float64() < rate {
+			if len(unmeasured) > 0 {
+				return unmeasured[s.rng.Intn(len(unmeasured))], nil
+			}
+			return ranked[1+s.rng.Intn(len(ranked)-1)], nil
+		}
+	}
+	return ranked[0], nil
+}
+
+// ObserveResult folds an execution outcome into the EWMA statistics of the auth and model.
+func (s *AdaptiveSelector) ObserveResult(result Result) {
+	if result.AuthID == "" {
+		return
+	}
+	if !result.Success && !countsAgainstAuth(result.Error) {
+		return
+	}
+	alpha := s.alpha()
+	key := adaptiveKey{authID: result.AuthID, model: result.Model}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[adaptiveKey]*adaptiveStats)
+	}
+	st := s.stats[key]
+	if st == nil {
+		st = &adaptiveStats{}
+		s.stats[key] = st
+	}
+	failure := 0.0
+	if !result.Success {
+		failure = 1
+	}
+	if st.samples == 0 {
+		st.errorRate = failure
+	} else {
+		st.errorRate = alpha*failure + (1-alpha)*st.errorRate
+	}
+	if result.Success && result.Latency > 0 {
+		ms := float64(result.Latency) / float64(time.Millisecond)
+		if st.latencies == 0 {
+			st.latencyMs = ms
+		} else {
+			st.latencyMs = alpha*ms + (1-alpha)*st.latencyMs
+		}
+		st.latencies++
+	}
+	st.samples++
+	st.updatedAt = time.Now()
+	s.sweepLocked(st.updatedAt)
+}
+
+// ForgetAuth drops every statistic recorded for the auth.
+func (s *AdaptiveSelector) ForgetAuth(authID string) {
+	if authID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.stats {
+		if key.authID == authID {
+			delete(s.stats, key)
+		}
+	}
+}
+
+// sweepLocked drops statistics that have not been updated within adaptiveStatsTTL.
+func (s *AdaptiveSelector) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < adaptiveSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, st := range s.stats {
+		if st == nil || now.Sub(st.updatedAt) > adaptiveStatsTTL {
+			delete(s.stats, key)
+		}
+	}
+}
+
+// Scores returns the adaptive statistics recorded for the auth, ordered by model.
+func (s *AdaptiveSelector) Scores(authID string) []AdaptiveScore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []AdaptiveScore
+	for key, st := range s.stats {
+		if key.authID != authID || st == nil {
+			continue
+		}
+		out = append(out, AdaptiveScore{
+			Model:     key.model,
+			LatencyMs: st.latencyMs,
+			ErrorRate: st.errorRate,
+			Samples:   st.samples,
+			Score:     st.score(adaptiveFallbackLatency.Seconds() * 1000),
+			UpdatedAt: st.updatedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// score returns the expected cost of routing to the credential; lower is better.
+func (st *adaptiveStats) score(fallbackLatencyMs float64) float64 {
+	if st == nil {
+		return 0
+	}
+	latency := st.latencyMs
+	if st.latencies == 0 {
+		latency = fallbackLatencyMs
+	}
+	return latency * (1 + adaptiveErrorPenalty*st.errorRate)
+}
+
+// countsAgainstAuth reports whether a failure reflects on the credential or upstream
+// rather than on the client request itself.
+func countsAgainstAuth(err *Error) bool {
+	status := statusCodeFromResult(err)
+	if status == 0 || status >= 500 {
+		return true
+	}
+	switch status {
+	case 401, 402, 403, 408, 429:
+		return true
+	default:
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 )
@@ -111,3 +112,374 @@ func TestRoundRobinSelectorPick_Concurrent(t *testing.T) {
 	default:
 	}
 }
+
+func TestPrioritySelectorPick_PrefersLowestTier(t *testing.T) {
+	t.Parallel()
+
+	selector := &PrioritySelector{}
+	auths := []*Auth{
+		{ID: "paid", Attributes: map[string]string{"priority": "1"}},
+		{ID: "oauth-b", Metadata: map[string]any{"priority": float64(0)}},
+		{ID: "oauth-a"},
+	}
+
+	want := []string{"oauth-a", "oauth-b", "oauth-a"}
+	for i, id := range want {
+		got, err := selector.Pick(context.Background(), "claude", "", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		if got.ID != id {
+			t.Fatalf("Pick() #%d auth.ID = %q, want %q", i, got.ID, id)
+		}
+	}
+}
+
+func TestPrioritySelectorPick_SpillsWhenTierCoolingDown(t *testing.T) {
+	t.Parallel()
+
+	selector := &PrioritySelector{}
+	cooling := func(id string) *Auth {
+		return &Auth{
+			ID:             id,
+			Unavailable:    true,
+			NextRetryAfter: time.Now().Add(time.Minute),
+			Quota:          QuotaState{Exceeded: true},
+		}
+	}
+	auths := []*Auth{
+		cooling("oauth-a"),
+		cooling("oauth-b"),
+		{ID: "paid", Attributes: map[string]string{"priority": "5"}},
+	}
+
+	got, err := selector.Pick(context.Background(), "claude", "", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "paid" {
+		t.Fatalf("Pick() auth.ID = %q, want %q", got.ID, "paid")
+	}
+}
+
+func TestWeightedSelectorPick_Proportional(t *testing.T) {
+	t.Parallel()
+
+	selector := &WeightedSelector{}
+	auths := []*Auth{
+		{ID: "a", Attributes: map[string]string{"weight": "3"}},
+		{ID: "b"},
+	}
+
+	counts := make(map[string]int)
+	var sequence []string
+	for i := 0; i < 8; i++ {
+		got, err := selector.Pick(context.Background(), "codex", "gpt-5", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		counts[got.ID]++
+		sequence = append(sequence, got.ID)
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Fatalf("Pick() distribution = %v, want a=6 b=2", counts)
+	}
+	if sequence[0] != "a" || sequence[1] != "a" || sequence[2] != "b" {
+		t.Fatalf("Pick() sequence = %v, want interleaved starting a,a,b", sequence)
+	}
+}
+
+func TestNewSelector_Strategies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{"", StrategyRoundRobin},
+		{"FF", StrategyFillFirst},
+		{"priority", StrategyPriority},
+		{" Weighted ", StrategyWeighted},
+		{"adaptive", StrategyAdaptive},
+		{"unknown", StrategyRoundRobin},
+	}
+	for _, tt := range tests {
+		if got := NormalizeStrategy(tt.strategy); got != tt.want {
+			t.Errorf("NormalizeStrategy(%q) = %q, want %q", tt.strategy, got, tt.want)
+		}
+	}
+	if _, ok := NewSelector("priority").(*PrioritySelector); !ok {
+		t.Error("NewSelector(priority) did not return *PrioritySelector")
+	}
+	if _, ok := NewSelector("weighted").(*WeightedSelector); !ok {
+		t.Error("NewSelector(weighted) did not return *WeightedSelector")
+	}
+	if _, ok := NewSelector("adaptive").(*AdaptiveSelector); !ok {
+		t.Error("NewSelector(adaptive) did not return *AdaptiveSelector")
+	}
+}
+
+func TestAdaptiveSelectorPick_PrefersFastAndHealthy(t *testing.T) {
+	t.Parallel()
+
+	selector := &AdaptiveSelector{ExploreRate: -1}
+	auths := []*Auth{{ID: "slow"}, {ID: "fast"}, {ID: "flaky"}}
+
+	// Unmeasured credentials are tried before any scoring happens.
+	got, err := selector.Pick(context.Background(), "claude", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "fast" {
+		t.Fatalf("Pick() first unmeasured = %q, want %q", got.ID, "fast")
+	}
+
+	for i := 0; i < 5; i++ {
+		selector.ObserveResult(Result{AuthID: "slow", Model: "m", Success: true, Latency: 900 * time.Millisecond})
+		selector.ObserveResult(Result{AuthID: "fast", Model: "m", Success: true, Latency: 200 * time.Millisecond})
+		selector.ObserveResult(Result{AuthID: "flaky", Model: "m", Success: true, Latency: 100 * time.Millisecond})
+		selector.ObserveResult(Result{AuthID: "flaky", Model: "m", Success: false, Error: &Error{HTTPStatus: 503}})
+	}
+	// Client errors must not penalise the credential.
+	selector.ObserveResult(Result{AuthID: "fast", Model: "m", Success: false, Error: &Error{HTTPStatus: 400}})
+
+	got, err = selector.Pick(context.Background(), "claude", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "fast" {
+		t.Fatalf("Pick() = %q, want %q", got.ID, "fast")
+	}
+
+	scores := selector.Scores("flaky")
+	if len(scores) != 1 || scores[0].Model != "m" || scores[0].ErrorRate <= 0 {
+		t.Fatalf("Scores(flaky) = %+v, want one entry with a positive error rate", scores)
+	}
+	if fast := selector.Scores("fast"); len(fast) != 1 || fast[0].ErrorRate != 0 || fast[0].Samples != 5 {
+		t.Fatalf("Scores(fast) = %+v, want 5 clean samples", fast)
+	}
+}
+
+func TestAdaptiveSelectorPick_Explores(t *testing.T) {
+	t.Parallel()
+
+	selector := &AdaptiveSelector{ExploreRate: 1}
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: 10 * time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "b", Model: "m", Success: true, Latency: 500 * time.Millisecond})
+
+	got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("Pick() with ExploreRate=1 = %q, want the non-best %q", got.ID, "b")
+	}
+}
+
+func TestAdaptiveSelectorPick_BoundsExplorationOfNewCredentials(t *testing.T) {
+	t.Parallel()
+
+	auths := []*Auth{{ID: "a"}, {ID: "new"}}
+	measure := func(selector *AdaptiveSelector) {
+		selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: 300 * time.Millisecond})
+	}
+
+	steady := &AdaptiveSelector{ExploreRate: -1}
+	measure(steady)
+	for i := 0; i < 20; i++ {
+		got, err := steady.Pick(context.Background(), "claude", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		if got.ID != "a" {
+			t.Fatalf("Pick() without exploration = %q, want the measured %q", got.ID, "a")
+		}
+	}
+
+	probing := &AdaptiveSelector{ExploreRate: 1}
+	measure(probing)
+	got, err := probing.Pick(context.Background(), "claude", "m", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "new" {
+		t.Fatalf("Pick() exploration = %q, want the unmeasured %q", got.ID, "new")
+	}
+}
+
+func TestAdaptiveSelector_ForgetsAndSweepsStats(t *testing.T) {
+	t.Parallel()
+
+	selector := &AdaptiveSelector{}
+	selector.ObserveResult(Result{AuthID: "a", Model: "m1", Success: true, Latency: time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "a", Model: "m2", Success: true, Latency: time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "b", Model: "m1", Success: true, Latency: time.Millisecond})
+
+	selector.ForgetAuth("a")
+	if scores := selector.Scores("a"); len(scores) != 0 {
+		t.Fatalf("Scores(a) after ForgetAuth = %+v, want none", scores)
+	}
+
+	selector.mu.Lock()
+	selector.stats[adaptiveKey{authID: "b", model: "m1"}].updatedAt = time.Now().Add(-2 * adaptiveStatsTTL)
+	selector.lastSweep = time.Time{}
+	selector.mu.Unlock()
+	selector.ObserveResult(Result{AuthID: "c", Model: "m1", Success: true, Latency: time.Millisecond})
+	if scores := selector.Scores("b"); len(scores) != 0 {
+		t.Fatalf("Scores(b) after sweep = %+v, want stale stats dropped", scores)
+	}
+}
+
+func TestManagerUpdate_DisabledAuthForgetsSelectorStats(t *testing.T) {
+	t.Parallel()
+
+	selector := &AdaptiveSelector{}
+	manager := NewManager(nil, selector, nil)
+	auth := &Auth{ID: "a", Provider: "claude"}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: time.Millisecond})
+
+	auth.Disabled = true
+	auth.Status = StatusDisabled
+	if _, err := manager.Update(context.Background(), auth); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if scores := selector.Scores("a"); len(scores) != 0 {
+		t.Fatalf("Scores(a) after disabling = %+v, want none", scores)
+	}
+}
+
+type noopProviderExecutor struct{ provider string }
+
+func (e noopProviderExecutor) Identifier() string { return e.provider }
+
+func (e noopProviderExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (e noopProviderExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, nil
+}
+
+func (e noopProviderExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e noopProviderExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func TestManagerPickNext_SessionAffinity(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(noopProviderExecutor{provider: "claude"})
+	m.SetSessionAffinity(time.Hour)
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "claude"}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+
+	session := cliproxyexecutor.Options{Metadata: map[string]any{SessionKeyMetadataKey: "conv-1"}}
+	first, _, err := m.pickNext(context.Background(), "claude", "", session, nil)
+	if err != nil {
+		t.Fatalf("pickNext() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		// Unrelated traffic keeps advancing the round-robin cursor.
+		if _, _, err = m.pickNext(context.Background(), "claude", "", cliproxyexecutor.Options{}, nil); err != nil {
+			t.Fatalf("pickNext() error = %v", err)
+		}
+		got, _, errPick := m.pickNext(context.Background(), "claude", "", session, nil)
+		if errPick != nil {
+			t.Fatalf("pickNext() error = %v", errPick)
+		}
+		if got.ID != first.ID {
+			t.Fatalf("pickNext() session pick #%d = %q, want pinned %q", i, got.ID, first.ID)
+		}
+	}
+
+	// A cooling-down credential releases the session to another one, which becomes the new pin.
+	m.MarkResult(context.Background(), Result{AuthID: first.ID, Provider: "claude", Success: false, Error: &Error{HTTPStatus: 429}})
+	moved, _, err := m.pickNext(context.Background(), "claude", "", session, nil)
+	if err != nil {
+		t.Fatalf("pickNext() error = %v", err)
+	}
+	if moved.ID == first.ID {
+		t.Fatalf("pickNext() kept session on cooling-down auth %q", first.ID)
+	}
+	again, _, err := m.pickNext(context.Background(), "claude", "", session, nil)
+	if err != nil {
+		t.Fatalf("pickNext() error = %v", err)
+	}
+	if again.ID != moved.ID {
+		t.Fatalf("pickNext() = %q, want re-pinned %q", again.ID, moved.ID)
+	}
+}
+
+func TestManagerPickNext_SessionAffinityKeepsPinOnSpill(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(noopProviderExecutor{provider: "claude"})
+	m.SetSessionAffinity(time.Hour)
+	m.SetConcurrency(ConcurrencySettings{ProviderLimits: map[string]int{"claude": 1}, QueueTimeout: 5 * time.Second})
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "claude"}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	session := cliproxyexecutor.Options{Metadata: map[string]any{SessionKeyMetadataKey: "conv-1"}}
+	pinned, _, err := m.pickNext(context.Background(), "claude", "", session, nil)
+	if err != nil {
+		t.Fatalf("pickNext() error = %v", err)
+	}
+
+	// A retry after the pinned credential failed is served elsewhere without moving the pin.
+	retry, _, err := m.pickNext(context.Background(), "claude", "", session, map[string]struct{}{pinned.ID: {}})
+	if err != nil {
+		t.Fatalf("pickNext() retry error = %v", err)
+	}
+	if retry.ID == pinned.ID {
+		t.Fatalf("pickNext() retry reused tried auth %q", pinned.ID)
+	}
+	m.concurrency.release(retry.ID)
+
+	// A busy pinned credential is waited for instead of spilling to a free one.
+	picked := make(chan string, 1)
+	go func() {
+		got, _, errPick := m.pickNext(context.Background(), "claude", "", session, nil)
+		if errPick != nil {
+			picked <- errPick.Error()
+			return
+		}
+		picked <- got.ID
+	}()
+	select {
+	case got := <-picked:
+		t.Fatalf("pickNext() = %q while the pinned auth was busy, want it to wait", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	m.concurrency.release(pinned.ID)
+	select {
+	case got := <-picked:
+		if got != pinned.ID {
+			t.Fatalf("pickNext() after release = %q, want pinned %q", got, pinned.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pickNext() did not resume after the pinned auth was released")
+	}
+}
+
+func TestSessionAffinityKeyScopedToClientKey(t *testing.T) {
+	t.Parallel()
+
+	session := cliproxyexecutor.Options{Metadata: map[string]any{SessionKeyMetadataKey: "1"}}
+	keyA := sessionAffinityKey(cliproxyexecutor.WithClientAPIKey(context.Background(), "key-a"), "claude", "m", session)
+	keyB := sessionAffinityKey(cliproxyexecutor.WithClientAPIKey(context.Background(), "key-b"), "claude", "m", session)
+	if keyA == "" || keyA == keyB {
+		t.Fatalf("session keys for different clients = %q and %q, want distinct", keyA, keyB)
+	}
+}
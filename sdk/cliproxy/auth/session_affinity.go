@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// SessionKeyMetadataKey is the execution metadata key carrying the client session
+// identifier used for session-sticky credential routing.
+const SessionKeyMetadataKey = "session_key"
+
+// sessionAffinity pins client sessions to the credential that served them last so that
+// upstream prompt caches keep hitting the same account.
+type sessionAffinity struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	pins      map[string]sessionPin
+	lastSweep time.Time
+}
+
+type sessionPin struct {
+	authID    string
+	expiresAt time.Time
+}
+
+// SetSessionAffinity enables session-sticky routing with the given idle TTL.
+// A non-positive TTL disables stickiness and drops existing pins.
+func (m *Manager) SetSessionAffinity(ttl time.Duration) {
+	if m == nil {
+		return
+	}
+	m.sessions.configure(ttl)
+}
+
+func (s *sessionAffinity) configure(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ttl <= 0 {
+		s.ttl = 0
+		s.pins = nil
+		return
+	}
+	s.ttl = ttl
+}
+
+// lookup returns the credential the session is pinned to.
+func (s *sessionAffinity) lookup(key string, now time.Time) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl <= 0 {
+		return "", false
+	}
+	pin, ok := s.pins[key]
+	if !ok || now.After(pin.expiresAt) {
+		return "", false
+	}
+	return pin.authID, true
+}
+
+// sessionPinHolds reports whether a session pinned to auth must stay on it. Only a removed,
+// disabled or cooling-down credential releases the pin; a credential that is merely
+// busy, behind an open circuit or already tried by this request keeps it, and such
+// requests are served elsewhere without moving the session.
+func sessionPinHolds(auth *Auth, provider, model string, now time.Time) bool {
+	if auth == nil || auth.Provider != provider {
+		return false
+	}
+	if model != "" {
+		if registryRef := registry.GetGlobalRegistry(); registryRef != nil && !registryRef.ClientSupportsModel(auth.ID, model) {
+			return false
+		}
+	}
+	blocked, _, _ := isAuthBlockedForModel(auth, model, now)
+	return !blocked
+}
+
+// pin records the credential chosen for the session, or extends the TTL of its pin.
+func (s *sessionAffinity) pin(key, authID string, now time.Time) {
+	if key == "" || authID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl <= 0 {
+		return
+	}
+	if s.pins == nil {
+		s.pins = make(map[string]sessionPin)
+	}
+	if now.Sub(s.lastSweep) >= s.ttl {
+		for k, p := range s.pins {
+			if now.After(p.expiresAt) {
+				delete(s.pins, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.pins[key] = sessionPin{authID: authID, expiresAt: now.Add(s.ttl)}
+}
+
+// sessionAffinityKey scopes the client session identifier to the client API key, provider
+// and model, so clients reusing trivial session IDs never share a pin.
+func sessionAffinityKey(ctx context.Context, provider, model string, opts cliproxyexecutor.Options) string {
+	if len(opts.Metadata) == 0 {
+		return ""
+	}
+	raw, ok := opts.Metadata[SessionKeyMetadataKey].(string)
+	if !ok {
+		return ""
+	}
+	session := strings.TrimSpace(raw)
+	if session == "" {
+		return ""
+	}
+	return cliproxyexecutor.ClientAPIKey(ctx) + "\x00" + provider + ":" + model + ":" + session
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan opens a manager span tagged with the request ID carried by ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if requestID := logging.GetRequestID(ctx); requestID != "" {
+		attrs = append(attrs, tracing.AttrRequestID.String(requestID))
+	}
+	return tracing.Start(ctx, name, attrs...)
+}
+
+// executorSpanAttributes describes the credential an executor call runs with.
+func executorSpanAttributes(provider, model string, auth *Auth) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{tracing.AttrProvider.String(provider), tracing.AttrModel.String(model)}
+	if auth != nil {
+		attrs = append(attrs, tracing.AttrAuthIndex.String(auth.Index))
+	}
+	return attrs
+}
@@ -245,6 +245,64 @@ func (a *Auth) AccountInfo() (string, string) {
 	return "", ""
 }
 
+// Priority returns the routing priority declared for the credential; lower values are preferred.
+// The "priority" attribute takes precedence over auth file metadata. Defaults to 0.
+func (a *Auth) Priority() int {
+	if value, ok := a.routingHint("priority"); ok {
+		return value
+	}
+	return 0
+}
+
+// Weight returns the routing weight declared for the credential. Defaults to 1.
+func (a *Auth) Weight() int {
+	if value, ok := a.routingHint("weight"); ok && value > 0 {
+		return value
+	}
+	return 1
+}
+
+// MaxConcurrency returns the per-credential in-flight request limit declared through the
+// "max_concurrency" attribute or auth file metadata. Zero means no credential-level limit.
+func (a *Auth) MaxConcurrency() int {
+	if value, ok := a.routingHint("max_concurrency"); ok && value > 0 {
+		return value
+	}
+	return 0
+}
+
+func (a *Auth) routingHint(key string) (int, bool) {
+	if a == nil {
+		return 0, false
+	}
+	if a.Attributes != nil {
+		if raw := strings.TrimSpace(a.Attributes[key]); raw != "" {
+			if value, err := strconv.Atoi(raw); err == nil {
+				return value, true
+			}
+		}
+	}
+	if a.Metadata != nil {
+		switch value := a.Metadata[key].(type) {
+		case float64:
+			return int(value), true
+		case int:
+			return value, true
+		case int64:
+			return int(value), true
+		case json.Number:
+			if i, err := value.Int64(); err == nil {
+				return int(i), true
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // ExpirationTime attempts to extract the credential expiration timestamp from metadata.
 // It inspects common keys such as "expired", "expire", "expires_at", and also
 // nested "token" objects to remain compatible with legacy auth file formats.
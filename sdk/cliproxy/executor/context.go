@@ -0,0 +1,25 @@
+package executor
+
+import "context"
+
+// clientAPIKeyKey is the context key for the client API key of background executions.
+type clientAPIKeyKey struct{}
+
+// WithClientAPIKey returns a context that attributes executions to the client API key.
+// HTTP handlers attach the key of the authenticated client; background work such as batch
+// jobs attaches the submitting client's key so that usage is still charged to it.
+func WithClientAPIKey(ctx context.Context, apiKey string) context.Context {
+	if apiKey == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientAPIKeyKey{}, apiKey)
+}
+
+// ClientAPIKey returns the client API key attached by WithClientAPIKey, or "".
+func ClientAPIKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	apiKey, _ := ctx.Value(clientAPIKeyKey{}).(string)
+	return apiKey
+}
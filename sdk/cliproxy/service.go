@@ -315,6 +315,28 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	}
 	maxInterval := time.Duration(cfg.MaxRetryInterval) * time.Second
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
+	s.coreManager.SetSessionAffinity(cfg.Routing.SessionAffinity.TTL())
+	breaker := cfg.Routing.CircuitBreaker
+	s.coreManager.SetCircuitBreaker(coreauth.CircuitBreakerSettings{
+		Enabled:          breaker.Enabled,
+		FailureThreshold: breaker.FailureThreshold,
+		Window:           time.Duration(breaker.WindowSeconds) * time.Second,
+		OpenDuration:     time.Duration(breaker.OpenSeconds) * time.Second,
+	})
+	hedging := cfg.Routing.Hedging
+	s.coreManager.SetHedging(coreauth.HedgingSettings{
+		Enabled:      hedging.Enabled,
+		Percentile:   hedging.Percentile,
+		InitialDelay: time.Duration(hedging.InitialDelayMs) * time.Millisecond,
+		MinDelay:     time.Duration(hedging.MinDelayMs) * time.Millisecond,
+		Models:       hedging.Models,
+	})
+	concurrency := cfg.Routing.Concurrency
+	s.coreManager.SetConcurrency(coreauth.ConcurrencySettings{
+		ProviderLimits: concurrency.ProviderLimits,
+		QueueSize:      concurrency.QueueSize,
+		QueueTimeout:   time.Duration(concurrency.QueueTimeoutSeconds) * time.Second,
+	})
 }
 
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
@@ -513,7 +535,7 @@ func (s *Service) Run(ctx context.Context) error {
 		previousStrategy := ""
 		s.cfgMu.RLock()
 		if s.cfg != nil {
-			previousStrategy = strings.ToLower(strings.TrimSpace(s.cfg.Routing.Strategy))
+			previousStrategy = s.cfg.Routing.Strategy
 		}
 		s.cfgMu.RUnlock()
 
@@ -526,26 +548,10 @@ func (s *Service) Run(ctx context.Context) error {
 			return
 		}
 
-		nextStrategy := strings.ToLower(strings.TrimSpace(newCfg.Routing.Strategy))
-		normalizeStrategy := func(strategy string) string {
-			switch strategy {
-			case "fill-first", "fillfirst", "ff":
-				return "fill-first"
-			default:
-				return "round-robin"
-			}
-		}
-		previousStrategy = normalizeStrategy(previousStrategy)
-		nextStrategy = normalizeStrategy(nextStrategy)
+		previousStrategy = coreauth.NormalizeStrategy(previousStrategy)
+		nextStrategy := coreauth.NormalizeStrategy(newCfg.Routing.Strategy)
 		if s.coreManager != nil && previousStrategy != nextStrategy {
-			var selector coreauth.Selector
-			switch nextStrategy {
-			case "fill-first":
-				selector = &coreauth.FillFirstSelector{}
-			default:
-				selector = &coreauth.RoundRobinSelector{}
-			}
-			s.coreManager.SetSelector(selector)
+			s.coreManager.SetSelector(coreauth.NewSelector(nextStrategy))
 			log.Infof("routing strategy updated to %s", nextStrategy)
 		}
 
@@ -558,6 +564,7 @@ func (s *Service) Run(ctx context.Context) error {
 		s.cfgMu.Unlock()
 		if s.coreManager != nil {
 			s.coreManager.SetOAuthModelMappings(newCfg.OAuthModelMappings)
+			s.coreManager.SetModelFallbacks(newCfg.ModelFallbacks)
 		}
 		s.rebindExecutors()
 	}
@@ -712,7 +719,7 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 	var models []*ModelInfo
 	switch provider {
 	case "gemini":
-		models = registry.GetGeminiModels()
+		models = append(registry.GetGeminiModels(), registry.GetGeminiEmbeddingModels()...)
 		if entry := s.resolveConfigGeminiKey(a); entry != nil {
 			if len(entry.Models) > 0 {
 				models = buildGeminiConfigModels(entry)
@@ -724,7 +731,7 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 		models = applyExcludedModels(models, excluded)
 	case "vertex":
 		// Vertex AI Gemini supports the same model identifiers as Gemini.
-		models = registry.GetGeminiVertexModels()
+		models = append(registry.GetGeminiVertexModels(), registry.GetGeminiVertexEmbeddingModels()...)
 		if authKind == "apikey" {
 			if entry := s.resolveConfigVertexCompatKey(a); entry != nil && len(entry.Models) > 0 {
 				models = buildVertexCompatConfigModels(entry)
@@ -827,14 +834,18 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 						if modelID == "" {
 							modelID = m.Name
 						}
-						ms = append(ms, &ModelInfo{
+						info := &ModelInfo{
 							ID:          modelID,
 							Object:      "model",
 							Created:     time.Now().Unix(),
 							OwnedBy:     compat.Name,
 							Type:        "openai-compatibility",
 							DisplayName: modelID,
-						})
+						}
+						if m.Embedding {
+							info.SupportedGenerationMethods = []string{"embedContent", "batchEmbedContents"}
+						}
+						ms = append(ms, info)
 					}
 					// Register and return
 					if len(ms) > 0 {
@@ -17,8 +17,16 @@ type Record struct {
 	AuthIndex   string
 	Source      string
 	RequestedAt time.Time
-	Failed      bool
-	Detail      Detail
+	// Latency is the time from dispatching the upstream request until the response
+	// completed; for streams, until the last chunk was received.
+	Latency time.Duration
+	// TimeToFirstToken is the delay until the first stream chunk arrived; zero for
+	// non-streaming requests.
+	TimeToFirstToken time.Duration
+	// OutputTokensPerSecond is the stream output rate measured after the first chunk.
+	OutputTokensPerSecond float64
+	Failed                bool
+	Detail                Detail
 }
 
 // Detail holds the token usage breakdown.
@@ -103,12 +111,106 @@ func (m *Manager) Register(plugin Plugin) {
 	m.pluginsMu.Unlock()
 }
 
+type bufferKey struct{}
+
+// Buffer holds the usage records published with a context until the caller decides
+// whether they count. It lets speculative requests, such as the attempts of a hedged
+// request, record usage only for the attempt whose response is used.
+type Buffer struct {
+	mu      sync.Mutex
+	state   bufferState
+	pending []bufferedRecord
+}
+
+type bufferedRecord struct {
+	manager *Manager
+	item    queueItem
+}
+
+type bufferState int
+
+const (
+	bufferHolding bufferState = iota
+	bufferFlushed
+	bufferDropped
+)
+
+// WithBuffer returns a context whose usage records are held by the returned buffer.
+// Records published with the context (or contexts derived from it) are delivered only
+// once Flush is called, and never after Drop.
+func WithBuffer(ctx context.Context) (context.Context, *Buffer) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	buf := &Buffer{}
+	return context.WithValue(ctx, bufferKey{}, buf), buf
+}
+
+// hold keeps or drops a record published through m. It returns false once the buffer
+// has been flushed, so later records are published directly.
+func (b *Buffer) hold(m *Manager, item queueItem) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case bufferHolding:
+		b.pending = append(b.pending, bufferedRecord{manager: m, item: item})
+		return true
+	case bufferDropped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Flush publishes the held records and lets later ones through.
+func (b *Buffer) Flush() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.state != bufferHolding {
+		b.mu.Unlock()
+		return
+	}
+	b.state = bufferFlushed
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	for _, held := range pending {
+		held.manager.enqueue(held.item)
+	}
+}
+
+// Drop discards the held records and every record published later.
+func (b *Buffer) Drop() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != bufferHolding {
+		return
+	}
+	b.state = bufferDropped
+	b.pending = nil
+}
+
 // Publish enqueues a usage record for processing. If no plugin is registered
 // the record will be discarded downstream.
 func (m *Manager) Publish(ctx context.Context, record Record) {
 	if m == nil {
 		return
 	}
+	item := queueItem{ctx: ctx, record: record}
+	if ctx != nil {
+		if buf, ok := ctx.Value(bufferKey{}).(*Buffer); ok && buf.hold(m, item) {
+			return
+		}
+	}
+	m.enqueue(item)
+}
+
+func (m *Manager) enqueue(item queueItem) {
 	// ensure worker is running even if Start was not called explicitly
 	m.Start(context.Background())
 	m.mu.Lock()
@@ -116,7 +218,7 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 		m.mu.Unlock()
 		return
 	}
-	m.queue = append(m.queue, queueItem{ctx: ctx, record: record})
+	m.queue = append(m.queue, item)
 	m.mu.Unlock()
 	m.cond.Signal()
 }
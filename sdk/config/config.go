@@ -13,6 +13,7 @@ type AccessProvider = internalconfig.AccessProvider
 type Config = internalconfig.Config
 
 type StreamingConfig = internalconfig.StreamingConfig
+type ContextOverflowRule = internalconfig.ContextOverflowRule
 type TLSConfig = internalconfig.TLSConfig
 type RemoteManagement = internalconfig.RemoteManagement
 type AmpCode = internalconfig.AmpCode
@@ -20,6 +21,7 @@ type ModelNameMapping = internalconfig.ModelNameMapping
 type PayloadConfig = internalconfig.PayloadConfig
 type PayloadRule = internalconfig.PayloadRule
 type PayloadModelRule = internalconfig.PayloadModelRule
+type RequestLogSinkConfig = internalconfig.RequestLogSinkConfig
 
 type GeminiKey = internalconfig.GeminiKey
 type CodexKey = internalconfig.CodexKey
@@ -36,6 +38,10 @@ const (
 	AccessProviderTypeConfigAPIKey = internalconfig.AccessProviderTypeConfigAPIKey
 	DefaultAccessProviderName      = internalconfig.DefaultAccessProviderName
 	DefaultPanelGitHubRepository   = internalconfig.DefaultPanelGitHubRepository
+
+	ContextOverflowReject    = internalconfig.ContextOverflowReject
+	ContextOverflowTruncate  = internalconfig.ContextOverflowTruncate
+	ContextOverflowSummarize = internalconfig.ContextOverflowSummarize
 )
 
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
@@ -1,7 +1,10 @@
 // Package logging re-exports request logging primitives for SDK consumers.
 package logging
 
-import internallogging "github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+import (
+	internallogging "github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
 
 // RequestLogger defines the interface for logging HTTP requests and responses.
 type RequestLogger = internallogging.RequestLogger
@@ -16,3 +19,11 @@ type FileRequestLogger = internallogging.FileRequestLogger
 func NewFileRequestLogger(enabled bool, logsDir string, configDir string) *FileRequestLogger {
 	return internallogging.NewFileRequestLogger(enabled, logsDir, configDir)
 }
+
+// JSONLRequestLogger implements RequestLogger by appending redacted JSON lines to a rotating file.
+type JSONLRequestLogger = internallogging.JSONLRequestLogger
+
+// NewJSONLRequestLogger creates a JSONL request logger.
+func NewJSONLRequestLogger(enabled bool, logsDir string, configDir string, cfg config.RequestLogSinkConfig) *JSONLRequestLogger {
+	return internallogging.NewJSONLRequestLogger(enabled, logsDir, configDir, cfg)
+}
@@ -9,4 +9,15 @@ const (
 	FormatGeminiCLI      Format = "gemini-cli"
 	FormatCodex          Format = "codex"
 	FormatAntigravity    Format = "antigravity"
+
+	// Embedding request formats are translated separately from generation formats.
+	FormatOpenAIEmbedding Format = "openai-embedding"
+	FormatGeminiEmbedding Format = "gemini-embedding"
+
+	// FormatOpenAIImage carries OpenAI Images API generation and edit requests.
+	FormatOpenAIImage Format = "openai-image"
+
+	// FormatOpenAITranscription and FormatOpenAISpeech carry OpenAI Audio API requests.
+	FormatOpenAITranscription Format = "openai-transcription"
+	FormatOpenAISpeech        Format = "openai-speech"
 )
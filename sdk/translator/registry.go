@@ -3,6 +3,10 @@ package translator
 import (
 	"context"
 	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Registry manages translation functions across schemas.
@@ -72,6 +76,7 @@ func (r *Registry) TranslateStream(ctx context.Context, from, to Format, model s
 
 	if byTarget, ok := r.responses[to]; ok {
 		if fn, isOk := byTarget[from]; isOk && fn.Stream != nil {
+			defer tracing.RecordTranslation(ctx, time.Now())
 			return fn.Stream(ctx, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
 		}
 	}
@@ -85,7 +90,11 @@ func (r *Registry) TranslateNonStream(ctx context.Context, from, to Format, mode
 
 	if byTarget, ok := r.responses[to]; ok {
 		if fn, isOk := byTarget[from]; isOk && fn.NonStream != nil {
-			return fn.NonStream(ctx, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+			spanCtx, span := tracing.Start(ctx, "cliproxy.translate_response",
+				attribute.String("cliproxy.translate.from", from.String()),
+				attribute.String("cliproxy.translate.to", to.String()))
+			defer span.End()
+			return fn.NonStream(spanCtx, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
 		}
 	}
 	return string(rawJSON)
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/handlers/management"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/quota"
+)
+
+func setupAPIKeyPolicyRouter(t *testing.T) (*gin.Engine, *config.Config, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8080\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg := &config.Config{}
+	h := management.NewHandler(cfg, configPath, nil)
+
+	r := gin.New()
+	mgmt := r.Group("/v0/management")
+	{
+		mgmt.GET("/api-key-policies", h.GetAPIKeyPolicies)
+		mgmt.PUT("/api-key-policies", h.PutAPIKeyPolicies)
+		mgmt.PATCH("/api-key-policies", h.PatchAPIKeyPolicy)
+		mgmt.DELETE("/api-key-policies", h.DeleteAPIKeyPolicy)
+		mgmt.GET("/quota/usage", h.GetQuotaUsage)
+		mgmt.POST("/quota/reset", h.ResetQuotaUsage)
+	}
+	return r, cfg, configPath
+}
+
+// TestAPIKeyPolicies_CRUD verifies policies are persisted and applied to the quota manager.
+func TestAPIKeyPolicies_CRUD(t *testing.T) {
+	r, cfg, configPath := setupAPIKeyPolicyRouter(t)
+
+	body := `{"api-key":"sk-intern-0001","value":{"name":"intern","limits":{"day":{"tokens":200000}}}}`
+	req := httptest.NewRequest(http.MethodPatch, "/v0/management/api-key-policies", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	policy, ok := cfg.APIKeyPolicies["sk-intern-0001"]
+	if !ok || policy.Limits == nil || policy.Limits.Day == nil || policy.Limits.Day.Tokens != 200000 {
+		t.Fatalf("unexpected policy in config: %+v", cfg.APIKeyPolicies)
+	}
+	if quota.GetManager().GetPolicy("sk-intern-0001") == nil {
+		t.Fatal("expected policy to be loaded into the quota manager")
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if !strings.Contains(string(data), "sk-intern-0001") {
+		t.Fatalf("expected policy to be persisted, got:\n%s", data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0/management/quota/usage?api-key=sk-intern-0001", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET usage status = %d", w.Code)
+	}
+	var usageResp struct {
+		Items []map[string]any `json:"quota-usage"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &usageResp); err != nil || len(usageResp.Items) != 1 {
+		t.Fatalf("unexpected usage response: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/v0/management/api-key-policies", bytes.NewBufferString(`{"api-key":"sk-bad","value":{"expires_at":"tomorrow"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid expires_at to be rejected, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/v0/management/api-key-policies?api-key=sk-intern-0001", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d", w.Code)
+	}
+	if len(cfg.APIKeyPolicies) != 0 {
+		t.Fatalf("expected policies to be empty, got %+v", cfg.APIKeyPolicies)
+	}
+	if quota.GetManager().GetPolicy("sk-intern-0001") != nil {
+		t.Fatal("expected policy to be removed from the quota manager")
+	}
+}
+
+// TestResetQuotaUsage verifies usage of a single key can be reset.
+func TestResetQuotaUsage(t *testing.T) {
+	r, _, _ := setupAPIKeyPolicyRouter(t)
+
+	manager := quota.GetManager()
+	manager.UpdateUsage("sk-reset-0001", "gpt-4o", 10, 5, 0)
+	if manager.GetUsageReadOnly("sk-reset-0001") == nil {
+		t.Fatal("expected usage to be recorded")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v0/management/quota/reset", bytes.NewBufferString(`{"api-key":"sk-reset-0001"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reset status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if manager.GetUsageReadOnly("sk-reset-0001") != nil {
+		t.Fatal("expected usage to be cleared")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v0/management/quota/reset", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing key to be rejected, got %d", w.Code)
+	}
+}